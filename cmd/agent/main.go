@@ -13,9 +13,11 @@ import (
 	"time"
 
 	"deeptrace/logger"
+	"deeptrace/pkg/agent/config"
 	"deeptrace/pkg/agent/grpcserver"
 	"deeptrace/pkg/agent/httpserver"
 	"deeptrace/pkg/agent/util/storage"
+	"deeptrace/pkg/agent/watchdog"
 	"deeptrace/pkg/prom/metrics"
 	"deeptrace/pkg/version"
 	pb "deeptrace/v1"
@@ -35,12 +37,26 @@ var (
 	JobName        = flag.String("job-name", "deeptraced", "Job name for metrics")
 	PushInterval   = flag.Duration("push-interval", 15*time.Second, "Metrics push interval")
 	PersistenceDir = flag.String("persistence-dir", "", "persistent directory for events such as trainning alert message. will use $WORK_DIR if unset. use /tmp if $WORK_DIR unset.")
+	ConfigPath     = flag.String("config", "", "agent config file (yaml), e.g. custom log formats. will use $DEEPTRACED_CONFIG if unset.")
 )
 
 func main() {
 	flag.Parse()
 	go metrics.PushMetricsToGateway(*PushGatewayURL, *JobName, *PushInterval)
 
+	if *ConfigPath == "" {
+		*ConfigPath = os.Getenv("DEEPTRACED_CONFIG")
+	}
+	cfg, err := config.Load(*ConfigPath)
+	if err != nil {
+		logger.Logger.Error("Failed to load config", zap.Error(err))
+		os.Exit(1)
+	}
+	if err := cfg.Apply(); err != nil {
+		logger.Logger.Error("Invalid config", zap.Error(err))
+		os.Exit(1)
+	}
+
 	restartChan := make(chan struct{}, 1)
 	storageC, err := storage.NewEventStorage(*PersistenceDir, 0)
 	if err != nil {
@@ -49,6 +65,7 @@ func main() {
 	}
 	grpcServer := grpc.NewServer(
 		grpc.UnaryInterceptor(metrics.MetricsInterceptor),
+		grpc.StreamInterceptor(metrics.StreamMetricsInterceptor),
 	)
 	pb.RegisterDeepTraceServiceServer(grpcServer, &grpcserver.TraceServiceServer{
 		RestartChan: restartChan,
@@ -109,6 +126,10 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if cfg.Watchdog.Enabled {
+		go watchdog.New(cfg.Watchdog, storageC).Run(ctx)
+	}
+
 	group, _ := errgroup.WithContext(ctx)
 
 	logger.Logger.Info("Starting service", zap.Any("version", version.GetAgentVersionInfo()))
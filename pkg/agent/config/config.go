@@ -0,0 +1,60 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+
+	"deeptrace/pkg/agent/logtail"
+	"deeptrace/pkg/agent/stacktrace"
+	"deeptrace/pkg/agent/util/textparser"
+	"deeptrace/pkg/agent/watchdog"
+
+	"github.com/spf13/viper"
+)
+
+// Config is the optional agent configuration file
+type Config struct {
+	// User-defined log formats, selectable by name or picked by auto-detection
+	LogFormats []textparser.RegexFormatConfig `mapstructure:"log_formats"`
+	// Extra patterns for training progress fields, tried before the built-in ones
+	ProgressExtractors []textparser.ProgressExtractorConfig `mapstructure:"progress_extractors"`
+	// Where rank logs are located under the work dir, tried in order before the default layout
+	LogLayouts []logtail.LogLayout `mapstructure:"log_layouts"`
+	// Tool used to dump process stacks when a request names none, "auto" picks the first installed
+	StackBackend string `mapstructure:"stack_backend"`
+	// Agent-side hang detection on the rank logs of the node
+	Watchdog watchdog.Config `mapstructure:"watchdog"`
+}
+
+// Load reads the agent configuration, an empty path gives the default configuration
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Apply registers everything the configuration defines
+func (c *Config) Apply() error {
+	if err := textparser.RegisterRegexFormats(c.LogFormats); err != nil {
+		return err
+	}
+	if err := textparser.RegisterProgressExtractors(c.ProgressExtractors); err != nil {
+		return err
+	}
+	if err := logtail.SetLogLayouts(c.LogLayouts); err != nil {
+		return err
+	}
+	return stacktrace.SetDefaultBackend(c.StackBackend)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	valid := filepath.Join(tmpDir, "valid.yaml")
+	os.WriteFile(valid, []byte(`
+log_formats:
+  - name: "custom"
+    pattern: '^\[(?P<timestamp>[^\]]+)\] (?P<message>.*)$'
+    time_layout: "2006-01-02 15:04:05"
+    fields:
+      message: message
+`), 0644)
+	broken := filepath.Join(tmpDir, "broken.yaml")
+	os.WriteFile(broken, []byte("log_formats: [\n"), 0644)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantFormats int
+		wantErr     bool
+	}{
+		{name: "no config", path: "", wantFormats: 0},
+		{name: "valid", path: valid, wantFormats: 1},
+		{name: "missing file", path: filepath.Join(tmpDir, "missing.yaml"), wantErr: true},
+		{name: "broken yaml", path: broken, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(cfg.LogFormats) != tt.wantFormats {
+				t.Errorf("Load() got %d log formats, want %d", len(cfg.LogFormats), tt.wantFormats)
+			}
+			if err := cfg.Apply(); err != nil {
+				t.Errorf("Apply() error = %v", err)
+			}
+		})
+	}
+}
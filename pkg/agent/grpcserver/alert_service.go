@@ -4,10 +4,12 @@ package grpcserver
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"deeptrace/logger"
 	"deeptrace/pkg/agent/util/storage"
+	"deeptrace/pkg/agent/watchdog"
 	pb "deeptrace/v1"
 
 	"go.uber.org/zap"
@@ -16,6 +18,9 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Event types returned when a request names none: webhook alerts and watchdog events
+var defaultAlertTypes = []string{"alert", watchdog.EventType, watchdog.EventProcessExit, watchdog.EventWorkerRestart}
+
 type AlertServiceServer struct {
 	pb.UnimplementedAlertServiceServer
 	Storage *storage.EventStorage
@@ -40,12 +45,18 @@ func (s *AlertServiceServer) GetAlerts(ctx context.Context, req *pb.GetAlertsReq
 		endTime = req.EndTime.AsTime().UnixMilli()
 	}
 
+	types := req.Types
+	if len(types) == 0 {
+		types = defaultAlertTypes
+	}
+
 	alerts, err := s.Storage.LoadEvents(storage.EventFilter{
 		StartTime:   startTime,
 		EndTime:     endTime,
 		MinSeverity: int32(req.MinSeverity),
-		Type:        "alert",
+		Types:       types,
 		Unprocessed: req.Unprocessed,
+		Peek:        req.Peek,
 	})
 	if err != nil {
 		logger.Logger.Error("Failed to load alerts", zap.Error(err))
@@ -58,7 +69,31 @@ func (s *AlertServiceServer) GetAlerts(ctx context.Context, req *pb.GetAlertsReq
 			Message:   a.Message,
 			Timestamp: timestamppb.New(time.UnixMilli(a.Timestamp)),
 			Severity:  pb.Severity(a.Severity),
+			Type:      a.Type,
+			Source:    a.Source,
+			Metadata:  alertMetadata(a.Metadata),
 		})
 	}
 	return resp, nil
 }
+
+// Convert event metadata to strings, values other than strings are JSON encoded
+func alertMetadata(metadata storage.Metadata) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if str, ok := value.(string); ok {
+			result[key] = str
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			logger.Logger.Warn("Failed to encode alert metadata", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		result[key] = string(data)
+	}
+	return result
+}
@@ -42,6 +42,60 @@ func (s *TraceServiceServer) GetRecentLogs(ctx context.Context, req *pb.GetRecen
 	}, nil
 }
 
+// FollowLogs streams newly written log entries of all ranks until the client disconnects.
+//
+// Parameters:
+//   - req: The FollowLogsRequest containing parameters for log following.
+//   - stream: The server stream used to send rank log batches.
+//
+// Returns:
+//   - error: An error if log following fails.
+func (s *TraceServiceServer) FollowLogs(req *pb.FollowLogsRequest, stream pb.DeepTraceService_FollowLogsServer) error {
+	follower := logtail.NewFollower(req)
+	if err := follower.Follow(stream.Context(), stream.Send); err != nil {
+		logger.Logger.Error("FollowLogs failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ResolveLogFiles reports which log file is used for each rank, without reading them.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - req: The ResolveLogFilesRequest containing the work directory.
+//
+// Returns:
+//   - *pb.ResolveLogFilesResponse: The layout, run directory and file of each rank.
+//   - error: An error if no run directory is found.
+func (s *TraceServiceServer) ResolveLogFiles(ctx context.Context, req *pb.ResolveLogFilesRequest) (*pb.ResolveLogFilesResponse, error) {
+	resp, err := logtail.ResolveLogFiles(ctx, req)
+	if err != nil {
+		logger.Logger.Error("ResolveLogFiles failed", zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetLogErrors extracts Python tracebacks and known fatal errors, such as NCCL timeouts
+// and CUDA OOM, from the tail of the rank logs.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - req: The GetLogErrorsRequest containing the work directory and the lines scanned.
+//
+// Returns:
+//   - *pb.LogErrorsResponse: The errors found in each rank log.
+//   - error: An error if the rank logs could not be located.
+func (s *TraceServiceServer) GetLogErrors(ctx context.Context, req *pb.GetLogErrorsRequest) (*pb.LogErrorsResponse, error) {
+	resp, err := logtail.GetLogErrors(ctx, req)
+	if err != nil {
+		logger.Logger.Error("GetLogErrors failed", zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
 // GetProcessStacks retrieves process stacks based on the request parameters.
 //
 // Parameters:
@@ -49,8 +103,9 @@ func (s *TraceServiceServer) GetRecentLogs(ctx context.Context, req *pb.GetRecen
 //   - req: The GetProcessStacksRequest containing parameters for stack retrieval.
 //
 // Returns:
-//   - *pb.ProcessStacksResponse: The response containing process stacks.
-//   - error: An error if stack retrieval fails.
+//   - *pb.ProcessStacksResponse: The response containing process stacks, processes that
+//     could not be dumped are included with their status.
+//   - error: An error if the processes could not be listed.
 func (s *TraceServiceServer) GetProcessStacks(ctx context.Context, req *pb.GetProcessStacksRequest) (*pb.ProcessStacksResponse, error) {
 	stackTraceClient := stacktrace.NewPythonStack(ctx, 72, req)
 	proccesses, err := stackTraceClient.GetProcessStacks(ctx)
@@ -58,13 +113,40 @@ func (s *TraceServiceServer) GetProcessStacks(ctx context.Context, req *pb.GetPr
 		logger.Logger.Error("GetProcessStacks failed", zap.Error(err))
 		return nil, err
 	}
+	sampled := 0
+	for _, proc := range proccesses {
+		if proc.Status == pb.StackStatus_STACK_OK {
+			sampled++
+		}
+	}
 	return &pb.ProcessStacksResponse{
-		TotalProcesses: int32(len(proccesses)),
-		Processes:      proccesses,
-		SnapshotTime:   timestamppb.Now().String(),
+		TotalProcesses:   int32(len(proccesses)),
+		SampledProcesses: int32(sampled),
+		Processes:        proccesses,
+		SnapshotTime:     timestamppb.Now().String(),
 	}, nil
 }
 
+// SampleProcessStacks takes several stack snapshots of the training processes on this
+// node and summarizes each thread across them.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - req: The SampleProcessStacksRequest with the number of snapshots, their interval and
+//     the process selection.
+//
+// Returns:
+//   - *pb.SampleProcessStacksResponse: The snapshots with their time and the thread summaries.
+//   - error: An error if the request is invalid or the processes could not be listed.
+func (s *TraceServiceServer) SampleProcessStacks(ctx context.Context, req *pb.SampleProcessStacksRequest) (*pb.SampleProcessStacksResponse, error) {
+	resp, err := stacktrace.SampleProcessStacks(ctx, req)
+	if err != nil {
+		logger.Logger.Error("SampleProcessStacks failed", zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
 // RestartServer restarts the server based on the request parameters.
 //
 // Parameters:
@@ -0,0 +1,56 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+)
+
+// Lines scanned at the end of each rank log for fatal errors when the request sets none
+const defaultErrorScanLines = 2000
+
+// GetLogErrors extracts the Python tracebacks and known fatal errors from the tail of the
+// rank logs of this node
+func GetLogErrors(ctx context.Context, req *pb.GetLogErrorsRequest) (*pb.LogErrorsResponse, error) {
+	workDir := os.Getenv("WORK_DIR")
+	maxLines := defaultErrorScanLines
+	if req != nil {
+		if req.WorkDir != "" {
+			workDir = req.WorkDir
+		}
+		if req.MaxLines > 0 {
+			maxLines = int(req.MaxLines)
+		}
+	}
+
+	ranks, resolved, err := resolveNodeRanks(ctx, workDir)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.LogErrorsResponse{}
+	for _, rank := range ranks {
+		rankErrors := &pb.RankLogErrors{
+			Rank:   fmt.Sprintf("RANK%d", rank),
+			Status: pb.RankLogStatus_RANK_LOG_OK,
+		}
+		// Tracebacks are not in the log format, the raw lines are scanned
+		lines, _, _, err := readRankLogTail(resolved.rankFile(rank), maxLines, nil)
+		if err != nil {
+			rankErrors.Status = pb.RankLogStatus_RANK_LOG_READ_ERROR
+			if errors.Is(err, os.ErrNotExist) {
+				rankErrors.Status = pb.RankLogStatus_RANK_LOG_FILE_MISSING
+			}
+			rankErrors.StatusMessage = err.Error()
+		} else {
+			rankErrors.Errors = textparser.ExtractLogErrors(lines)
+		}
+		resp.Ranklogs = append(resp.Ranklogs, rankErrors)
+	}
+	return resp, nil
+}
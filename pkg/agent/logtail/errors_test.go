@@ -0,0 +1,83 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"deeptrace/pkg/agent/util/scripts"
+	pb "deeptrace/v1"
+)
+
+func TestGetLogErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	logDir := filepath.Join(tmpDir, "20230101_120000")
+	os.Mkdir(logDir, 0755)
+	os.WriteFile(filepath.Join(logDir, "rank0.log"), []byte("2023-01-01 12:00:00 [INFO] step 1\n"), 0644)
+	os.WriteFile(filepath.Join(logDir, "rank1.log"), []byte(strings.Join([]string{
+		"2023-01-01 12:00:00 [INFO] step 1",
+		"Traceback (most recent call last):",
+		`  File "/workspace/train.py", line 7, in step`,
+		"torch.OutOfMemoryError: CUDA out of memory.",
+		"2023-01-01 12:00:05 [ERROR] CUDA out of memory.",
+	}, "\n")+"\n"), 0644)
+
+	getLocalRanks = func(ctx context.Context) (map[int]int, error) {
+		return map[int]int{0: 0, 1: 1, 2: 2}, nil
+	}
+	defer func() { getLocalRanks = scripts.GetCurrentNodeLocalRanks }()
+
+	type rankResult struct {
+		rank   string
+		status pb.RankLogStatus
+		kinds  []string
+	}
+	tests := []struct {
+		name string
+		req  *pb.GetLogErrorsRequest
+		want []rankResult
+	}{
+		{
+			name: "whole tail",
+			req:  &pb.GetLogErrorsRequest{WorkDir: tmpDir},
+			want: []rankResult{
+				{"RANK0", pb.RankLogStatus_RANK_LOG_OK, nil},
+				{"RANK1", pb.RankLogStatus_RANK_LOG_OK, []string{"cuda_oom", "cuda_oom"}},
+				{"RANK2", pb.RankLogStatus_RANK_LOG_FILE_MISSING, nil},
+			},
+		},
+		{
+			name: "last line only",
+			req:  &pb.GetLogErrorsRequest{WorkDir: tmpDir, MaxLines: 1},
+			want: []rankResult{
+				{"RANK0", pb.RankLogStatus_RANK_LOG_OK, nil},
+				{"RANK1", pb.RankLogStatus_RANK_LOG_OK, []string{"cuda_oom"}},
+				{"RANK2", pb.RankLogStatus_RANK_LOG_FILE_MISSING, nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := GetLogErrors(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("GetLogErrors failed: %v", err)
+			}
+			var got []rankResult
+			for _, rankLog := range resp.Ranklogs {
+				var kinds []string
+				for _, e := range rankLog.Errors {
+					kinds = append(kinds, e.Kind)
+				}
+				got = append(got, rankResult{rankLog.Rank, rankLog.Status, kinds})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetLogErrors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,15 +4,16 @@ package logtail
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"sort"
+	"strings"
 	"time"
 
 	"deeptrace/logger"
-	"deeptrace/pkg/agent/util/scripts"
 	"deeptrace/pkg/agent/util/textparser"
 	pb "deeptrace/v1"
 
@@ -24,68 +25,166 @@ import (
 
 func NewFileReader(ctx context.Context, req *pb.GetRecentLogsRequest) Interface {
 	workDir := os.Getenv("WORK_DIR")
-	if req != nil && req.WorkDir != "" {
-		workDir = req.WorkDir
-	}
-	return &FileReader{
-		workDir:   workDir,
-		logParser: &textparser.LogParser{},
+	reader := &FileReader{}
+	if req != nil {
+		if req.WorkDir != "" {
+			workDir = req.WorkDir
+		}
+		var filterErr, formatErr error
+		reader.filter, filterErr = NewLogFilter(req.Levels, req.SearchKeyword, req.Regex, req.Since, req.Until)
+		reader.logParser, formatErr = lookupRequestedFormat(req.LogFormat)
+		reader.reqErr = errors.Join(filterErr, formatErr)
 	}
+	reader.workDir = workDir
+	return reader
 }
 
 // Get latest logs for all ranks
 func (s *FileReader) GetRecentLogs(ctx context.Context, maxLines int32) ([]*pb.RankLog, error) {
-	// 1. Get the latest log directory
-	logDir, err := getLatestLogDir(s.workDir)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Log directory not found: %v", err)
+	if s.reqErr != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", s.reqErr)
 	}
 
-	// 2. Determine the number of ranks (via environment variables)
-	rankMin, rankMax, err := scripts.GetCurrentNodeRankRange(ctx)
+	// 1. Determine the ranks of this node and locate their logs
+	ranks, resolved, err := resolveNodeRanks(ctx, s.workDir)
 	if err != nil {
-		logger.Logger.Error("Failed to get rank range", zap.Error(err))
 		return nil, err
 	}
-	rankRange := rankMax - rankMin + 1
 
-	// 3. Collect all rank logs
-	rankLogs := make([]*pb.RankLog, 0, rankRange)
+	// 2. Collect all rank logs, a failing rank doesn't affect the others
+	rankLogs := make([]*pb.RankLog, 0, len(ranks))
+	for _, rank := range ranks {
+		rankLogs = append(rankLogs, s.readRankLog(ctx, rank, resolved.rankFile(rank), maxLines))
+	}
+
+	return rankLogs, nil
+}
 
-	for rank := rankMin; rank < rankMax; rank++ {
-		lines, fmodTime, err := readRankLogTail(logDir, rank, int(maxLines))
-		if err != nil {
-			// Partial failure doesn't affect other ranks
-			lines = []string{fmt.Sprintf("Log reading failed: %v", err)}
+// Ranks of the training processes on this node, or the ranks of the logs found when no
+// training process is running, together with the located rank logs
+func resolveNodeRanks(ctx context.Context, workDir string) ([]int, *resolvedLogs, error) {
+	ranks, localRanks, rankErr := getNodeRanks(ctx)
+	resolved, err := resolveRankLogs(workDir, localRanks)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "Log directory not found: %v", err)
+	}
+	if rankErr != nil {
+		ranks = resolved.ranks()
+		if len(ranks) == 0 {
+			logger.Logger.Error("Failed to get node ranks", zap.Error(rankErr))
+			return nil, nil, status.Errorf(codes.NotFound, "No training processes and no rank logs found: %v", rankErr)
 		}
+		logger.Logger.Info("No training processes found, using the rank logs found", zap.Error(rankErr), zap.Ints("ranks", ranks))
+	}
+	return ranks, resolved, nil
+}
+
+// Read and parse the tail of one rank log
+func (s *FileReader) readRankLog(ctx context.Context, rank int, logFile string, maxLines int32) *pb.RankLog {
+	now := time.Now()
+	ranklog := &pb.RankLog{
+		Rank:     fmt.Sprintf("RANK%d", rank),
+		TailTime: timestamppb.New(now),
+		Status:   pb.RankLogStatus_RANK_LOG_OK,
+	}
 
-		// entries, latestTime := parceLogs(ctx, lines)
-		entries, err := textparser.ParseWithType(ctx, s.logParser, lines)
-		if err != nil {
-			logger.Logger.Error("LogParser ParseWithType", zap.Error(err))
+	parser := resolveLogFormat(s.logParser, logFile)
+	lines, fmodTime, truncated, err := readRankLogTail(logFile, int(maxLines), newLineMatcher(ctx, parser, s.filter))
+	if err != nil {
+		ranklog.Status = pb.RankLogStatus_RANK_LOG_READ_ERROR
+		if errors.Is(err, os.ErrNotExist) {
+			ranklog.Status = pb.RankLogStatus_RANK_LOG_FILE_MISSING
 		}
+		ranklog.StatusMessage = err.Error()
+		return ranklog
+	}
 
-		now := time.Now()
-		ranklog := &pb.RankLog{
-			Rank:     fmt.Sprintf("RANK%d", rank),
-			TailTime: timestamppb.New(now),
+	entries, err := textparser.ParseWithType(ctx, parser, lines)
+	if err != nil {
+		logger.Logger.Error("LogParser ParseWithType", zap.Error(err))
+		ranklog.Status = pb.RankLogStatus_RANK_LOG_PARSE_ERROR
+		ranklog.StatusMessage = err.Error()
+	}
+
+	// Filtered entries may be old, the unfiltered tail tells whether the rank is still writing
+	latestLines, latestEntries := lines, entries
+	if s.filter != nil {
+		if probe, _, _, err := readRankLogTail(logFile, suspendProbeLines, nil); err == nil {
+			latestLines = probe
+			latestEntries, _ = textparser.ParseWithType(ctx, parser, probe)
 		}
-		if len(entries) > 0 {
-			latestTime := getLatestTime(entries, fmodTime)
-			// Default to 1970 or init value, set -10 if no valid time
-			if latestTime.Before(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.Local)) {
-				ranklog.SuspendSeconds = -10
-			} else {
-				ranklog.SuspendSeconds = int32(now.Sub(latestTime).Seconds())
-			}
+	}
+	if ranklog.Status == pb.RankLogStatus_RANK_LOG_OK && !inLogFormat(parser, latestLines) {
+		ranklog.Status = pb.RankLogStatus_RANK_LOG_PARSE_ERROR
+		ranklog.StatusMessage = fmt.Sprintf("none of the last %d lines is in the %s log format", len(latestLines), parser.Name())
+	}
+	if ranklog.Status == pb.RankLogStatus_RANK_LOG_OK && truncated {
+		ranklog.Status = pb.RankLogStatus_RANK_LOG_TRUNCATED
+		ranklog.StatusMessage = fmt.Sprintf("filter scanned only the last %d MB of the log, older matches are left out", maxFilterScanBytes>>20)
+	}
+
+	if len(latestEntries) > 0 {
+		latestTime := getLatestTime(latestEntries, fmodTime)
+		// Default to 1970 or init value, set -10 if no valid time
+		if latestTime.Before(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.Local)) {
+			ranklog.SuspendSeconds = -10
+		} else {
+			ranklog.SuspendSeconds = int32(now.Sub(latestTime).Seconds())
+		}
+	}
+	if len(entries) > 0 {
+		ranklog.Entries = entries
+	}
+	return ranklog
+}
+
+// Whether a line of the tail is in the log format, an empty tail has nothing to parse
+func inLogFormat(parser textparser.LogFormat, lines []string) bool {
+	empty := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		empty = false
+		if parser.Match(line) {
+			return true
+		}
+	}
+	return empty
+}
 
-			ranklog.Entries = entries
+// Parse each line to apply the filter while reading, nil when nothing is filtered
+func newLineMatcher(ctx context.Context, parser textparser.LogFormat, filter *LogFilter) lineMatcher {
+	if filter == nil {
+		return nil
+	}
+	return func(line string) (bool, bool) {
+		entries, err := textparser.ParseWithType(ctx, parser, []string{line})
+		if err != nil || len(entries) == 0 {
+			return false, false
 		}
+		return filter.Match(entries[0]), filter.Before(entries[0])
+	}
+}
 
-		rankLogs = append(rankLogs, ranklog)
+// Resolve the log format requested by name, empty or "auto" means detection per rank log
+func lookupRequestedFormat(name string) (textparser.LogFormat, error) {
+	if name == "" || strings.EqualFold(name, autoLogFormat) {
+		return nil, nil
 	}
+	return textparser.LookupLogFormat(name)
+}
 
-	return rankLogs, nil
+// Use the requested format, or detect it from the first lines of the log
+func resolveLogFormat(format textparser.LogFormat, logFile string) textparser.LogFormat {
+	if format != nil {
+		return format
+	}
+	lines, err := readFirstLines(logFile, textparser.DetectLines)
+	if err != nil {
+		lines = nil
+	}
+	return textparser.DetectLogFormat(lines)
 }
 
 func getLatestTime(entries []*pb.LogEntry, fmodTime time.Time) time.Time {
@@ -102,87 +201,153 @@ func getLatestTime(entries []*pb.LogEntry, fmodTime time.Time) time.Time {
 	return latestTime
 }
 
-// Get latest log directory (only one task at a time)
-func getLatestLogDir(workDir string) (string, error) {
-	files, err := os.ReadDir(workDir)
+// Read tail of specific rank's log, truncated reports that a filtered read stopped at
+// maxFilterScanBytes
+func readRankLogTail(logFile string, lines int, match lineMatcher) ([]string, time.Time, bool, error) {
+	var fileModTime time.Time
+	file, err := os.Open(logFile)
 	if err != nil {
-		return "", err
+		return nil, fileModTime, false, err
 	}
+	defer file.Close()
 
-	// Filter and sort timestamp directories
-	var dirs []os.FileInfo
-	for _, f := range files {
-		if f.IsDir() {
-			info, err := f.Info()
-			if err != nil {
-
-			}
-			dirs = append(dirs, info)
-		}
+	fstat, err := file.Stat()
+	if err != nil {
+		logger.Logger.Error("failed to fetch file stat", zap.Any("file", file.Name()), zap.Error(err))
+		return nil, fileModTime, false, fmt.Errorf("Log stat error: %v", err)
 	}
+	fileModTime = fstat.ModTime()
 
-	if len(dirs) == 0 {
-		return "", fmt.Errorf("no log directories found")
+	logLines, truncated, err := readLastLines(file, fstat.Size(), lines, tailBlockSize, match)
+	if err != nil {
+		logger.Logger.Error("Log scanning error", zap.Error(err))
+		return nil, fileModTime, false, fmt.Errorf("Log scanning error: %v", err)
 	}
 
-	// Sort by modification time (newest first)
-	sort.Slice(dirs, func(i, j int) bool {
-		return dirs[i].ModTime().After(dirs[j].ModTime())
-	})
-
-	return filepath.Join(workDir, dirs[0].Name()), nil
+	return logLines, fileModTime, truncated, nil
 }
 
-// Read tail of specific rank's log
-func readRankLogTail(logDir string, rank int, lines int) ([]string, time.Time, error) {
-	var fileModTime time.Time
-	logFile := filepath.Join(logDir, fmt.Sprintf("rank%d.log", rank))
-	file, err := os.Open(logFile)
+// Read the first n lines of a file
+func readFirstLines(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fileModTime, err
+		return nil, err
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
-	// Set 1MB buffer for long lines
-	const maxCapacity = 1024 * 1024
-	buf := make([]byte, maxCapacity)
-	scanner.Buffer(buf, maxCapacity)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	lines := make([]string, 0, n)
+	for len(lines) < n && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	// An over-long line ends detection early, the lines read so far are enough
+	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+		return lines, err
+	}
+	return lines, nil
+}
 
-	var logLines []string
-	lineCount := 0
+// Read the last n lines of r by scanning blocks backwards from EOF, so the cost
+// depends on the tail size rather than the file size. Lines longer than
+// maxLineLength are cut to their first maxLineLength bytes. With a matcher only
+// matching lines are counted, and at most maxFilterScanBytes are scanned: truncated
+// reports that the limit was hit before n lines matched.
+func readLastLines(r io.ReaderAt, size int64, n int, blockSize int, match lineMatcher) (lines []string, truncated bool, err error) {
+	if n <= 0 || size <= 0 {
+		return nil, false, nil
+	}
 
-	// Use ring buffer to store last N lines
-	ringBuffer := make([]string, lines)
-	index := 0
+	buf := make([]byte, blockSize)
+	// Reversed result, the last line comes first
+	reversed := make([]string, 0, min(n, 1024))
+	// Pieces of the line currently being assembled, in reverse order
+	var chunks [][]byte
+	carryLen := 0
+	tooLong := false
+	stopped := false
 
-	for scanner.Scan() {
-		ringBuffer[index] = scanner.Text()
-		index = (index + 1) % lines
-		lineCount++
+	emit := func(lineStart int64) error {
+		line := make([]byte, 0, carryLen)
+		if tooLong {
+			// Only the head of an over-long line is kept, read it directly
+			line = line[:maxLineLength]
+			if _, err := r.ReadAt(line, lineStart); err != nil && err != io.EOF {
+				return err
+			}
+		} else {
+			for i := len(chunks) - 1; i >= 0; i-- {
+				line = append(line, chunks[i]...)
+			}
+		}
+		text := string(bytes.TrimSuffix(line, []byte("\r")))
+		chunks, carryLen, tooLong = chunks[:0], 0, false
+		if match != nil {
+			keep, stop := match(text)
+			stopped = stop
+			if !keep {
+				return nil
+			}
+		}
+		reversed = append(reversed, text)
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		logger.Logger.Error("Log scanning error", zap.Error(err))
-		return nil, fileModTime, fmt.Errorf("Log scanning error: %v", err)
+	// A trailing newline terminates the last line instead of starting an empty one
+	last := make([]byte, 1)
+	if _, err := r.ReadAt(last, size-1); err != nil && err != io.EOF {
+		return nil, false, err
 	}
 
-	// Populate actual results
-	start := 0
-	if lineCount > lines {
-		start = index
+	pos := size
+	if last[0] == '\n' {
+		pos = size - 1
 	}
+	for pos > 0 && len(reversed) < n && !stopped {
+		if match != nil && size-pos > maxFilterScanBytes {
+			stopped, truncated = true, true
+			break
+		}
+		readSize := int64(blockSize)
+		if pos < readSize {
+			readSize = pos
+		}
+		pos -= readSize
+		block := buf[:readSize]
+		if _, err := r.ReadAt(block, pos); err != nil && err != io.EOF {
+			return nil, false, err
+		}
 
-	for i := 0; i < min(lineCount, lines); i++ {
-		pos := (start + i) % lines
-		logLines = append(logLines, ringBuffer[pos])
+		end := len(block)
+		for end >= 0 && len(reversed) < n && !stopped {
+			idx := bytes.LastIndexByte(block[:end], '\n')
+			if !tooLong && end > idx+1 {
+				chunks = append(chunks, append([]byte(nil), block[idx+1:end]...))
+				carryLen += end - idx - 1
+				if carryLen > maxLineLength {
+					chunks, carryLen, tooLong = chunks[:0], maxLineLength, true
+				}
+			}
+			if idx < 0 {
+				break
+			}
+			if err := emit(pos + int64(idx) + 1); err != nil {
+				return nil, false, err
+			}
+			end = idx
+		}
 	}
 
-	if fstat, err := file.Stat(); err == nil {
-		fileModTime = fstat.ModTime()
-	} else {
-		logger.Logger.Error("failed to fetch file stat", zap.Any("file", file.Name()), zap.Error(err))
+	// The first line of the file has no newline before it
+	if pos == 0 && len(reversed) < n && !stopped {
+		if err := emit(0); err != nil {
+			return nil, false, err
+		}
 	}
 
-	return logLines, fileModTime, nil
+	lines = make([]string, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines, truncated, nil
 }
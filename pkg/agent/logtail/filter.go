@@ -0,0 +1,96 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "deeptrace/v1"
+
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LogFilter selects log entries on the agent before they are returned
+type LogFilter struct {
+	levels  map[pb.LogLevel]bool
+	keyword string
+	regex   *regexp.Regexp
+	since   time.Time
+	until   time.Time
+}
+
+// NewLogFilter builds a filter from request fields, nil means no filtering
+func NewLogFilter(levels []pb.LogLevel, keyword, regex string, since, until *timestamppb.Timestamp) (*LogFilter, error) {
+	f := &LogFilter{keyword: keyword}
+	for _, level := range levels {
+		if level == pb.LogLevel_LOG_UNSPECIFIED {
+			continue
+		}
+		if f.levels == nil {
+			f.levels = make(map[pb.LogLevel]bool)
+		}
+		f.levels[level] = true
+	}
+	if regex != "" {
+		reg, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %v", regex, err)
+		}
+		f.regex = reg
+	}
+	if since != nil {
+		f.since = since.AsTime()
+	}
+	if until != nil {
+		f.until = until.AsTime()
+	}
+	if !f.until.IsZero() && f.since.After(f.until) {
+		return nil, fmt.Errorf("since %v is after until %v", f.since, f.until)
+	}
+
+	if f.levels == nil && f.keyword == "" && f.regex == nil && f.since.IsZero() && f.until.IsZero() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Match reports whether the entry passes the filter
+func (f *LogFilter) Match(entry *pb.LogEntry) bool {
+	if f == nil {
+		return true
+	}
+	if f.levels != nil && !f.levels[entry.Level] {
+		return false
+	}
+	if f.keyword != "" && !strings.Contains(entry.Message, f.keyword) {
+		return false
+	}
+	if f.regex != nil && !f.regex.MatchString(entry.Message) {
+		return false
+	}
+	if !f.since.IsZero() || !f.until.IsZero() {
+		if entry.Timestamp == nil {
+			return false
+		}
+		ts := entry.Timestamp.AsTime()
+		if !f.since.IsZero() && ts.Before(f.since) {
+			return false
+		}
+		if !f.until.IsZero() && ts.After(f.until) {
+			return false
+		}
+	}
+	return true
+}
+
+// Before reports whether the entry is older than the time window, logs are
+// written in time order so nothing before it can match either
+func (f *LogFilter) Before(entry *pb.LogEntry) bool {
+	if f == nil || f.since.IsZero() || entry.Timestamp == nil {
+		return false
+	}
+	return entry.Timestamp.AsTime().Before(f.since)
+}
@@ -0,0 +1,169 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestNewLogFilter(t *testing.T) {
+	now := timestamppb.Now()
+	earlier := timestamppb.New(now.AsTime().Add(-time.Hour))
+	tests := []struct {
+		name    string
+		levels  []pb.LogLevel
+		keyword string
+		regex   string
+		since   *timestamppb.Timestamp
+		until   *timestamppb.Timestamp
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "no filter", wantNil: true},
+		{name: "unspecified level only", levels: []pb.LogLevel{pb.LogLevel_LOG_UNSPECIFIED}, wantNil: true},
+		{name: "keyword", keyword: "loss"},
+		{name: "invalid regex", regex: "(", wantErr: true},
+		{name: "since after until", since: now, until: earlier, wantErr: true},
+		{name: "time window", since: earlier, until: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLogFilter(tt.levels, tt.keyword, tt.regex, tt.since, tt.until)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLogFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("NewLogFilter() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestLogFilter_Match(t *testing.T) {
+	logtime := time.Date(2025, 7, 11, 2, 32, 52, 0, time.UTC)
+	entry := &pb.LogEntry{
+		Timestamp: timestamppb.New(logtime),
+		Level:     pb.LogLevel_LOG_ERROR,
+		Message:   "[Train] (Epoch 1) loss: nan",
+	}
+	noTime := &pb.LogEntry{Message: "Traceback (most recent call last):"}
+	tests := []struct {
+		name    string
+		levels  []pb.LogLevel
+		keyword string
+		regex   string
+		since   time.Time
+		until   time.Time
+		entry   *pb.LogEntry
+		want    bool
+	}{
+		{name: "level match", levels: []pb.LogLevel{pb.LogLevel_LOG_WARNING, pb.LogLevel_LOG_ERROR}, entry: entry, want: true},
+		{name: "level mismatch", levels: []pb.LogLevel{pb.LogLevel_LOG_INFO}, entry: entry, want: false},
+		{name: "unparsed level dropped", levels: []pb.LogLevel{pb.LogLevel_LOG_ERROR}, entry: noTime, want: false},
+		{name: "keyword match", keyword: "loss", entry: entry, want: true},
+		{name: "keyword is case sensitive", keyword: "LOSS", entry: entry, want: false},
+		{name: "regex match", regex: `loss: (nan|inf)`, entry: entry, want: true},
+		{name: "regex mismatch", regex: `^loss`, entry: entry, want: false},
+		{name: "inside window", since: logtime.Add(-time.Minute), until: logtime.Add(time.Minute), entry: entry, want: true},
+		{name: "before window", since: logtime.Add(time.Second), entry: entry, want: false},
+		{name: "after window", until: logtime.Add(-time.Second), entry: entry, want: false},
+		{name: "no timestamp with window", since: logtime, entry: noTime, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var since, until *timestamppb.Timestamp
+			if !tt.since.IsZero() {
+				since = timestamppb.New(tt.since)
+			}
+			if !tt.until.IsZero() {
+				until = timestamppb.New(tt.until)
+			}
+			f, err := NewLogFilter(tt.levels, tt.keyword, tt.regex, since, until)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(tt.entry); got != tt.want {
+				t.Errorf("LogFilter.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readLastLinesWithFilter(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 100; i++ {
+		level := "INFO"
+		if i%10 == 0 {
+			level = "ERROR"
+		}
+		fmt.Fprintf(&sb, "[XTuner][RANK 0][2025-07-11 02:%02d:00][%s] line %d\n", i%60, level, i)
+	}
+	content := sb.String()
+	ctx := context.Background()
+	parser := &textparser.LogParser{}
+
+	errorsOnly, _ := NewLogFilter([]pb.LogLevel{pb.LogLevel_LOG_ERROR}, "", "", nil, nil)
+	lines, _, err := readLastLines(strings.NewReader(content), int64(len(content)), 3, 128, newLineMatcher(ctx, parser, errorsOnly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"line 70", "line 80", "line 90"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %v", len(lines), len(want), lines)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("line %d = %q, want suffix %q", i, lines[i], want[i])
+		}
+	}
+
+	// Scanning stops at the first line before since
+	since := timestamppb.New(time.Date(2025, 7, 11, 2, 35, 0, 0, time.UTC))
+	window, _ := NewLogFilter(nil, "", "", since, nil)
+	lines, _, err = readLastLines(strings.NewReader(content), int64(len(content)), 100, 128, newLineMatcher(ctx, parser, window))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Minutes 35..39 of lines 95..99
+	if len(lines) != 5 || !strings.HasSuffix(lines[0], "line 95") {
+		t.Errorf("time window lines = %v", lines)
+	}
+}
+
+// Lines of 1KB without a match, generated instead of held in memory
+type unmatchedLog struct{}
+
+func (unmatchedLog) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		p[i] = '-'
+		if (off+int64(i))%1024 == 1023 {
+			p[i] = '\n'
+		}
+	}
+	return len(p), nil
+}
+
+func Test_readLastLinesScanLimit(t *testing.T) {
+	match := func(line string) (bool, bool) { return false, false }
+	lines, truncated, err := readLastLines(unmatchedLog{}, 2*maxFilterScanBytes, 10, tailBlockSize, match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 0 || !truncated {
+		t.Errorf("readLastLines() = %d lines, truncated %v, want none and truncated", len(lines), truncated)
+	}
+
+	// The whole file fits within the limit
+	if _, truncated, _ := readLastLines(unmatchedLog{}, 1024*1024, 10, tailBlockSize, match); truncated {
+		t.Error("readLastLines() truncated a log smaller than the scan limit")
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"deeptrace/logger"
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultPollInterval = 500 * time.Millisecond
+
+func NewFollower(req *pb.FollowLogsRequest) *Follower {
+	workDir := os.Getenv("WORK_DIR")
+	follower := &Follower{
+		pollInterval: defaultPollInterval,
+	}
+	if req != nil {
+		if req.WorkDir != "" {
+			workDir = req.WorkDir
+		}
+		follower.tailLines = int(req.TailLines)
+		var filterErr, formatErr error
+		follower.filter, filterErr = NewLogFilter(req.Levels, req.SearchKeyword, req.Regex, req.Since, req.Until)
+		follower.logParser, formatErr = lookupRequestedFormat(req.LogFormat)
+		follower.reqErr = errors.Join(filterErr, formatErr)
+	}
+	follower.workDir = workDir
+	return follower
+}
+
+// Follow tails every rank log of the latest run and calls send for each batch
+// of new entries until ctx is done or send fails.
+func (f *Follower) Follow(ctx context.Context, send func(*pb.RankLog) error) error {
+	if f.reqErr != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", f.reqErr)
+	}
+	localRanks := followLocalRanks(ctx)
+	resolved, err := resolveRankLogs(f.workDir, localRanks)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Log directory not found: %v", err)
+	}
+
+	files := map[string]*followedFile{}
+	defer func() {
+		for _, ff := range files {
+			ff.close()
+		}
+	}()
+
+	// Files present at start only replay their tail, files created later are read from the beginning
+	initial := true
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if latest, err := resolveRankLogs(f.workDir, localRanks); err == nil {
+			// A newer run directory means a new job started, switch to it
+			if latest.runDir != resolved.runDir || latest.layout.Name != resolved.layout.Name {
+				logger.Logger.Info("Log directory changed", zap.String("from", resolved.runDir), zap.String("to", latest.runDir))
+				for path, ff := range files {
+					ff.close()
+					delete(files, path)
+				}
+				initial = false
+			}
+			resolved = latest
+		}
+
+		if err := f.discover(ctx, resolved.files, files, initial, send); err != nil {
+			return err
+		}
+		initial = false
+
+		// A burst of output is read and sent in bounded chunks
+		for _, ff := range files {
+			for more := true; more && ctx.Err() == nil; {
+				var lines []string
+				var err error
+				lines, more, err = ff.poll()
+				if err != nil {
+					logger.Logger.Error("Failed to follow rank log", zap.String("file", ff.path), zap.Error(err))
+					break
+				}
+				if err := f.sendLines(ctx, ff, lines, send); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Global rank of every local rank, only needed by layouts that number files by local rank
+func followLocalRanks(ctx context.Context) map[int]int {
+	for _, layout := range currentLogLayouts() {
+		if layout.usesLocalRank() && !layout.usesRank() {
+			_, localRanks, err := getNodeRanks(ctx)
+			if err != nil {
+				logger.Logger.Error("Failed to get node ranks, using local ranks", zap.Error(err))
+				return nil
+			}
+			return localRanks
+		}
+	}
+	return nil
+}
+
+// Start following rank logs that are not tracked yet, and stop following the ones that
+// are no longer found
+func (f *Follower) discover(ctx context.Context, rankFiles map[int]string, files map[string]*followedFile, initial bool, send func(*pb.RankLog) error) error {
+	found := make(map[string]bool, len(rankFiles))
+	for _, path := range rankFiles {
+		found[path] = true
+	}
+	for path, ff := range files {
+		if !found[path] {
+			ff.close()
+			delete(files, path)
+		}
+	}
+
+	for rank, path := range rankFiles {
+		if _, ok := files[path]; ok {
+			continue
+		}
+
+		ff := &followedFile{rank: rank, path: path}
+		if err := ff.open(); err != nil {
+			logger.Logger.Error("Failed to open rank log", zap.String("file", path), zap.Error(err))
+			continue
+		}
+		files[path] = ff
+
+		if !initial {
+			continue
+		}
+		// Replay the tail and continue from the current end of file
+		ff.offset = ff.info.Size()
+		if f.tailLines <= 0 {
+			continue
+		}
+		lines, _, _, err := readRankLogTail(path, f.tailLines, newLineMatcher(ctx, f.parserFor(ff), f.filter))
+		if err != nil {
+			logger.Logger.Error("Failed to read rank log tail", zap.String("file", path), zap.Error(err))
+			continue
+		}
+		if err := f.sendLines(ctx, ff, lines, send); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Use the requested format, or detect it once the file has content
+func (f *Follower) parserFor(ff *followedFile) textparser.LogFormat {
+	if f.logParser != nil {
+		return f.logParser
+	}
+	if ff.parser == nil {
+		lines, err := readFirstLines(ff.path, textparser.DetectLines)
+		if err != nil || len(lines) == 0 {
+			return textparser.DetectLogFormat(nil)
+		}
+		ff.parser = textparser.DetectLogFormat(lines)
+	}
+	return ff.parser
+}
+
+func (f *Follower) sendLines(ctx context.Context, ff *followedFile, lines []string, send func(*pb.RankLog) error) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	entries, err := textparser.ParseWithType(ctx, f.parserFor(ff), lines)
+	if err != nil {
+		logger.Logger.Error("LogParser ParseWithType", zap.Error(err))
+	}
+	if f.filter != nil {
+		matched := entries[:0]
+		for _, entry := range entries {
+			if f.filter.Match(entry) {
+				matched = append(matched, entry)
+			}
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+		entries = matched
+	}
+	return send(&pb.RankLog{
+		Rank:     fmt.Sprintf("RANK%d", ff.rank),
+		Entries:  entries,
+		TailTime: timestamppb.Now(),
+		Status:   pb.RankLogStatus_RANK_LOG_OK,
+	})
+}
+
+func (ff *followedFile) open() error {
+	file, err := os.Open(ff.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	ff.file, ff.info = file, info
+	ff.offset = 0
+	ff.partial = nil
+	return nil
+}
+
+func (ff *followedFile) close() {
+	if ff.file != nil {
+		ff.file.Close()
+		ff.file = nil
+	}
+}
+
+// Read complete lines written since the last poll, handling truncation and rotation. At
+// most followReadSize bytes are read, more reports that the file has data left.
+func (ff *followedFile) poll() (lines []string, more bool, err error) {
+	info, err := os.Stat(ff.path)
+	if err == nil && !os.SameFile(info, ff.info) {
+		// Rotated: drain what is left in the old file, then switch to the new one
+		lines, more, err := ff.readNew()
+		if err != nil || more {
+			return lines, more, err
+		}
+		lines = append(lines, ff.flushPartial()...)
+		ff.close()
+		if err := ff.open(); err != nil {
+			return lines, false, err
+		}
+		return lines, true, nil
+	} else if err == nil && info.Size() < ff.offset {
+		// Truncated: start over from the beginning
+		ff.offset = 0
+		ff.partial = nil
+	} else if err == nil && info.Size() == ff.offset {
+		// Nothing new since the last poll
+		return nil, false, nil
+	}
+	return ff.readNew()
+}
+
+func (ff *followedFile) readNew() ([]string, bool, error) {
+	if ff.file == nil {
+		return nil, false, nil
+	}
+	if _, err := ff.file.Seek(ff.offset, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	if ff.buf == nil {
+		ff.buf = make([]byte, followReadSize)
+	}
+	data := ff.buf
+	n, err := io.ReadFull(ff.file, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, false, err
+	}
+	data = data[:n]
+	ff.offset += int64(n)
+
+	var lines []string
+	data = append(ff.partial, data...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		lines = append(lines, string(bytes.TrimSuffix(data[:idx], []byte("\r"))))
+		data = data[idx+1:]
+	}
+	ff.partial = append([]byte(nil), data...)
+	if len(ff.partial) >= maxLineLength {
+		lines = append(lines, ff.flushPartial()...)
+	}
+	return lines, n == followReadSize, nil
+}
+
+func (ff *followedFile) flushPartial() []string {
+	if len(ff.partial) == 0 {
+		return nil
+	}
+	line := string(ff.partial)
+	ff.partial = nil
+	return []string{line}
+}
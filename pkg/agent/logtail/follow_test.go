@@ -0,0 +1,204 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "deeptrace/v1"
+)
+
+type collector struct {
+	mu    sync.Mutex
+	lines map[string][]string
+}
+
+func (c *collector) send(rankLog *pb.RankLog) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range rankLog.Entries {
+		c.lines[rankLog.Rank] = append(c.lines[rankLog.Rank], entry.Message)
+	}
+	return nil
+}
+
+func (c *collector) get(rank string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines[rank]...)
+}
+
+func (c *collector) waitFor(t *testing.T, rank string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if lines := c.get(rank); len(lines) >= n {
+			return lines
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d lines of %s, got %v", n, rank, c.get(rank))
+	return nil
+}
+
+func appendLines(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, line := range lines {
+		fmt.Fprintln(file, line)
+	}
+}
+
+func TestFollower_Follow(t *testing.T) {
+	tmpDir := t.TempDir()
+	logDir := filepath.Join(tmpDir, "20230101_120000")
+	os.Mkdir(logDir, 0755)
+	rank0 := filepath.Join(logDir, "rank0.log")
+	appendLines(t, rank0, "old 1", "old 2", "old 3")
+
+	follower := &Follower{
+		workDir:      tmpDir,
+		tailLines:    2,
+		pollInterval: 10 * time.Millisecond,
+	}
+	c := &collector{lines: map[string][]string{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- follower.Follow(ctx, c.send) }()
+
+	// Tail replay
+	c.waitFor(t, "RANK0", 2)
+
+	// Appended lines, a partial line is held back until completed
+	appendLines(t, rank0, "new 1")
+	file, _ := os.OpenFile(rank0, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString("part")
+	file.Close()
+	c.waitFor(t, "RANK0", 3)
+	appendLines(t, rank0, "ial")
+	c.waitFor(t, "RANK0", 4)
+
+	// Rank log created after following started is read from the beginning
+	appendLines(t, filepath.Join(logDir, "rank1.log"), "rank1 first")
+	c.waitFor(t, "RANK1", 1)
+
+	// Truncation
+	os.WriteFile(rank0, []byte("after truncate\n"), 0644)
+	c.waitFor(t, "RANK0", 5)
+
+	// Rotation
+	os.Rename(rank0, rank0+".1")
+	appendLines(t, rank0, "after rotate")
+	lines := c.waitFor(t, "RANK0", 6)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Follow() error = %v", err)
+	}
+
+	want := []string{"old 2", "old 3", "new 1", "partial", "after truncate", "after rotate"}
+	if fmt.Sprint(lines) != fmt.Sprint(want) {
+		t.Errorf("Follow() lines = %v, want %v", lines, want)
+	}
+}
+
+func TestFollower_FollowNoLogDir(t *testing.T) {
+	follower := NewFollower(&pb.FollowLogsRequest{WorkDir: filepath.Join(t.TempDir(), "missing")})
+	if err := follower.Follow(context.Background(), func(*pb.RankLog) error { return nil }); err == nil {
+		t.Error("Follow() should fail when the work dir does not exist")
+	}
+}
+
+func TestFollowedFile_pollChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rank0.log")
+	ff := &followedFile{path: path}
+	appendLines(t, path, "start")
+	if err := ff.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer ff.close()
+
+	// A burst, and a rewrite after truncation, are read followReadSize bytes at a time
+	line := fmt.Sprintf("%0100d", 0)
+	for _, rewrite := range []bool{false, true} {
+		// The rewrite is shorter, so it is seen as a truncation
+		chunks := 4
+		if rewrite {
+			chunks = 3
+		}
+		burst := make([]string, chunks*followReadSize/len(line))
+		for i := range burst {
+			burst[i] = line
+		}
+		if rewrite {
+			os.WriteFile(path, nil, 0644)
+		}
+		appendLines(t, path, burst...)
+
+		var got []string
+		polls := 0
+		for more := true; more; polls++ {
+			lines, m, err := ff.poll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) > followReadSize/len(line) {
+				t.Fatalf("poll() read %d lines at once", len(lines))
+			}
+			got, more = append(got, lines...), m
+		}
+		if !rewrite {
+			got = got[1:]
+		}
+		if len(got) != len(burst) || polls < 3 {
+			t.Errorf("rewrite %v: poll() read %d lines in %d polls, want %d lines in at least 3", rewrite, len(got), polls, len(burst))
+		}
+	}
+}
+
+func TestFollower_discoverDropsMissing(t *testing.T) {
+	dir := t.TempDir()
+	rank0, rank1 := filepath.Join(dir, "rank0.log"), filepath.Join(dir, "rank1.log")
+	appendLines(t, rank0, "step 1")
+	appendLines(t, rank1, "step 1")
+
+	f := NewFollower(nil)
+	files := map[string]*followedFile{}
+	defer func() {
+		for _, ff := range files {
+			ff.close()
+		}
+	}()
+	send := func(*pb.RankLog) error { return nil }
+	if err := f.discover(context.Background(), map[int]string{0: rank0, 1: rank1}, files, true, send); err != nil {
+		t.Fatal(err)
+	}
+	dropped := files[rank1]
+
+	// Rank 1's log is no longer found in the same run directory
+	if err := f.discover(context.Background(), map[int]string{0: rank0}, files, false, send); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files[rank1]; ok || len(files) != 1 {
+		t.Errorf("files = %v, want only %s", files, rank0)
+	}
+	if dropped.file != nil {
+		t.Error("dropped rank log not closed")
+	}
+
+	// Polls without new data read nothing
+	if lines, more, err := files[rank0].poll(); err != nil || len(lines) != 0 || more {
+		t.Errorf("poll() = %v, %v, %v, want nothing", lines, more, err)
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Placeholders of LogLayout.RankFile
+const (
+	rankPlaceholder      = "{rank}"
+	localRankPlaceholder = "{local_rank}"
+	hostnamePlaceholder  = "{hostname}"
+)
+
+// LogLayout describes where the rank logs of a run are located under the work dir
+type LogLayout struct {
+	Name string `mapstructure:"name"`
+	// Glob relative to the work dir, the most recently modified matching directory is the run directory
+	RunDir string `mapstructure:"run_dir"`
+	// Path of a rank log relative to the run directory. It may contain glob patterns and
+	// the placeholders {rank}, {local_rank} and {hostname}.
+	RankFile string `mapstructure:"rank_file"`
+}
+
+// Layout written by XTuner: <work dir>/<timestamp>/rank<n>.log
+var defaultLogLayout = LogLayout{Name: "default", RunDir: "*", RankFile: "rank{rank}.log"}
+
+var (
+	layoutMu   sync.RWMutex
+	logLayouts = []LogLayout{defaultLogLayout}
+)
+
+// Rank logs located by a layout
+type resolvedLogs struct {
+	layout LogLayout
+	runDir string
+	// Global rank to log file, only files that exist
+	files map[int]string
+	// Global rank of every local rank, nil when the ranks of this node are unknown
+	localRanks map[int]int
+}
+
+// SetLogLayouts sets the layouts tried in order when locating rank logs. The default
+// layout is tried last unless a layout named "default" replaces it.
+func SetLogLayouts(layouts []LogLayout) error {
+	result := make([]LogLayout, 0, len(layouts)+1)
+	hasDefault := false
+	for _, layout := range layouts {
+		if err := layout.validate(); err != nil {
+			return err
+		}
+		hasDefault = hasDefault || layout.Name == defaultLogLayout.Name
+		result = append(result, layout)
+	}
+	if !hasDefault {
+		result = append(result, defaultLogLayout)
+	}
+
+	layoutMu.Lock()
+	defer layoutMu.Unlock()
+	logLayouts = result
+	return nil
+}
+
+func currentLogLayouts() []LogLayout {
+	layoutMu.RLock()
+	defer layoutMu.RUnlock()
+	return logLayouts
+}
+
+func (l LogLayout) validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("log layout name is empty")
+	}
+	if l.RunDir == "" || l.RankFile == "" {
+		return fmt.Errorf("log layout %s: run_dir and rank_file are required", l.Name)
+	}
+	if _, err := filepath.Match(l.RunDir, ""); err != nil {
+		return fmt.Errorf("log layout %s: invalid run_dir: %v", l.Name, err)
+	}
+	if !l.usesRank() && !l.usesLocalRank() {
+		return fmt.Errorf("log layout %s: rank_file needs a %s or %s placeholder", l.Name, rankPlaceholder, localRankPlaceholder)
+	}
+	if _, err := filepath.Match(l.expand(0, 0, ""), ""); err != nil {
+		return fmt.Errorf("log layout %s: invalid rank_file: %v", l.Name, err)
+	}
+	return nil
+}
+
+func (l LogLayout) usesRank() bool {
+	return strings.Contains(l.RankFile, rankPlaceholder)
+}
+
+func (l LogLayout) usesLocalRank() bool {
+	return strings.Contains(l.RankFile, localRankPlaceholder)
+}
+
+// Rank file pattern with the placeholders replaced
+func (l LogLayout) expand(rank, localRank int, hostname string) string {
+	return strings.NewReplacer(
+		rankPlaceholder, strconv.Itoa(rank),
+		localRankPlaceholder, strconv.Itoa(localRank),
+		hostnamePlaceholder, hostname,
+	).Replace(l.RankFile)
+}
+
+// Most recently modified directory matching the run dir glob
+func (l LogLayout) findRunDir(workDir string) (string, error) {
+	pattern := l.RunDir
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(escapeGlob(workDir), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestInfo os.FileInfo
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if latestInfo == nil || info.ModTime().After(latestInfo.ModTime()) {
+			latest, latestInfo = match, info
+		}
+	}
+	if latestInfo == nil {
+		return "", fmt.Errorf("no log directories found for %s", pattern)
+	}
+	return latest, nil
+}
+
+// Find the existing rank logs in the run directory. With several files for a rank
+// the most recently modified one is used. {local_rank} is mapped to the global rank
+// with localRanks, files of local ranks without a training process are skipped. When
+// the ranks are unknown the local rank is used as the rank.
+func (l LogLayout) discover(runDir string, localRanks map[int]int) (map[int]string, error) {
+	hostname, _ := os.Hostname()
+	glob := strings.NewReplacer(
+		rankPlaceholder, "*",
+		localRankPlaceholder, "*",
+		hostnamePlaceholder, escapeGlob(hostname),
+	).Replace(l.RankFile)
+	matches, err := filepath.Glob(filepath.Join(escapeGlob(runDir), glob))
+	if err != nil {
+		return nil, err
+	}
+	reg, err := l.pathRegexp(runDir, hostname)
+	if err != nil {
+		return nil, err
+	}
+	rankIdx, localIdx := reg.SubexpIndex("rank"), reg.SubexpIndex("local_rank")
+
+	files := make(map[int]string)
+	modTimes := make(map[int]int64)
+	for _, match := range matches {
+		groups := reg.FindStringSubmatch(match)
+		if groups == nil {
+			continue
+		}
+		var rank int
+		if rankIdx > 0 {
+			rank, _ = strconv.Atoi(groups[rankIdx])
+		} else {
+			rank, _ = strconv.Atoi(groups[localIdx])
+			if localRanks != nil {
+				var ok bool
+				if rank, ok = localRanks[rank]; !ok {
+					continue
+				}
+			}
+		}
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if _, ok := files[rank]; !ok || info.ModTime().UnixNano() > modTimes[rank] {
+			files[rank] = match
+			modTimes[rank] = info.ModTime().UnixNano()
+		}
+	}
+	return files, nil
+}
+
+// Regular expression matching rank log paths and capturing the rank numbers
+func (l LogLayout) pathRegexp(runDir, hostname string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	sb.WriteString(regexp.QuoteMeta(filepath.Clean(runDir) + string(filepath.Separator)))
+	captured := map[string]bool{}
+	tmpl := l.RankFile
+	for len(tmpl) > 0 {
+		switch {
+		case strings.HasPrefix(tmpl, rankPlaceholder), strings.HasPrefix(tmpl, localRankPlaceholder):
+			placeholder := rankPlaceholder
+			if strings.HasPrefix(tmpl, localRankPlaceholder) {
+				placeholder = localRankPlaceholder
+			}
+			name := strings.Trim(placeholder, "{}")
+			if captured[name] {
+				sb.WriteString(`\d+`)
+			} else {
+				sb.WriteString(`(?P<` + name + `>\d+)`)
+				captured[name] = true
+			}
+			tmpl = tmpl[len(placeholder):]
+		case strings.HasPrefix(tmpl, hostnamePlaceholder):
+			sb.WriteString(regexp.QuoteMeta(hostname))
+			tmpl = tmpl[len(hostnamePlaceholder):]
+		case tmpl[0] == '*':
+			sb.WriteString(`[^/]*`)
+			tmpl = tmpl[1:]
+		case tmpl[0] == '?':
+			sb.WriteString(`[^/]`)
+			tmpl = tmpl[1:]
+		case tmpl[0] == '[':
+			// Character class, glob and regexp share the syntax apart from negation
+			end := strings.IndexByte(tmpl, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("log layout %s: unterminated character class", l.Name)
+			}
+			sb.WriteString(strings.Replace(tmpl[:end+1], "[!", "[^", 1))
+			tmpl = tmpl[end+1:]
+		default:
+			sb.WriteString(regexp.QuoteMeta(tmpl[:1]))
+			tmpl = tmpl[1:]
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Path of the log of a rank, the discovered file or the expected path when it is missing
+func (r *resolvedLogs) rankFile(rank int) string {
+	if path, ok := r.files[rank]; ok {
+		return path
+	}
+	hostname, _ := os.Hostname()
+	return filepath.Join(r.runDir, r.layout.expand(rank, r.localRank(rank), hostname))
+}
+
+// Local rank of a global rank, the rank itself when it is not on this node
+func (r *resolvedLogs) localRank(rank int) int {
+	for local, global := range r.localRanks {
+		if global == rank {
+			return local
+		}
+	}
+	return rank
+}
+
+// Ranks with a log file, sorted
+func (r *resolvedLogs) ranks() []int {
+	ranks := make([]int, 0, len(r.files))
+	for rank := range r.files {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	return ranks
+}
+
+// Locate the rank logs with the first layout that finds any. When none does, the
+// first layout with a run directory is used so missing files are reported per rank.
+func resolveRankLogs(workDir string, localRanks map[int]int) (*resolvedLogs, error) {
+	var fallback *resolvedLogs
+	var firstErr error
+	for _, layout := range currentLogLayouts() {
+		runDir, err := layout.findRunDir(workDir)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		files, err := layout.discover(runDir, localRanks)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		resolved := &resolvedLogs{layout: layout, runDir: runDir, files: files, localRanks: localRanks}
+		if len(files) > 0 {
+			return resolved, nil
+		}
+		if fallback == nil {
+			fallback = resolved
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, firstErr
+}
+
+// Escape glob meta characters of a literal path
+func escapeGlob(path string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(path)
+}
+
+// ResolveLogFiles reports the log file used for every rank without reading it
+func ResolveLogFiles(ctx context.Context, req *pb.ResolveLogFilesRequest) (*pb.ResolveLogFilesResponse, error) {
+	workDir := os.Getenv("WORK_DIR")
+	if req != nil && req.WorkDir != "" {
+		workDir = req.WorkDir
+	}
+	resp := &pb.ResolveLogFilesResponse{WorkDir: workDir}
+
+	nodeRanks, localRanks, rankErr := getNodeRanks(ctx)
+	if rankErr != nil {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("Failed to get the ranks of this node, only existing files are listed: %v", rankErr))
+	}
+	resolved, err := resolveRankLogs(workDir, localRanks)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Log directory not found: %v", err)
+	}
+	resp.Layout = resolved.layout.Name
+	resp.RunDir = resolved.runDir
+
+	ranks := make(map[int]struct{})
+	for _, rank := range nodeRanks {
+		ranks[rank] = struct{}{}
+	}
+	for rank := range resolved.files {
+		if _, ok := ranks[rank]; !ok && rankErr == nil {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("RANK%d has a log file but no training process on this node", rank))
+		}
+		ranks[rank] = struct{}{}
+	}
+	sorted := make([]int, 0, len(ranks))
+	for rank := range ranks {
+		sorted = append(sorted, rank)
+	}
+	sort.Ints(sorted)
+
+	for _, rank := range sorted {
+		file := &pb.ResolvedLogFile{
+			Rank: fmt.Sprintf("RANK%d", rank),
+			Path: resolved.rankFile(rank),
+		}
+		if info, err := os.Stat(file.Path); err == nil {
+			file.Exists = true
+			file.Size = info.Size()
+			file.ModTime = timestamppb.New(info.ModTime())
+		}
+		resp.Files = append(resp.Files, file)
+	}
+	sort.Strings(resp.Warnings)
+	return resp, nil
+}
@@ -0,0 +1,205 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logtail
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func touch(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetLogLayouts(t *testing.T) {
+	t.Cleanup(func() { logLayouts = []LogLayout{defaultLogLayout} })
+
+	tests := []struct {
+		name      string
+		layouts   []LogLayout
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name:      "default only",
+			layouts:   nil,
+			wantNames: []string{"default"},
+		},
+		{
+			name:      "custom before default",
+			layouts:   []LogLayout{{Name: "torchrun", RunDir: "*/attempt_*", RankFile: "{local_rank}/stderr.log"}},
+			wantNames: []string{"torchrun", "default"},
+		},
+		{
+			name:      "replace default",
+			layouts:   []LogLayout{{Name: "default", RunDir: "logs", RankFile: "rank_{rank}.txt"}},
+			wantNames: []string{"default"},
+		},
+		{name: "no name", layouts: []LogLayout{{RunDir: "*", RankFile: "{rank}.log"}}, wantErr: true},
+		{name: "no rank placeholder", layouts: []LogLayout{{Name: "a", RunDir: "*", RankFile: "out.log"}}, wantErr: true},
+		{name: "bad run dir", layouts: []LogLayout{{Name: "a", RunDir: "[", RankFile: "{rank}.log"}}, wantErr: true},
+		{name: "bad rank file", layouts: []LogLayout{{Name: "a", RunDir: "*", RankFile: "[{rank}.log"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetLogLayouts(tt.layouts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetLogLayouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var names []string
+			for _, layout := range currentLogLayouts() {
+				names = append(names, layout.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("layouts = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func Test_resolveRankLogs(t *testing.T) {
+	t.Cleanup(func() { logLayouts = []LogLayout{defaultLogLayout} })
+	hostname, _ := os.Hostname()
+	old := time.Now().Add(-time.Hour)
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		layouts    []LogLayout
+		files      []string // Relative to the work dir, the last one is the newest
+		localRanks map[int]int
+		wantLayout string
+		wantRunDir string
+		wantFiles  map[int]string
+		wantErr    bool
+	}{
+		{
+			name:       "default layout picks the latest run",
+			files:      []string{"20250101/rank0.log", "20250102/rank0.log", "20250102/rank1.log"},
+			wantLayout: "default",
+			wantRunDir: "20250102",
+			wantFiles:  map[int]string{0: "20250102/rank0.log", 1: "20250102/rank1.log"},
+		},
+		{
+			name:       "per node layout with hostname and local ranks",
+			layouts:    []LogLayout{{Name: "per-node", RunDir: "logs/*/*", RankFile: "node-{hostname}/worker_{local_rank}.out"}},
+			files:      []string{"logs/job/0711/node-other/worker_0.out", "logs/job/0711/node-" + hostname + "/worker_0.out", "logs/job/0711/node-" + hostname + "/worker_1.out"},
+			localRanks: map[int]int{0: 8, 1: 9},
+			wantLayout: "per-node",
+			wantRunDir: "logs/job/0711",
+			wantFiles:  map[int]string{8: "logs/job/0711/node-" + hostname + "/worker_0.out", 9: "logs/job/0711/node-" + hostname + "/worker_1.out"},
+		},
+		{
+			name:       "local ranks of interleaved ranks",
+			layouts:    []LogLayout{{Name: "torchrun", RunDir: "*/attempt_*", RankFile: "{local_rank}/stderr.log"}},
+			files:      []string{"run_abc/attempt_0/0/stderr.log", "run_abc/attempt_0/1/stderr.log", "run_abc/attempt_0/2/stderr.log"},
+			localRanks: map[int]int{0: 1, 1: 3},
+			wantLayout: "torchrun",
+			wantRunDir: "run_abc/attempt_0",
+			// Local rank 2 has no training process
+			wantFiles: map[int]string{1: "run_abc/attempt_0/0/stderr.log", 3: "run_abc/attempt_0/1/stderr.log"},
+		},
+		{
+			name:       "torchrun layout",
+			layouts:    []LogLayout{{Name: "torchrun", RunDir: "*/attempt_*", RankFile: "{local_rank}/stderr.log"}},
+			files:      []string{"run_abc/attempt_0/0/stderr.log", "run_abc/attempt_0/1/stderr.log", "run_abc/attempt_0/1/stdout.log"},
+			wantLayout: "torchrun",
+			wantRunDir: "run_abc/attempt_0",
+			wantFiles:  map[int]string{0: "run_abc/attempt_0/0/stderr.log", 1: "run_abc/attempt_0/1/stderr.log"},
+		},
+		{
+			name:       "glob picks the newest file of a rank",
+			layouts:    []LogLayout{{Name: "glob", RunDir: "out", RankFile: "rank{rank}-*.log"}},
+			files:      []string{"out/rank0-a.log", "out/rank0-b.log"},
+			wantLayout: "glob",
+			wantRunDir: "out",
+			wantFiles:  map[int]string{0: "out/rank0-b.log"},
+		},
+		{
+			name:       "falls back to the default layout",
+			layouts:    []LogLayout{{Name: "torchrun", RunDir: "*/attempt_*", RankFile: "{local_rank}/stderr.log"}},
+			files:      []string{"20250101/rank0.log"},
+			wantLayout: "default",
+			wantRunDir: "20250101",
+			wantFiles:  map[int]string{0: "20250101/rank0.log"},
+		},
+		{
+			name:       "run directory without logs",
+			files:      []string{"20250101/other.txt"},
+			wantLayout: "default",
+			wantRunDir: "20250101",
+			wantFiles:  map[int]string{},
+		},
+		{
+			name:    "no run directory",
+			files:   []string{"plain.txt"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetLogLayouts(tt.layouts); err != nil {
+				t.Fatal(err)
+			}
+			workDir := t.TempDir()
+			for i, file := range tt.files {
+				modTime := old.Add(time.Duration(i) * time.Minute)
+				if i == len(tt.files)-1 {
+					modTime = now
+				}
+				touch(t, filepath.Join(workDir, file), modTime)
+				dir := filepath.Dir(filepath.Join(workDir, file))
+				os.Chtimes(dir, modTime, modTime)
+			}
+
+			resolved, err := resolveRankLogs(workDir, tt.localRanks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRankLogs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resolved.layout.Name != tt.wantLayout || resolved.runDir != filepath.Join(workDir, tt.wantRunDir) {
+				t.Errorf("resolved %s in %s, want %s in %s", resolved.layout.Name, resolved.runDir, tt.wantLayout, tt.wantRunDir)
+			}
+			wantFiles := make(map[int]string)
+			for rank, file := range tt.wantFiles {
+				wantFiles[rank] = filepath.Join(workDir, file)
+			}
+			if !reflect.DeepEqual(resolved.files, wantFiles) {
+				t.Errorf("files = %v, want %v", resolved.files, wantFiles)
+			}
+		})
+	}
+}
+
+func Test_resolvedLogs_rankFile(t *testing.T) {
+	resolved := &resolvedLogs{
+		layout:     LogLayout{Name: "torchrun", RunDir: "*", RankFile: "{local_rank}/stderr.log"},
+		runDir:     "/work/run",
+		files:      map[int]string{8: "/work/run/0/stderr.log"},
+		localRanks: map[int]int{0: 8, 1: 10},
+	}
+	if got := resolved.rankFile(8); got != "/work/run/0/stderr.log" {
+		t.Errorf("rankFile(8) = %s", got)
+	}
+	// Missing ranks get the path they are expected at
+	if got := resolved.rankFile(10); got != "/work/run/1/stderr.log" {
+		t.Errorf("rankFile(10) = %s", got)
+	}
+}
@@ -4,16 +4,83 @@ package logtail
 
 import (
 	"context"
+	"os"
+	"sort"
+	"time"
 
+	"deeptrace/pkg/agent/util/scripts"
 	"deeptrace/pkg/agent/util/textparser"
 	pb "deeptrace/v1"
 )
 
+const (
+	// Lines longer than 1MB are cut
+	maxLineLength = 1024 * 1024
+	// Block size used when scanning rank logs backwards
+	tailBlockSize = 64 * 1024
+	// Bytes read from a followed rank log at a time
+	followReadSize = 256 * 1024
+	// Upper bound of bytes scanned per rank log when a filter is set, older matches are
+	// reported as truncated
+	maxFilterScanBytes = 32 * 1024 * 1024
+	// Unfiltered lines used to determine the latest log time when a filter is set
+	suspendProbeLines = 20
+	// Requested log format meaning detection from the first lines
+	autoLogFormat = "auto"
+)
+
+// Global rank of every local rank of the training processes on this node, replaced in tests
+var getLocalRanks = scripts.GetCurrentNodeLocalRanks
+
+// Ranks of the training processes on this node in ascending order, and the global rank of
+// every local rank
+func getNodeRanks(ctx context.Context) ([]int, map[int]int, error) {
+	localRanks, err := getLocalRanks(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ranks := make([]int, 0, len(localRanks))
+	for _, rank := range localRanks {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	return ranks, localRanks, nil
+}
+
+// Decides whether a line is kept, and whether scanning further back can be skipped
+type lineMatcher func(line string) (keep, stop bool)
+
 type Interface interface {
 	GetRecentLogs(ctx context.Context, maxLines int32) ([]*pb.RankLog, error)
 }
 
 type FileReader struct {
-	workDir   string
-	logParser *textparser.LogParser
+	workDir string
+	// Requested log format, detected per rank log when nil
+	logParser textparser.LogFormat
+	filter    *LogFilter
+	reqErr    error
+}
+
+// Follower streams newly written lines of every rank log
+type Follower struct {
+	workDir      string
+	tailLines    int
+	pollInterval time.Duration
+	logParser    textparser.LogFormat
+	filter       *LogFilter
+	reqErr       error
+}
+
+// Read position of a single followed rank log
+type followedFile struct {
+	rank    int
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	parser  textparser.LogFormat
+	// Read buffer reused across polls
+	buf []byte
 }
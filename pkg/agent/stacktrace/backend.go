@@ -0,0 +1,127 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	pb "deeptrace/v1"
+)
+
+// Name that selects the first available backend
+const BackendAuto = "auto"
+
+// Backend dumps the thread stacks of one process with a particular tool
+type Backend interface {
+	Name() string
+	// Whether the tool is installed on this node
+	Available() bool
+	Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error)
+}
+
+var (
+	backendMu sync.RWMutex
+	// Registered backends in order of preference for auto-selection
+	backends       []Backend
+	defaultBackend = BackendAuto
+)
+
+// Swapped in tests
+var lookPath = exec.LookPath
+
+func init() {
+	RegisterBackend(&pystackBackend{})
+	RegisterBackend(&pyspyBackend{})
+	RegisterBackend(&gdbBackend{})
+	RegisterBackend(newProcBackend("/proc"))
+}
+
+// RegisterBackend adds a backend after the registered ones, replacing one of the same name
+func RegisterBackend(b Backend) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	for i, registered := range backends {
+		if registered.Name() == b.Name() {
+			backends[i] = b
+			return
+		}
+	}
+	backends = append(backends, b)
+}
+
+// SetDefaultBackend sets the backend used when a request names none
+func SetDefaultBackend(name string) error {
+	if name == "" {
+		name = BackendAuto
+	}
+	if name != BackendAuto {
+		if _, err := lookupBackend(name); err != nil {
+			return err
+		}
+	}
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	defaultBackend = name
+	return nil
+}
+
+// SelectBackend returns the named backend, or the first available one for "auto" or an
+// empty name. A named backend must be installed.
+func SelectBackend(name string) (Backend, error) {
+	if name == "" {
+		backendMu.RLock()
+		name = defaultBackend
+		backendMu.RUnlock()
+	}
+	if name != BackendAuto {
+		b, err := lookupBackend(name)
+		if err != nil {
+			return nil, err
+		}
+		if !b.Available() {
+			return nil, fmt.Errorf("stack backend %s is not installed", name)
+		}
+		return b, nil
+	}
+
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	for _, b := range backends {
+		if b.Available() {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("no stack backend is installed")
+}
+
+func lookupBackend(name string) (Backend, error) {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for _, b := range backends {
+		if strings.EqualFold(b.Name(), name) {
+			return b, nil
+		}
+		names = append(names, b.Name())
+	}
+	return nil, fmt.Errorf("unknown stack backend %q, available backends: %s, %s", name, BackendAuto, strings.Join(names, ", "))
+}
+
+// Command killed when the context ends. Output is only waited for briefly after the
+// kill, in case the tool left children holding the pipes.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// Whether an executable is in PATH
+func installed(command string) bool {
+	_, err := lookPath(command)
+	return err == nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Backend returning canned stacks, processes without stacks fail and hung processes
+// block until the context ends
+type fakeBackend struct {
+	stacks map[int][]*pb.ThreadStack
+	hung   map[int]bool
+}
+
+func (b *fakeBackend) Name() string { return "fake" }
+
+func (b *fakeBackend) Available() bool { return true }
+
+func (b *fakeBackend) Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error) {
+	if b.hung[pid] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	threads, ok := b.stacks[pid]
+	if !ok {
+		return nil, fmt.Errorf("process %d not found", pid)
+	}
+	return threads, nil
+}
+
+func TestSelectBackend(t *testing.T) {
+	origLookPath := lookPath
+	defer func() { lookPath = origLookPath }()
+	origDefault := defaultBackend
+	defer func() { defaultBackend = origDefault }()
+
+	tests := []struct {
+		name      string
+		installed []string
+		defaultTo string
+		backend   string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "auto prefers pystack",
+			installed: []string{"gdb", "pystack", "py-spy"},
+			want:      "pystack",
+		},
+		{
+			name:      "auto falls back to py-spy",
+			installed: []string{"gdb", "py-spy"},
+			backend:   "auto",
+			want:      "py-spy",
+		},
+		{
+			name:      "named backend",
+			installed: []string{"gdb", "pystack"},
+			backend:   "gdb",
+			want:      "gdb",
+		},
+		{
+			name:      "configured default",
+			installed: []string{"gdb", "pystack"},
+			defaultTo: "gdb",
+			want:      "gdb",
+		},
+		{
+			name:      "named backend not installed",
+			installed: []string{"pystack"},
+			backend:   "py-spy",
+			wantErr:   true,
+		},
+		{
+			name:    "unknown backend",
+			backend: "perf",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath = func(file string) (string, error) {
+				for _, name := range tt.installed {
+					if name == file {
+						return "/usr/bin/" + file, nil
+					}
+				}
+				return "", fmt.Errorf("%s not found", file)
+			}
+			assert.NoError(t, SetDefaultBackend(tt.defaultTo))
+
+			got, err := SelectBackend(tt.backend)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Name())
+		})
+	}
+}
+
+func Test_procBackend_Dump(t *testing.T) {
+	root := t.TempDir()
+	writeTask := func(tid, stat, comm, wchan, stack string) {
+		dir := filepath.Join(root, "200", "task", tid)
+		assert.NoError(t, os.MkdirAll(dir, 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "wchan"), []byte(wchan), 0644))
+		if stack != "" {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "stack"), []byte(stack), 0644))
+		}
+	}
+	writeTask("200", "200 (python) S 1 200", "python", "futex_wait_queue",
+		"[<0>] futex_wait_queue+0x60/0xa0\n[<0>] futex_wait+0x175/0x260\n[<0>] do_syscall_64+0x5b/0x110\n")
+	writeTask("201", "201 (pt_autograd_0) R 1 200", "pt_autograd_0", "0", "")
+	writeTask("202", "202 (nccl (x)) D 1 200", "nccl (x)", "nvidia_poll", "")
+
+	got, err := newProcBackend(root).Dump(context.Background(), 200, pb.NativeMode_NATIVE_OFF)
+	assert.NoError(t, err)
+	want := []*pb.ThreadStack{
+		{
+			ThreadId:    200,
+			ThreadName:  "python",
+			StackFrames: []string{},
+			Idle:        true,
+			Frames: []*pb.StackFrame{
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "do_syscall_64", Module: "kernel"},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "futex_wait", Module: "kernel"},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "futex_wait_queue", Module: "kernel"},
+			},
+		},
+		{
+			ThreadId:    201,
+			ThreadName:  "pt_autograd_0",
+			StackFrames: []string{},
+		},
+		{
+			ThreadId:    202,
+			ThreadName:  "nccl (x)",
+			StackFrames: []string{},
+			Frames: []*pb.StackFrame{
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "nvidia_poll", Module: "kernel"},
+			},
+		},
+	}
+	assert.Equal(t, want, got)
+
+	_, err = newProcBackend(root).Dump(context.Background(), 300, pb.NativeMode_NATIVE_OFF)
+	assert.Error(t, err)
+}
@@ -0,0 +1,33 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+)
+
+// Native stacks of all threads from gdb, for processes pystack and py-spy can't read
+type gdbBackend struct{}
+
+func (b *gdbBackend) Name() string { return "gdb" }
+
+func (b *gdbBackend) Available() bool { return installed("gdb") }
+
+// gdb always dumps native frames, the mode is ignored
+func (b *gdbBackend) Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error) {
+	cmd := command(ctx, "gdb", gdbArgs(pid)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("gdb error: %v\noutput: %s", err, output)
+	}
+	return textparser.ParseWithType(ctx, &textparser.GDBParser{}, []string{string(output)})
+}
+
+func gdbArgs(pid int) []string {
+	return []string{"-p", strconv.Itoa(pid), "-batch", "-nx", "-ex", "set pagination off", "-ex", "thread apply all bt"}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+)
+
+// Kernel stacks of all threads read from procfs. Needs no tools, only shows where each
+// thread waits in the kernel. Without the privilege to read the stack files, the wait
+// channel is the only frame.
+type procBackend struct {
+	root string
+}
+
+func newProcBackend(root string) *procBackend {
+	return &procBackend{root: root}
+}
+
+func (b *procBackend) Name() string { return "proc" }
+
+func (b *procBackend) Available() bool {
+	_, err := os.Stat(filepath.Join(b.root, "self", "task"))
+	return err == nil
+}
+
+// Kernel frames only, the mode is ignored
+func (b *procBackend) Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error) {
+	taskDir := filepath.Join(b.root, strconv.Itoa(pid), "task")
+	entries, err := os.ReadDir(taskDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read threads of process %d: %v", pid, err)
+	}
+
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	sort.Ints(tids)
+
+	threads := []*pb.ThreadStack{}
+	for _, tid := range tids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dir := filepath.Join(taskDir, strconv.Itoa(tid))
+		// Threads may exit while reading
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue
+		}
+		thread := &pb.ThreadStack{
+			ThreadId:    int32(tid),
+			ThreadName:  readProcString(filepath.Join(dir, "comm")),
+			StackFrames: []string{},
+			// Sleeping interruptibly, a thread in D state is stuck rather than idle
+			Idle: taskState(string(stat)) == "S",
+		}
+		if stack, err := os.ReadFile(filepath.Join(dir, "stack")); err == nil {
+			thread.Frames = textparser.ParseKernelStack(string(stack))
+		}
+		if len(thread.Frames) == 0 {
+			if wchan := readProcString(filepath.Join(dir, "wchan")); wchan != "" && wchan != "0" {
+				thread.Frames = []*pb.StackFrame{{Language: pb.FrameLanguage_FRAME_NATIVE, Function: wchan, Module: "kernel"}}
+			}
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+func readProcString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// State letter of "pid (comm) state ...", the command name may contain parentheses
+func taskState(stat string) string {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return ""
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,46 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+)
+
+// Python and native stacks from py-spy
+type pyspyBackend struct{}
+
+func (b *pyspyBackend) Name() string { return "py-spy" }
+
+func (b *pyspyBackend) Available() bool { return installed("py-spy") }
+
+func (b *pyspyBackend) Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error) {
+	cmd := command(ctx, "py-spy", pySpyArgs(pid, mode)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("py-spy error: %v\noutput: %s", err, stderrOf(err))
+	}
+	return textparser.ParseWithType(ctx, &textparser.PySpyParser{}, []string{string(output)})
+}
+
+// py-spy has no separate mode for threads without Python frames
+func pySpyArgs(pid int, mode pb.NativeMode) []string {
+	args := []string{"dump", "--pid", strconv.Itoa(pid), "--json"}
+	if mode != pb.NativeMode_NATIVE_OFF {
+		args = append(args, "--native")
+	}
+	return args
+}
+
+// Error output of a failed command, the JSON output is read from stdout only
+func stderrOf(err error) []byte {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.Stderr
+	}
+	return nil
+}
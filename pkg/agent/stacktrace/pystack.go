@@ -0,0 +1,48 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"deeptrace/pkg/agent/util/textparser"
+	pb "deeptrace/v1"
+)
+
+// Python and native stacks from pystack
+type pystackBackend struct{}
+
+func (b *pystackBackend) Name() string { return "pystack" }
+
+func (b *pystackBackend) Available() bool { return installed("pystack") }
+
+func (b *pystackBackend) Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error) {
+	output, err := pyStack(ctx, pid, mode)
+	if err != nil {
+		return nil, err
+	}
+	return textparser.ParseWithType(ctx, &textparser.StackParser{}, []string{output})
+}
+
+// Get stack using pystack
+func pyStack(ctx context.Context, pid int, mode pb.NativeMode) (string, error) {
+	cmd := command(ctx, "pystack", pyStackArgs(pid, mode)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("pystack error: %v\noutput: %s", err, output)
+	}
+	return string(output), nil
+}
+
+func pyStackArgs(pid int, mode pb.NativeMode) []string {
+	args := []string{"remote", strconv.Itoa(pid)}
+	switch mode {
+	case pb.NativeMode_NATIVE:
+		args = append(args, "--native")
+	case pb.NativeMode_NATIVE_ALL:
+		args = append(args, "--native-all")
+	}
+	return args
+}
@@ -0,0 +1,72 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pyStack(t *testing.T) {
+	type args struct {
+		pid int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "normal - requires pystack command",
+			args:    args{pid: 1234},
+			want:    "",   // Would depend on pystack output
+			wantErr: true, // Expected to fail if pystack command is not available
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pyStack(context.Background(), tt.args.pid, pb.NativeMode_NATIVE_OFF)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("pyStack() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_pyStackArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		mode pb.NativeMode
+		want []string
+	}{
+		{
+			name: "python frames only",
+			mode: pb.NativeMode_NATIVE_OFF,
+			want: []string{"remote", "1234"},
+		},
+		{
+			name: "native",
+			mode: pb.NativeMode_NATIVE,
+			want: []string{"remote", "1234", "--native"},
+		},
+		{
+			name: "native all threads",
+			mode: pb.NativeMode_NATIVE_ALL,
+			want: []string{"remote", "1234", "--native-all"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pyStackArgs(1234, tt.mode))
+		})
+	}
+}
@@ -6,14 +6,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"deeptrace/logger"
 	"deeptrace/pkg/agent/util/scripts"
-	"deeptrace/pkg/agent/util/textparser"
 	pb "deeptrace/v1"
 
 	"go.uber.org/zap"
@@ -23,95 +21,186 @@ import (
 
 var _ Interface = &PythonStack{}
 
+// Time allowed to dump one process when the request sets none
+const defaultStackTimeout = 20 * time.Second
+
 func NewPythonStack(ctx context.Context, maxConcurrent int, req *pb.GetProcessStacksRequest) Interface {
 
 	return &PythonStack{
 		sem: make(chan struct{}, maxConcurrent),
-		req:        req,
-		textParser: &textparser.StackParser{},
+		req: req,
 	}
 }
 
 // Get process stacks by type
 func (s *PythonStack) GetProcessStacks(ctx context.Context) ([]*pb.ProcessInfo, error) {
 	processesInfo := make([]*pb.ProcessInfo, 0)
-	processes, err := scripts.GetProcessInfo(ctx)
+	if s.backend == nil {
+		backend, err := SelectBackend(s.req.GetBackend())
+		if err != nil {
+			logger.Logger.Error("Failed to select stack backend", zap.Error(err))
+			return nil, status.Errorf(codes.FailedPrecondition, "Failed to select stack backend: %v", err)
+		}
+		s.backend = backend
+	}
+	processes, err := getProcessInfo(ctx)
 	if err != nil {
 		logger.Logger.Error("Failed to get training process information", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "Failed to get training process information: %v", err)
 	}
 
-	// Iterate through each process
+	timeout := defaultStackTimeout
+	if s.req.GetTimeoutSeconds() > 0 {
+		timeout = time.Duration(s.req.GetTimeoutSeconds()) * time.Second
+	}
+
+	// Iterate through each process, every goroutine fills only its own ProcessInfo
 	wg := sync.WaitGroup{}
-	mu := sync.Mutex{}
-	errs := make([]error, len(processes))
 	for _, proc := range processes {
 		proTpe := processType(proc.Type)
-		rank := fmt.Sprintf("RANK%d", proc.Rank)
 		if s.req.ProcessType != 0 && proTpe != s.req.ProcessType {
 			continue
 		}
-		if len(s.req.Rank) != 0 && !strings.EqualFold(s.req.Rank, rank) {
+		if len(s.req.Rank) != 0 && !matchRank(proc, s.req.Rank) {
 			continue
 		}
 		procInfo := &pb.ProcessInfo{
-			Type:      proTpe,
-			Pid:       int32(proc.PID),
-			Ppid:      int32(proc.PPID),
-			Rank:      rank,
-			LocalRank: fmt.Sprintf("RANK%d", proc.LocalRank),
+			Type:    proTpe,
+			Pid:     int32(proc.PID),
+			Ppid:    int32(proc.PPID),
+			Backend: s.backend.Name(),
 		}
+		// A launcher serves several ranks and has none of its own
+		if proTpe != pb.ProcessType_PROCESS_LAUNCHER {
+			procInfo.Rank = fmt.Sprintf("RANK%d", proc.Rank)
+			procInfo.LocalRank = fmt.Sprintf("RANK%d", proc.LocalRank)
+		}
+		processesInfo = append(processesInfo, procInfo)
 
 		wg.Add(1)
-		go func(proc *pb.ProcessInfo) {
+		go func(procInfo *pb.ProcessInfo) {
 			defer wg.Done()
-			// Call Fetch with semaphore control
-			stack, err := s.Fetch(int(proc.Pid))
-			mu.Lock()
-			defer mu.Unlock()
+			procCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
 
+			// Call Fetch with semaphore control
+			threads, err := s.Fetch(procCtx, int(procInfo.Pid))
+			procInfo.Status = stackStatus(ctx, procCtx, err)
 			if err != nil {
-				errs = append(errs, err)
+				procInfo.Error = err.Error()
+				logger.Logger.Warn("Failed to dump process stacks", zap.Int32("pid", procInfo.Pid),
+					zap.String("status", procInfo.Status.String()), zap.Error(err))
 				return
 			}
-
-			if threadsStack, err := textparser.ParseWithType(ctx, s.textParser, []string{stack}); err == nil {
-				procInfo.Threads = threadsStack
-			}
-
-			processesInfo = append(processesInfo, procInfo)
+			procInfo.Threads = threads
 		}(procInfo)
 	}
 
 	wg.Wait()
 
-	return processesInfo, errors.Join(errs...)
+	return processesInfo, nil
 }
 
-func (f *PythonStack) Fetch(pid int) (string, error) {
-	f.sem <- struct{}{}
+// Fetch dumps the threads of a process with the backend, waiting for other dumps of the
+// process and for a free slot unless the context ends first
+func (f *PythonStack) Fetch(ctx context.Context, pid int) ([]*pb.ThreadStack, error) {
+	unlock, err := dumpLocks.lock(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	select {
+	case f.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 	defer func() { <-f.sem }()
 
-	return pyStack(pid)
+	return f.backend.Dump(ctx, pid, f.req.GetNativeMode())
+}
+
+// Dumps of one process are serialized across requests, the watchdog and samples: a process
+// accepts a single ptrace tracer, a second attach fails while the first dump runs
+var dumpLocks = &pidLocks{locks: make(map[int]*pidLock)}
+
+type pidLocks struct {
+	mu    sync.Mutex
+	locks map[int]*pidLock
+}
+
+type pidLock struct {
+	held chan struct{}
+	// Dumps holding or waiting for the lock, it is dropped at 0
+	refs int
+}
+
+// Lock the dumps of pid unless ctx ends first, the returned function unlocks
+func (l *pidLocks) lock(ctx context.Context, pid int) (func(), error) {
+	l.mu.Lock()
+	pl, ok := l.locks[pid]
+	if !ok {
+		pl = &pidLock{held: make(chan struct{}, 1)}
+		l.locks[pid] = pl
+	}
+	pl.refs++
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if pl.refs--; pl.refs == 0 {
+			delete(l.locks, pid)
+		}
+	}
+	select {
+	case pl.held <- struct{}{}:
+		return func() {
+			<-pl.held
+			release()
+		}, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// Status of a dump, telling a timeout of the process apart from the end of the request
+func stackStatus(reqCtx, procCtx context.Context, err error) pb.StackStatus {
+	switch {
+	case err == nil:
+		return pb.StackStatus_STACK_OK
+	case reqCtx.Err() != nil:
+		return pb.StackStatus_STACK_CANCELED
+	case errors.Is(procCtx.Err(), context.DeadlineExceeded):
+		return pb.StackStatus_STACK_TIMEOUT
+	default:
+		return pb.StackStatus_STACK_FAILED
+	}
+}
+
+// Whether the process belongs to the rank, a launcher belongs to the ranks it started
+func matchRank(proc scripts.ProcessInfo, rank string) bool {
+	if proc.Type == scripts.TypeLauncher {
+		for _, r := range proc.Ranks {
+			if strings.EqualFold(rank, fmt.Sprintf("RANK%d", r)) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.EqualFold(rank, fmt.Sprintf("RANK%d", proc.Rank))
 }
 
 func processType(typ string) pb.ProcessType {
 	switch typ {
-	case "trainer":
+	case scripts.TypeLauncher:
+		return pb.ProcessType_PROCESS_LAUNCHER
+	case scripts.TypeTrainer:
 		return pb.ProcessType_PROCESS_TRAINER
-	case "dataloader":
+	case scripts.TypeDataLoader:
 		return pb.ProcessType_PROCESS_DATA_LOADER
 	default:
 		return pb.ProcessType_PROCESS_UNSPECIFIED
 	}
 }
-
-// Get stack using pystack
-func pyStack(pid int) (string, error) {
-	cmd := exec.Command("pystack", "remote", strconv.Itoa(pid))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("pystack error: %v\noutput: %s", err, output)
-	}
-	return string(output), nil
-}
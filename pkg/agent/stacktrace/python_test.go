@@ -3,8 +3,12 @@
 package stacktrace
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
 
+	"deeptrace/pkg/agent/util/scripts"
 	pb "deeptrace/v1"
 
 	"github.com/stretchr/testify/assert"
@@ -29,6 +33,11 @@ func Test_processType(t *testing.T) {
 			args: args{typ: "dataloader"},
 			want: pb.ProcessType_PROCESS_DATA_LOADER,
 		},
+		{
+			name: "launcher process",
+			args: args{typ: "launcher"},
+			want: pb.ProcessType_PROCESS_LAUNCHER,
+		},
 		{
 			name: "unspecified process",
 			args: args{typ: "unknown"},
@@ -48,69 +57,240 @@ func Test_processType(t *testing.T) {
 	}
 }
 
-func TestPythonStack_Fetch(t *testing.T) {
-	type args struct {
-		pid int
+func Test_matchRank(t *testing.T) {
+	tests := []struct {
+		name string
+		proc scripts.ProcessInfo
+		rank string
+		want bool
+	}{
+		{
+			name: "trainer of the rank",
+			proc: scripts.ProcessInfo{Type: scripts.TypeTrainer, Rank: 3},
+			rank: "RANK3",
+			want: true,
+		},
+		{
+			name: "dataloader of another rank",
+			proc: scripts.ProcessInfo{Type: scripts.TypeDataLoader, Rank: 3},
+			rank: "rank4",
+			want: false,
+		},
+		{
+			name: "launcher started the rank",
+			proc: scripts.ProcessInfo{Type: scripts.TypeLauncher, Ranks: []int{2, 3}},
+			rank: "rank3",
+			want: true,
+		},
+		{
+			name: "launcher of other ranks",
+			proc: scripts.ProcessInfo{Type: scripts.TypeLauncher, Ranks: []int{2, 3}},
+			rank: "RANK0",
+			want: false,
+		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchRank(tt.proc, tt.rank))
+		})
+	}
+}
+
+func TestPythonStack_Fetch(t *testing.T) {
+	threads := []*pb.ThreadStack{{ThreadId: 1234, ThreadName: "python"}}
 	tests := []struct {
 		name    string
-		args    args
-		want    string
+		pid     int
+		want    []*pb.ThreadStack
 		wantErr bool
 	}{
 		{
-			name:    "normal - requires pystack",
-			args:    args{pid: 1234},
-			want:    "",   // Would depend on pystack output
-			wantErr: true, // Expected to fail if pystack is not available
+			name: "dumped by the backend",
+			pid:  1234,
+			want: threads,
+		},
+		{
+			name:    "backend error",
+			pid:     4321,
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-
 			ps := &PythonStack{
-				sem: make(chan struct{}, 1),
+				sem:     make(chan struct{}, 1),
+				backend: &fakeBackend{stacks: map[int][]*pb.ThreadStack{1234: threads}},
 			}
 
-			got, err := ps.Fetch(tt.args.pid)
+			got, err := ps.Fetch(context.Background(), tt.pid)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("PythonStack.Fetch() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr {
-				assert.Equal(t, tt.want, got)
-			}
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func Test_pyStack(t *testing.T) {
-	type args struct {
-		pid int
+// Backend recording how many dumps of a process run at once
+type overlapBackend struct {
+	fakeBackend
+	mu      sync.Mutex
+	running map[int]int
+	overlap bool
+}
+
+func (b *overlapBackend) Dump(ctx context.Context, pid int, mode pb.NativeMode) ([]*pb.ThreadStack, error) {
+	b.mu.Lock()
+	b.running[pid]++
+	b.overlap = b.overlap || b.running[pid] > 1
+	b.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	b.mu.Lock()
+	b.running[pid]--
+	b.mu.Unlock()
+	return b.fakeBackend.Dump(ctx, pid, mode)
+}
+
+func TestPythonStack_Fetch_serialized(t *testing.T) {
+	// Separate requests dumping the same process, e.g. a client and the watchdog
+	backend := &overlapBackend{
+		fakeBackend: fakeBackend{stacks: map[int][]*pb.ThreadStack{1234: {{ThreadId: 1234}}}},
+		running:     make(map[int]int),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ps := &PythonStack{sem: make(chan struct{}, 1), backend: backend}
+			_, err := ps.Fetch(context.Background(), 1234)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.False(t, backend.overlap)
+	assert.Empty(t, dumpLocks.locks)
+
+	// A dump waiting for the process gives up when its context ends
+	unlock, err := dumpLocks.lock(context.Background(), 1234)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ps := &PythonStack{sem: make(chan struct{}, 1), backend: backend}
+	_, err = ps.Fetch(ctx, 1234)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	unlock()
+	assert.Empty(t, dumpLocks.locks)
+}
+
+func TestPythonStack_GetProcessStacks(t *testing.T) {
+	processes := []scripts.ProcessInfo{
+		{Type: scripts.TypeLauncher, PID: 100, Launcher: scripts.LauncherTorchrun, Ranks: []int{0, 1}},
+		{Type: scripts.TypeTrainer, PID: 101, PPID: 100, Rank: 0},
+		{Type: scripts.TypeTrainer, PID: 102, PPID: 100, Rank: 1, LocalRank: 1},
+		{Type: scripts.TypeDataLoader, PID: 103, PPID: 101, Rank: 0},
 	}
+	backend := &fakeBackend{stacks: map[int][]*pb.ThreadStack{
+		100: {{ThreadId: 100, ThreadName: "torchrun"}},
+		101: {{ThreadId: 101, ThreadName: "python"}},
+		102: {{ThreadId: 102, ThreadName: "python"}},
+		103: {{ThreadId: 103, ThreadName: "pt_data_worker"}},
+	}}
+	orig := getProcessInfo
+	getProcessInfo = func(ctx context.Context) ([]scripts.ProcessInfo, error) { return processes, nil }
+	defer func() { getProcessInfo = orig }()
+
 	tests := []struct {
-		name    string
-		args    args
-		want    string
-		wantErr bool
+		name string
+		req  *pb.GetProcessStacksRequest
+		want []int32
 	}{
 		{
-			name:    "normal - requires pystack command",
-			args:    args{pid: 1234},
-			want:    "",   // Would depend on pystack output
-			wantErr: true, // Expected to fail if pystack command is not available
+			name: "all processes",
+			req:  &pb.GetProcessStacksRequest{},
+			want: []int32{100, 101, 102, 103},
+		},
+		{
+			name: "trainers",
+			req:  &pb.GetProcessStacksRequest{ProcessType: pb.ProcessType_PROCESS_TRAINER},
+			want: []int32{101, 102},
+		},
+		{
+			name: "one rank with its launcher",
+			req:  &pb.GetProcessStacksRequest{Rank: "RANK1"},
+			want: []int32{100, 102},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := pyStack(tt.args.pid)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("pyStack() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !tt.wantErr {
-				assert.Equal(t, tt.want, got)
+			ps := NewPythonStack(context.Background(), 2, tt.req).(*PythonStack)
+			ps.backend = backend
+
+			got, err := ps.GetProcessStacks(context.Background())
+			assert.NoError(t, err)
+			pids := make([]int32, 0, len(got))
+			for _, proc := range got {
+				assert.Equal(t, backend.stacks[int(proc.Pid)], proc.Threads)
+				assert.Equal(t, "fake", proc.Backend)
+				assert.Equal(t, pb.StackStatus_STACK_OK, proc.Status)
+				pids = append(pids, proc.Pid)
 			}
+			assert.Equal(t, tt.want, pids)
 		})
 	}
 }
+
+func TestPythonStack_GetProcessStacks_noProcesses(t *testing.T) {
+	// A node whose trainers all exited lists no processes, the client counts it as dead
+	orig := getProcessInfo
+	getProcessInfo = func(ctx context.Context) ([]scripts.ProcessInfo, error) { return nil, nil }
+	defer func() { getProcessInfo = orig }()
+
+	ps := NewPythonStack(context.Background(), 2, &pb.GetProcessStacksRequest{ProcessType: pb.ProcessType_PROCESS_TRAINER}).(*PythonStack)
+	ps.backend = &fakeBackend{}
+	got, err := ps.GetProcessStacks(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestPythonStack_GetProcessStacks_partial(t *testing.T) {
+	processes := []scripts.ProcessInfo{
+		{Type: scripts.TypeTrainer, PID: 101, Rank: 0},
+		{Type: scripts.TypeTrainer, PID: 102, Rank: 1},
+		{Type: scripts.TypeTrainer, PID: 103, Rank: 2},
+	}
+	orig := getProcessInfo
+	getProcessInfo = func(ctx context.Context) ([]scripts.ProcessInfo, error) { return processes, nil }
+	defer func() { getProcessInfo = orig }()
+	backend := &fakeBackend{
+		stacks: map[int][]*pb.ThreadStack{101: {{ThreadId: 101, ThreadName: "python"}}},
+		hung:   map[int]bool{103: true},
+	}
+
+	t.Run("per-process timeout", func(t *testing.T) {
+		ps := NewPythonStack(context.Background(), 2, &pb.GetProcessStacksRequest{TimeoutSeconds: 1}).(*PythonStack)
+		ps.backend = backend
+
+		got, err := ps.GetProcessStacks(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, got, 3)
+		assert.Equal(t, pb.StackStatus_STACK_OK, got[0].Status)
+		assert.Equal(t, pb.StackStatus_STACK_FAILED, got[1].Status)
+		assert.Equal(t, "process 102 not found", got[1].Error)
+		assert.Equal(t, pb.StackStatus_STACK_TIMEOUT, got[2].Status)
+		assert.NotEmpty(t, got[2].Error)
+	})
+
+	t.Run("request canceled", func(t *testing.T) {
+		ps := NewPythonStack(context.Background(), 2, &pb.GetProcessStacksRequest{}).(*PythonStack)
+		ps.backend = backend
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		got, err := ps.GetProcessStacks(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, got, 3)
+		assert.Equal(t, pb.StackStatus_STACK_CANCELED, got[2].Status)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"deeptrace/pkg/stackfmt"
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Defaults and limits of SampleProcessStacksRequest
+const (
+	defaultSampleCount    = 5
+	maxSampleCount        = 60
+	defaultSampleInterval = 5 * time.Second
+	maxSampleInterval     = 60 * time.Second
+	maxSampleConcurrent   = 72
+)
+
+// Swapped in tests
+var newSampler = func(req *pb.GetProcessStacksRequest) Interface {
+	return NewPythonStack(context.Background(), maxSampleConcurrent, req)
+}
+
+// SampleProcessStacks takes several stack snapshots of the selected processes and
+// summarizes every thread across them
+func SampleProcessStacks(ctx context.Context, req *pb.SampleProcessStacksRequest) (*pb.SampleProcessStacksResponse, error) {
+	count := int(req.GetCount())
+	if count <= 0 {
+		count = defaultSampleCount
+	}
+	if count > maxSampleCount {
+		return nil, status.Errorf(codes.InvalidArgument, "count must be at most %d", maxSampleCount)
+	}
+	interval := defaultSampleInterval
+	if req.GetIntervalSeconds() > 0 {
+		interval = time.Duration(req.GetIntervalSeconds()) * time.Second
+	}
+	if interval > maxSampleInterval {
+		return nil, status.Errorf(codes.InvalidArgument, "interval must be at most %v", maxSampleInterval)
+	}
+
+	sampler := newSampler(&pb.GetProcessStacksRequest{
+		ProcessType:    req.GetProcessType(),
+		Rank:           req.GetRank(),
+		NativeMode:     req.GetNativeMode(),
+		Backend:        req.GetBackend(),
+		TimeoutSeconds: req.GetTimeoutSeconds(),
+	})
+	resp := &pb.SampleProcessStacksResponse{}
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, status.FromContextError(ctx.Err()).Err()
+			case <-time.After(interval):
+			}
+		}
+		now := time.Now()
+		processes, err := sampler.GetProcessStacks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp.Samples = append(resp.Samples, &pb.StackSample{
+			Time:      timestamppb.New(now),
+			Processes: processes,
+		})
+	}
+	resp.Threads = summarizeSamples(resp.Samples)
+	return resp, nil
+}
+
+// Thread of a process across samples
+type threadKey struct {
+	pid int32
+	tid int32
+}
+
+type threadStats struct {
+	summary *pb.ThreadSummary
+	// Stack of the first sample, to tell whether it changed
+	stack      string
+	changed    bool
+	frameCount map[string]int32
+	frames     map[string]*pb.StackFrame
+	// Position of a frame when first seen, orders frames of the same frequency
+	frameOrder map[string]int
+}
+
+// Count how often each thread and frame was seen, threads ordered by PID and thread ID
+// and frames by frequency, then stack order. Processes whose dump failed don't count
+// as a sample.
+func summarizeSamples(samples []*pb.StackSample) []*pb.ThreadSummary {
+	stats := make(map[threadKey]*threadStats)
+	var keys []threadKey
+	for _, sample := range samples {
+		for _, proc := range sample.Processes {
+			if proc.Status != pb.StackStatus_STACK_OK {
+				continue
+			}
+			for _, thread := range proc.Threads {
+				key := threadKey{pid: proc.Pid, tid: thread.ThreadId}
+				stack := strings.Join(stackfmt.ThreadFrames(thread), "\n")
+				st, ok := stats[key]
+				if !ok {
+					st = &threadStats{
+						summary: &pb.ThreadSummary{
+							Pid:        proc.Pid,
+							Type:       proc.Type,
+							Rank:       proc.Rank,
+							ThreadId:   thread.ThreadId,
+							ThreadName: thread.ThreadName,
+						},
+						stack:      stack,
+						frameCount: make(map[string]int32),
+						frames:     make(map[string]*pb.StackFrame),
+						frameOrder: make(map[string]int),
+					}
+					stats[key] = st
+					keys = append(keys, key)
+				}
+				st.summary.Samples++
+				st.changed = st.changed || st.stack != stack
+
+				// A frame counts once per sample, even in a recursive stack
+				seen := make(map[string]bool)
+				for _, frame := range thread.Frames {
+					formatted := stackfmt.FormatFrame(frame)
+					if seen[formatted] {
+						continue
+					}
+					seen[formatted] = true
+					st.frameCount[formatted]++
+					if _, ok := st.frames[formatted]; !ok {
+						st.frames[formatted] = frame
+						st.frameOrder[formatted] = len(st.frameOrder)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pid != keys[j].pid {
+			return keys[i].pid < keys[j].pid
+		}
+		return keys[i].tid < keys[j].tid
+	})
+	summaries := make([]*pb.ThreadSummary, 0, len(keys))
+	for _, key := range keys {
+		st := stats[key]
+		st.summary.Unchanged = st.summary.Samples > 1 && !st.changed
+		formatted := make([]string, 0, len(st.frameCount))
+		for frame := range st.frameCount {
+			formatted = append(formatted, frame)
+		}
+		sort.Slice(formatted, func(i, j int) bool {
+			fi, fj := formatted[i], formatted[j]
+			if st.frameCount[fi] != st.frameCount[fj] {
+				return st.frameCount[fi] > st.frameCount[fj]
+			}
+			return st.frameOrder[fi] < st.frameOrder[fj]
+		})
+		for _, frame := range formatted {
+			st.summary.Frames = append(st.summary.Frames, &pb.FrameCount{Frame: st.frames[frame], Samples: st.frameCount[frame]})
+		}
+		summaries = append(summaries, st.summary)
+	}
+	return summaries
+}
@@ -0,0 +1,107 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacktrace
+
+import (
+	"context"
+	"testing"
+
+	"deeptrace/pkg/agent/util/scripts"
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_summarizeSamples(t *testing.T) {
+	main := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 120, Function: "<module>"}
+	step := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step"}
+	nccl := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, File: "???", Function: "ncclGroupEnd", Module: "libnccl.so.2"}
+	load := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/data.py", Line: 30, Function: "load"}
+	wait := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/lib/python3.12/threading.py", Line: 355, Function: "wait"}
+
+	process := func(status pb.StackStatus, threads ...*pb.ThreadStack) *pb.ProcessInfo {
+		return &pb.ProcessInfo{Pid: 101, Type: pb.ProcessType_PROCESS_TRAINER, Rank: "RANK0", Status: status, Threads: threads}
+	}
+	samples := []*pb.StackSample{
+		{Processes: []*pb.ProcessInfo{process(pb.StackStatus_STACK_OK,
+			&pb.ThreadStack{ThreadId: 101, ThreadName: "python", Frames: []*pb.StackFrame{main, step, nccl}},
+			&pb.ThreadStack{ThreadId: 102, ThreadName: "loader", Frames: []*pb.StackFrame{load}},
+		)}},
+		{Processes: []*pb.ProcessInfo{process(pb.StackStatus_STACK_OK,
+			&pb.ThreadStack{ThreadId: 101, ThreadName: "python", Frames: []*pb.StackFrame{main, step, nccl}},
+			&pb.ThreadStack{ThreadId: 102, ThreadName: "loader", Frames: []*pb.StackFrame{load, wait}},
+		)}},
+		// Failed dumps are not counted
+		{Processes: []*pb.ProcessInfo{process(pb.StackStatus_STACK_TIMEOUT)}},
+	}
+
+	want := []*pb.ThreadSummary{
+		{
+			Pid: 101, Type: pb.ProcessType_PROCESS_TRAINER, Rank: "RANK0", ThreadId: 101, ThreadName: "python",
+			Samples: 2, Unchanged: true,
+			Frames: []*pb.FrameCount{{Frame: main, Samples: 2}, {Frame: step, Samples: 2}, {Frame: nccl, Samples: 2}},
+		},
+		{
+			Pid: 101, Type: pb.ProcessType_PROCESS_TRAINER, Rank: "RANK0", ThreadId: 102, ThreadName: "loader",
+			Samples: 2, Unchanged: false,
+			Frames: []*pb.FrameCount{{Frame: load, Samples: 2}, {Frame: wait, Samples: 1}},
+		},
+	}
+	assert.Equal(t, want, summarizeSamples(samples))
+
+	// A single sample can't show a thread is stuck
+	single := summarizeSamples(samples[:1])
+	assert.False(t, single[0].Unchanged)
+}
+
+func TestSampleProcessStacks(t *testing.T) {
+	orig := getProcessInfo
+	getProcessInfo = func(ctx context.Context) ([]scripts.ProcessInfo, error) {
+		return []scripts.ProcessInfo{{Type: scripts.TypeTrainer, PID: 101, Rank: 0}}, nil
+	}
+	defer func() { getProcessInfo = orig }()
+	origSampler := newSampler
+	newSampler = func(req *pb.GetProcessStacksRequest) Interface {
+		ps := NewPythonStack(context.Background(), 1, req).(*PythonStack)
+		ps.backend = &fakeBackend{stacks: map[int][]*pb.ThreadStack{101: {{ThreadId: 101, ThreadName: "python"}}}}
+		return ps
+	}
+	defer func() { newSampler = origSampler }()
+
+	tests := []struct {
+		name        string
+		req         *pb.SampleProcessStacksRequest
+		wantSamples int
+		wantErr     bool
+	}{
+		{
+			name:        "one sample",
+			req:         &pb.SampleProcessStacksRequest{Count: 1},
+			wantSamples: 1,
+		},
+		{
+			name:    "too many samples",
+			req:     &pb.SampleProcessStacksRequest{Count: maxSampleCount + 1},
+			wantErr: true,
+		},
+		{
+			name:    "interval too long",
+			req:     &pb.SampleProcessStacksRequest{Count: 2, IntervalSeconds: 3600},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SampleProcessStacks(context.Background(), tt.req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got.Samples, tt.wantSamples)
+			assert.NotNil(t, got.Samples[0].Time)
+			assert.Len(t, got.Threads, 1)
+			assert.Equal(t, int32(101), got.Threads[0].ThreadId)
+		})
+	}
+}
@@ -5,7 +5,7 @@ package stacktrace
 import (
 	"context"
 
-	"deeptrace/pkg/agent/util/textparser"
+	"deeptrace/pkg/agent/util/scripts"
 	pb "deeptrace/v1"
 )
 
@@ -13,8 +13,12 @@ type Interface interface {
 	GetProcessStacks(ctx context.Context) ([]*pb.ProcessInfo, error)
 }
 
+// PythonStack dumps the stacks of the training processes on the node
 type PythonStack struct {
-	sem        chan struct{}
-	req        *pb.GetProcessStacksRequest
-	textParser *textparser.StackParser
+	sem     chan struct{}
+	req     *pb.GetProcessStacksRequest
+	backend Backend
 }
+
+// Swapped in tests
+var getProcessInfo = scripts.GetProcessInfo
@@ -4,114 +4,74 @@ package scripts
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"text/template"
 
 	"deeptrace/logger"
 
 	"go.uber.org/zap"
 )
 
-const processStackTemplate = `#!/bin/bash
+// Process information is read from the procfs of this node
+var procFS = NewProcFS("/proc")
 
-# Get PIDs of all torchrun launcher processes
-launcher_pids=$(pgrep -f torchrun)
-
-# Check if launcher processes are found
-if [ -z "$launcher_pids" ]; then
-    echo '{"error": "No torchrun launcher processes found"}' >&2
-    exit 1
-fi
-
-# Prepare output JSON array
-echo '['
-first_entry=true
-
-# Iterate through each launcher process
-for launcher_pid in $launcher_pids; do
-    # Get direct child processes (training processes) of the launcher
-    train_pids=$(pgrep -P $launcher_pid)
-    
-    # Iterate through each training process
-    for train_pid in $train_pids; do
-        # Get environment variable information of the training process
-        rank=""
-        local_rank=""
-        
-        # Get environment variables from /proc filesystem
-        if [ -f /proc/$train_pid/environ ]; then
-            # Parse RANK and LOCAL_RANK
-            rank=$(cat /proc/$train_pid/environ 2>/dev/null | tr '\0' '\n' | grep "^RANK=" | cut -d= -f2)
-            local_rank=$(cat /proc/$train_pid/environ 2>/dev/null | tr '\0' '\n' | grep "^LOCAL_RANK=" | cut -d= -f2)
-        fi
-        
-        # Output training process if rank information is found
-        if [ -n "$rank" ]; then
-            # Add comma separator (if not the first element)
-            if [ "$first_entry" = true ]; then
-                first_entry=false
-            else
-                echo ','
-            fi
-            
-            # Output training process JSON object
-            echo -n "  {\"type\": \"trainer\", \"pid\": $train_pid, \"ppid\": $launcher_pid, \"rank\": $rank, \"local_rank\": ${local_rank:-\"\"}}"
-            
-            # Get direct child processes (DataLoader workers) of the training process
-            worker_pids=$(pgrep -P $train_pid -x 'pt_data_worker')
-            
-            # Iterate through each DataLoader worker
-            for worker_pid in $worker_pids; do
-                # Add comma separator
-                echo ','
-                
-                # Output DataLoader worker JSON object
-                echo -n "  {\"type\": \"dataloader\", \"pid\": $worker_pid, \"ppid\": $train_pid, \"rank\": $rank, \"local_rank\": ${local_rank:-\"\"}}"
-            done
-        fi
-    done
-done
-
-echo
-echo ']'
-`
-
-// Get training-related process information for the current node
+// Get training-related process information for the current node, empty when no training
+// runs on it
 func GetProcessInfo(ctx context.Context) ([]ProcessInfo, error) {
-	tmpl, err := template.New("rankRange").Parse(processStackTemplate)
-	if err != nil {
-		return nil, err
-	}
-
-	return getTrainingProcesses(ctx, tmpl)
+	return procFS.ProcessInfo(ctx)
 }
 
-func getTrainingProcesses(ctx context.Context, tmpl *template.Template) ([]ProcessInfo, error) {
-	output, err := executeScript(ctx, tmpl)
+// ProcessInfo lists the launchers, trainers and DataLoader workers of the procfs
+func (fs *ProcFS) ProcessInfo(ctx context.Context) ([]ProcessInfo, error) {
+	launchers, err := fs.FindTrainingProcesses(ctx)
 	if err != nil {
-		logger.Logger.Error("Failed to execute process information script",
-			zap.Error(err),
-			zap.String("output", string(output)))
+		logger.Logger.Error("Failed to find training processes", zap.Error(err))
 		return nil, err
 	}
+	return flattenProcesses(launchers), nil
+}
 
-	// Try to parse as error
-	var jsonErr struct {
-		Error string `json:"error"`
-	}
-	if err := json.Unmarshal(output, &jsonErr); err == nil && jsonErr.Error != "" {
-		return nil, fmt.Errorf("get processed error: %s", jsonErr.Error)
-	}
-
-	// Parse as process information array
+// List every launcher followed by its trainers, each followed by its DataLoader workers
+func flattenProcesses(launchers []LauncherProcess) []ProcessInfo {
 	var processes []ProcessInfo
-	if err := json.Unmarshal(output, &processes); err != nil {
-		logger.Logger.Error("Failed to parse process information JSON",
-			zap.Error(err),
-			zap.String("output", string(output)))
-		return nil, fmt.Errorf("Failed to parse JSON: %v", err)
+	for _, launcher := range launchers {
+		info := ProcessInfo{
+			Type:     TypeLauncher,
+			PID:      launcher.PID,
+			PPID:     launcher.PPID,
+			Launcher: launcher.Kind,
+		}
+		for _, trainer := range launcher.Trainers {
+			info.Ranks = append(info.Ranks, trainer.Rank)
+		}
+		processes = append(processes, info)
+
+		for _, trainer := range launcher.Trainers {
+			processes = append(processes, ProcessInfo{
+				Type:      TypeTrainer,
+				PID:       trainer.PID,
+				PPID:      trainer.PPID,
+				Rank:      trainer.Rank,
+				LocalRank: trainer.LocalRank,
+				WorldSize: trainer.WorldSize,
+				Launcher:  launcher.Kind,
+			})
+			for _, worker := range trainer.DataLoaders {
+				processes = append(processes, ProcessInfo{
+					Type:      TypeDataLoader,
+					PID:       worker,
+					PPID:      trainer.PID,
+					Rank:      trainer.Rank,
+					LocalRank: trainer.LocalRank,
+					WorldSize: trainer.WorldSize,
+					Launcher:  launcher.Kind,
+				})
+			}
+		}
 	}
+	return processes
+}
 
-	return processes, nil
+// GetZombieExitStatus returns the exit status of a process of this node that exited but
+// was not reaped yet
+func GetZombieExitStatus(pid int) (ExitStatus, bool) {
+	return procFS.ZombieExitStatus(pid)
 }
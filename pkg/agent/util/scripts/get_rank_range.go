@@ -5,109 +5,77 @@ package scripts
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
-	"text/template"
+	"sort"
 
 	"deeptrace/logger"
 
 	"go.uber.org/zap"
 )
 
-const rankRangeTemplate = `#!/bin/bash
-
-# 1. Find launcher process
-launcher_pid=$(ps aux | grep -m1 "torchrun\|distributed.launch" | grep -v grep | awk '{print $2}')
-
-# Check if launcher process is found
-if [[ -z "$launcher_pid" ]]; then
-    echo "error: No launcher process found"
-    exit 1
-fi
-
-#echo "[Launcher] PID: $launcher_pid"
-
-# 2. Collect Ranks of all training processes
-declare -A train_pids
-ranks=()  # Store all valid Rank values
-found_ranks=0
-
-# Only check direct child processes of the launcher
-for pid in $(pgrep -P $launcher_pid); do
-    # Get process environment variables
-    env_data=$(tr '\0' '\n' < /proc/$pid/environ 2>/dev/null)
-    
-    # Check if RANK variable exists
-    rank=$(grep "^RANK=" <<< "$env_data" | cut -d= -f2)
-    
-    # Check if RANK is valid (numeric)
-    if [[ -n "$rank" && "$rank" =~ ^[0-9]+$ ]]; then
-        gpu_id=$(grep "^LOCAL_RANK=" <<< "$env_data" | cut -d= -f2)
-        train_pids[$rank]="$pid:$gpu_id"
-        #echo "[Train Process] RANK=$rank PID=$pid GPU=$gpu_id"
-        
-        # Add to Rank list
-        ranks+=($rank)
-        ((found_ranks++))
-    fi
-done
-
-# 3. Check if valid Ranks are found
-if [[ $found_ranks -eq 0 ]]; then
-    echo "error: No valid training processes with RANK found"
-    exit 1
-fi
-
-# 4. Calculate Rank range
-# Sort Ranks
-sorted_ranks=($(printf "%s\n" "${ranks[@]}" | sort -n))
-
-min_rank=${sorted_ranks[0]}
-max_rank=${sorted_ranks[${#sorted_ranks[@]}-1]}
-
-# 5. Output Rank range
-echo "$min_rank:$max_rank"
-exit 0
-`
-
 // Get the rank range of the current node
 func GetCurrentNodeRankRange(ctx context.Context) (minNum, maxNum int, err error) {
-	tmpl, err := template.New("rankRange").Parse(rankRangeTemplate)
+	ranks, err := GetCurrentNodeRanks(ctx)
 	if err != nil {
 		return 0, 0, err
 	}
+	logger.Logger.Info("GetCurrentNodeRankRange ", zap.Ints("ranks", ranks))
+	return ranks[0], ranks[len(ranks)-1], nil
+}
 
-	output, err := executeScript(ctx, tmpl)
+// Get the ranks of the training processes on the current node in ascending order.
+// Unlike the rank range, this is exact when ranks on a node are not contiguous.
+func GetCurrentNodeRanks(ctx context.Context) ([]int, error) {
+	processes, err := GetProcessInfo(ctx)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
-	logger.Logger.Info("GetCurrentNodeRankRange ", zap.String("output:", string(output)))
-
-	// Parse output
-	return parseRankRangeOutput(string(output))
-}
-
-// Parse script output
-func parseRankRangeOutput(output string) (minNum, maxNum int, err error) {
-	output = strings.TrimSpace(output)
-	if strings.HasPrefix(output, "error:") {
-		return 0, 0, fmt.Errorf("rank range script error: %s", output)
+	ranks := trainerRanks(processes)
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("No valid training processes with RANK found")
 	}
+	return ranks, nil
+}
 
-	parts := strings.Split(output, ":")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("Invalid output format: %s", output)
+// Unique ranks of the trainer processes, sorted
+func trainerRanks(processes []ProcessInfo) []int {
+	seen := make(map[int]struct{})
+	ranks := make([]int, 0, len(processes))
+	for _, p := range processes {
+		if p.Type != TypeTrainer {
+			continue
+		}
+		if _, ok := seen[p.Rank]; ok {
+			continue
+		}
+		seen[p.Rank] = struct{}{}
+		ranks = append(ranks, p.Rank)
 	}
+	sort.Ints(ranks)
+	return ranks
+}
 
-	minRank, err := strconv.Atoi(parts[0])
+// Get the global rank of every local rank of the training processes on the current node.
+// Ranks may be placed on nodes interleaved, so the global rank is not always local rank 0
+// plus the local rank.
+func GetCurrentNodeLocalRanks(ctx context.Context) (map[int]int, error) {
+	processes, err := GetProcessInfo(ctx)
 	if err != nil {
-		return 0, 0, fmt.Errorf("Failed to parse minRank: %v", err)
+		return nil, err
 	}
-
-	maxRank, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, 0, fmt.Errorf("Failed to parse maxRank: %v", err)
+	localRanks := trainerLocalRanks(processes)
+	if len(localRanks) == 0 {
+		return nil, fmt.Errorf("No valid training processes with RANK found")
 	}
+	return localRanks, nil
+}
 
-	return minRank, maxRank, nil
+// Global rank of every local rank of the trainer processes
+func trainerLocalRanks(processes []ProcessInfo) map[int]int {
+	localRanks := make(map[int]int)
+	for _, p := range processes {
+		if p.Type == TypeTrainer {
+			localRanks[p.LocalRank] = p.Rank
+		}
+	}
+	return localRanks
 }
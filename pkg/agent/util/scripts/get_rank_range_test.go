@@ -4,6 +4,7 @@ package scripts
 
 import (
 	"context"
+	"reflect"
 	"testing"
 )
 
@@ -45,58 +46,57 @@ func TestGetCurrentNodeRankRange(t *testing.T) {
 	}
 }
 
-func Test_parseRankRangeOutput(t *testing.T) {
-	type args struct {
-		output string
-	}
+func Test_trainerRanks(t *testing.T) {
 	tests := []struct {
-		name       string
-		args       args
-		wantMinNum int
-		wantMaxNum int
-		wantErr    bool
+		name      string
+		processes []ProcessInfo
+		want      []int
 	}{
 		{
-			name: "valid range",
-			args: args{
-				output: "0:7",
-			},
-			wantMinNum: 0,
-			wantMaxNum: 7,
-			wantErr:    false,
+			name:      "no processes",
+			processes: nil,
+			want:      []int{},
 		},
 		{
-			name: "invalid format",
-			args: args{
-				output: "invalid",
+			name: "non contiguous ranks with dataloaders",
+			processes: []ProcessInfo{
+				{Type: "trainer", PID: 10, Rank: 12, LocalRank: 1},
+				{Type: "dataloader", PID: 11, PPID: 10, Rank: 12, LocalRank: 1},
+				{Type: "trainer", PID: 20, Rank: 3, LocalRank: 0},
+				{Type: "trainer", PID: 30, Rank: 7, LocalRank: 2},
+				{Type: "dataloader", PID: 31, PPID: 30, Rank: 7, LocalRank: 2},
 			},
-			wantMinNum: 0,
-			wantMaxNum: 0,
-			wantErr:    true,
+			want: []int{3, 7, 12},
 		},
 		{
-			name: "error output",
-			args: args{
-				output: "error: No launcher process found",
+			name: "duplicate ranks",
+			processes: []ProcessInfo{
+				{Type: "trainer", PID: 10, Rank: 0},
+				{Type: "trainer", PID: 11, Rank: 0},
 			},
-			wantMinNum: 0,
-			wantMaxNum: 0,
-			wantErr:    true,
+			want: []int{0},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotMinNum, gotMaxNum, err := parseRankRangeOutput(tt.args.output)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseRankRangeOutput() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if gotMinNum != tt.wantMinNum {
-				t.Errorf("parseRankRangeOutput() gotMinNum = %v, want %v", gotMinNum, tt.wantMinNum)
-			}
-			if gotMaxNum != tt.wantMaxNum {
-				t.Errorf("parseRankRangeOutput() gotMaxNum = %v, want %v", gotMaxNum, tt.wantMaxNum)
+			if got := trainerRanks(tt.processes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trainerRanks() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_trainerLocalRanks(t *testing.T) {
+	// Ranks interleaved across nodes, e.g. node 1 of 2 runs the odd ranks
+	processes := []ProcessInfo{
+		{Type: "trainer", PID: 10, Rank: 1, LocalRank: 0},
+		{Type: "dataloader", PID: 11, PPID: 10, Rank: 1, LocalRank: 0},
+		{Type: "trainer", PID: 20, Rank: 3, LocalRank: 1},
+		{Type: "trainer", PID: 30, Rank: 5, LocalRank: 2},
+		{Type: "launcher", PID: 1, Ranks: []int{1, 3, 5}},
+	}
+	want := map[int]int{0: 1, 1: 3, 2: 5}
+	if got := trainerLocalRanks(processes); !reflect.DeepEqual(got, want) {
+		t.Errorf("trainerLocalRanks() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package scripts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of launchers that start training processes
+const (
+	LauncherTorchrun    = "torchrun"
+	LauncherTorchLaunch = "torch.distributed.launch"
+	LauncherDeepSpeed   = "deepspeed"
+	LauncherMPI         = "mpirun"
+	LauncherSlurm       = "srun"
+)
+
+// Process types of ProcessInfo
+const (
+	TypeLauncher   = "launcher"
+	TypeTrainer    = "trainer"
+	TypeDataLoader = "dataloader"
+)
+
+// Environment variables holding the rank information, in order of preference
+var (
+	rankEnvVars      = []string{"RANK", "OMPI_COMM_WORLD_RANK", "PMIX_RANK", "PMI_RANK", "SLURM_PROCID"}
+	localRankEnvVars = []string{"LOCAL_RANK", "OMPI_COMM_WORLD_LOCAL_RANK", "MPI_LOCALRANKID", "SLURM_LOCALID"}
+	worldSizeEnvVars = []string{"WORLD_SIZE", "OMPI_COMM_WORLD_SIZE", "PMI_SIZE", "SLURM_NTASKS"}
+)
+
+// Shells between a launcher and the trainer, e.g. srun bash -c "python train.py"
+var wrapperComms = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "env": true}
+
+const (
+	// Name of PyTorch DataLoader worker processes
+	dataLoaderComm = "pt_data_worker"
+	// Levels of wrapper processes searched below a launcher
+	maxWrapperDepth = 3
+)
+
+// ProcFS reads process information from a procfs mount
+type ProcFS struct {
+	root string
+}
+
+// A single process read from procfs
+type procEntry struct {
+	pid     int
+	ppid    int
+	comm    string
+	cmdline []string
+}
+
+// LauncherProcess is a launcher and the trainers it started
+type LauncherProcess struct {
+	PID      int
+	PPID     int
+	Kind     string
+	Trainers []TrainerProcess
+}
+
+// TrainerProcess is a training process and its DataLoader workers
+type TrainerProcess struct {
+	PID         int
+	PPID        int
+	Rank        int
+	LocalRank   int
+	WorldSize   int
+	DataLoaders []int
+}
+
+// NewProcFS reads processes below root, normally /proc
+func NewProcFS(root string) *ProcFS {
+	return &ProcFS{root: root}
+}
+
+// FindTrainingProcesses returns the launchers on the node and their trainers, ordered by
+// PID. A launcher without trainers, e.g. torchrun in rendezvous or between worker restarts,
+// is listed unless it only started other launchers. No launcher is not an error.
+func (fs *ProcFS) FindTrainingProcesses(ctx context.Context) ([]LauncherProcess, error) {
+	procs, children, err := fs.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]int, 0, len(procs))
+	for pid := range procs {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+
+	var launchers []LauncherProcess
+	for _, pid := range pids {
+		p := procs[pid]
+		kind := launcherKind(p)
+		if kind == "" {
+			continue
+		}
+		launcher := LauncherProcess{PID: p.pid, PPID: p.ppid, Kind: kind}
+		launcher.Trainers = fs.findTrainers(p.pid, procs, children, 0)
+		if len(launcher.Trainers) == 0 && hasLauncherBelow(p.pid, procs, children) {
+			continue
+		}
+		launchers = append(launchers, launcher)
+	}
+	return launchers, nil
+}
+
+// Whether a descendant of pid is a launcher, e.g. torchrun started by slurmstepd
+func hasLauncherBelow(pid int, procs map[int]*procEntry, children map[int][]int) bool {
+	for _, childPID := range children[pid] {
+		if launcherKind(procs[childPID]) != "" || hasLauncherBelow(childPID, procs, children) {
+			return true
+		}
+	}
+	return false
+}
+
+// Trainers below pid, looking through wrapper shells. Nested launchers are skipped
+// since they are reported on their own.
+func (fs *ProcFS) findTrainers(pid int, procs map[int]*procEntry, children map[int][]int, depth int) []TrainerProcess {
+	var trainers []TrainerProcess
+	for _, childPID := range children[pid] {
+		child := procs[childPID]
+		if launcherKind(child) != "" {
+			continue
+		}
+		if wrapperComms[child.comm] {
+			if depth < maxWrapperDepth {
+				trainers = append(trainers, fs.findTrainers(child.pid, procs, children, depth+1)...)
+			}
+			continue
+		}
+
+		env, err := fs.environ(child.pid)
+		if err != nil {
+			continue
+		}
+		rank, ok := lookupIntEnv(env, rankEnvVars)
+		if !ok {
+			continue
+		}
+		trainer := TrainerProcess{PID: child.pid, PPID: child.ppid, Rank: rank}
+		trainer.LocalRank, _ = lookupIntEnv(env, localRankEnvVars)
+		trainer.WorldSize, _ = lookupIntEnv(env, worldSizeEnvVars)
+		for _, workerPID := range children[child.pid] {
+			if procs[workerPID].comm == dataLoaderComm {
+				trainer.DataLoaders = append(trainer.DataLoaders, workerPID)
+			}
+		}
+		trainers = append(trainers, trainer)
+	}
+	return trainers
+}
+
+// Read all processes and index their children, both ordered by PID. Reading procfs can
+// block on processes stuck in the kernel, the walk stops when ctx ends.
+func (fs *ProcFS) snapshot(ctx context.Context) (map[int]*procEntry, map[int][]int, error) {
+	dirs, err := os.ReadDir(fs.root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	procs := make(map[int]*procEntry)
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil || !dir.IsDir() {
+			continue
+		}
+		// Processes may exit while walking, skip what can't be read
+		p, err := fs.readProcess(pid)
+		if err != nil {
+			continue
+		}
+		procs[pid] = p
+	}
+
+	children := make(map[int][]int)
+	for pid, p := range procs {
+		children[p.ppid] = append(children[p.ppid], pid)
+	}
+	for _, pids := range children {
+		sort.Ints(pids)
+	}
+	return procs, children, nil
+}
+
+func (fs *ProcFS) readProcess(pid int) (*procEntry, error) {
+	dir := filepath.Join(fs.root, strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseStat(string(stat))
+	if err != nil {
+		return nil, err
+	}
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		p.cmdline = splitNul(cmdline)
+	}
+	return p, nil
+}
+
+// Parse "pid (comm) state ppid ...", the command name may contain spaces and parentheses
+func parseStat(stat string) (*procEntry, error) {
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("invalid stat: %q", stat)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:open]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat pid: %v", err)
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid stat: %q", stat)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat ppid: %v", err)
+	}
+	return &procEntry{pid: pid, ppid: ppid, comm: stat[open+1 : end]}, nil
+}
+
+// ExitStatus of a process that exited
+type ExitStatus struct {
+	// Exit code, meaningful when Signal is 0
+	Code int
+	// Signal that terminated the process, 0 if it exited normally
+	Signal int
+}
+
+// ZombieExitStatus reads the exit status of a process that exited but was not reaped by
+// its parent yet. ok is false when the process is running or already gone.
+func (fs *ProcFS) ZombieExitStatus(pid int) (status ExitStatus, ok bool) {
+	stat, err := os.ReadFile(filepath.Join(fs.root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ExitStatus{}, false
+	}
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return ExitStatus{}, false
+	}
+	// Fields after the command name start at the state, the exit code is field 52 of stat
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) == 0 || fields[0] != "Z" {
+		return ExitStatus{}, false
+	}
+	if len(fields) < 50 {
+		return ExitStatus{Code: -1}, true
+	}
+	wstatus, err := strconv.Atoi(fields[49])
+	if err != nil {
+		return ExitStatus{Code: -1}, true
+	}
+	// Encoded like the status of waitpid
+	if sig := wstatus & 0x7f; sig != 0 {
+		return ExitStatus{Code: -1, Signal: sig}, true
+	}
+	return ExitStatus{Code: (wstatus >> 8) & 0xff}, true
+}
+
+func (fs *ProcFS) environ(pid int) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(fs.root, strconv.Itoa(pid), "environ"))
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for _, kv := range splitNul(data) {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	return env, nil
+}
+
+// First variable of names that holds an integer
+func lookupIntEnv(env map[string]string, names []string) (int, bool) {
+	for _, name := range names {
+		if v, err := strconv.Atoi(env[name]); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func splitNul(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(string(data), "\x00")
+}
+
+// Kind of launcher the process is, or an empty string
+func launcherKind(p *procEntry) string {
+	switch p.comm {
+	case "slurmstepd":
+		return LauncherSlurm
+	case "orted", "prted":
+		return LauncherMPI
+	}
+	if len(p.cmdline) == 0 {
+		return ""
+	}
+
+	if kind := launcherByName(filepath.Base(p.cmdline[0])); kind != "" {
+		return kind
+	}
+	if !strings.HasPrefix(filepath.Base(p.cmdline[0]), "python") {
+		return ""
+	}
+	// python [options] -m module ... or python [options] script ...
+	for i := 1; i < len(p.cmdline); i++ {
+		arg := p.cmdline[i]
+		if arg == "-m" && i+1 < len(p.cmdline) {
+			return launcherByModule(p.cmdline[i+1])
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if kind := launcherByName(filepath.Base(arg)); kind != "" {
+			return kind
+		}
+		return launcherByModule(strings.ReplaceAll(strings.TrimSuffix(arg, ".py"), "/", "."))
+	}
+	return ""
+}
+
+// Launcher executables
+func launcherByName(name string) string {
+	switch name {
+	case "torchrun":
+		return LauncherTorchrun
+	case "deepspeed":
+		return LauncherDeepSpeed
+	case "mpirun", "mpiexec", "orterun", "prterun":
+		return LauncherMPI
+	case "srun":
+		return LauncherSlurm
+	}
+	return ""
+}
+
+// Launcher python modules, a script path converted to a dotted name also matches by suffix
+func launcherByModule(module string) string {
+	switch {
+	case strings.HasSuffix(module, "torch.distributed.run"):
+		return LauncherTorchrun
+	case strings.HasSuffix(module, "torch.distributed.launch"):
+		return LauncherTorchLaunch
+	case strings.HasSuffix(module, "deepspeed.launcher.launch"), strings.HasSuffix(module, "deepspeed.launcher.runner"):
+		return LauncherDeepSpeed
+	}
+	return ""
+}
@@ -0,0 +1,282 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package scripts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Process written to a fake procfs root
+type fakeProc struct {
+	pid     int
+	ppid    int
+	comm    string
+	cmdline []string
+	env     []string
+}
+
+func writeFakeProcFS(t *testing.T, procs []fakeProc) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.pid))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		stat := strconv.Itoa(p.pid) + " (" + p.comm + ") S " + strconv.Itoa(p.ppid) + " 1 1 0 -1"
+		os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644)
+		os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(p.cmdline, "\x00")+"\x00"), 0644)
+		os.WriteFile(filepath.Join(dir, "environ"), []byte(strings.Join(p.env, "\x00")+"\x00"), 0644)
+	}
+	// Non process entries are ignored
+	os.MkdirAll(filepath.Join(root, "sys"), 0755)
+	os.WriteFile(filepath.Join(root, "uptime"), []byte("1.0 1.0"), 0644)
+	return root
+}
+
+func python(args ...string) []string {
+	return append([]string{"/usr/bin/python3"}, args...)
+}
+
+func TestProcFS_FindTrainingProcesses(t *testing.T) {
+	tests := []struct {
+		name    string
+		procs   []fakeProc
+		want    []LauncherProcess
+		wantErr bool
+	}{
+		{
+			name: "torchrun with dataloader workers",
+			procs: []fakeProc{
+				{pid: 100, ppid: 1, comm: "torchrun", cmdline: []string{"/usr/bin/python3", "/usr/local/bin/torchrun", "--nproc-per-node=2", "train.py"}},
+				{pid: 101, ppid: 100, comm: "python3", cmdline: python("-u", "train.py"), env: []string{"RANK=8", "LOCAL_RANK=0", "WORLD_SIZE=16"}},
+				{pid: 102, ppid: 100, comm: "python3", cmdline: python("-u", "train.py"), env: []string{"RANK=9", "LOCAL_RANK=1", "WORLD_SIZE=16"}},
+				{pid: 103, ppid: 101, comm: "pt_data_worker", cmdline: python("-u", "train.py"), env: []string{"RANK=8"}},
+				{pid: 104, ppid: 101, comm: "pt_data_worker", cmdline: python("-u", "train.py"), env: []string{"RANK=8"}},
+				{pid: 105, ppid: 102, comm: "python3", cmdline: python("-c", "from multiprocessing"), env: []string{"RANK=9"}},
+			},
+			want: []LauncherProcess{{
+				PID: 100, PPID: 1, Kind: LauncherTorchrun,
+				Trainers: []TrainerProcess{
+					{PID: 101, PPID: 100, Rank: 8, LocalRank: 0, WorldSize: 16, DataLoaders: []int{103, 104}},
+					{PID: 102, PPID: 100, Rank: 9, LocalRank: 1, WorldSize: 16},
+				},
+			}},
+		},
+		{
+			name: "torch.distributed.launch module",
+			procs: []fakeProc{
+				{pid: 200, ppid: 1, comm: "python", cmdline: python("-m", "torch.distributed.launch", "--nproc_per_node=1", "train.py")},
+				{pid: 201, ppid: 200, comm: "python", cmdline: python("train.py"), env: []string{"RANK=0", "LOCAL_RANK=0", "WORLD_SIZE=1"}},
+			},
+			want: []LauncherProcess{{
+				PID: 200, PPID: 1, Kind: LauncherTorchLaunch,
+				Trainers: []TrainerProcess{{PID: 201, PPID: 200, Rank: 0, LocalRank: 0, WorldSize: 1}},
+			}},
+		},
+		{
+			name: "deepspeed runner and per node launcher",
+			procs: []fakeProc{
+				{pid: 300, ppid: 1, comm: "deepspeed", cmdline: []string{"/usr/bin/python3", "/usr/local/bin/deepspeed", "train.py"}},
+				{pid: 301, ppid: 300, comm: "python3", cmdline: python("-u", "-m", "deepspeed.launcher.launch", "--world_info=xxx", "train.py")},
+				{pid: 302, ppid: 301, comm: "python3", cmdline: python("-u", "train.py"), env: []string{"RANK=0", "LOCAL_RANK=0", "WORLD_SIZE=2"}},
+				{pid: 303, ppid: 301, comm: "python3", cmdline: python("-u", "train.py"), env: []string{"RANK=1", "LOCAL_RANK=1", "WORLD_SIZE=2"}},
+			},
+			want: []LauncherProcess{{
+				PID: 301, PPID: 300, Kind: LauncherDeepSpeed,
+				Trainers: []TrainerProcess{
+					{PID: 302, PPID: 301, Rank: 0, LocalRank: 0, WorldSize: 2},
+					{PID: 303, PPID: 301, Rank: 1, LocalRank: 1, WorldSize: 2},
+				},
+			}},
+		},
+		{
+			name: "mpirun with Open MPI variables",
+			procs: []fakeProc{
+				{pid: 400, ppid: 1, comm: "mpirun", cmdline: []string{"mpirun", "-np", "2", "python", "train.py"}},
+				{pid: 401, ppid: 400, comm: "python", cmdline: python("train.py"), env: []string{"OMPI_COMM_WORLD_RANK=4", "OMPI_COMM_WORLD_LOCAL_RANK=0", "OMPI_COMM_WORLD_SIZE=8"}},
+			},
+			want: []LauncherProcess{{
+				PID: 400, PPID: 1, Kind: LauncherMPI,
+				Trainers: []TrainerProcess{{PID: 401, PPID: 400, Rank: 4, LocalRank: 0, WorldSize: 8}},
+			}},
+		},
+		{
+			name: "slurm step with a wrapper shell, batch step only listing the srun client",
+			procs: []fakeProc{
+				{pid: 500, ppid: 1, comm: "slurmstepd", cmdline: []string{"slurmstepd: [42.batch]"}},
+				{pid: 501, ppid: 500, comm: "bash", cmdline: []string{"/bin/bash", "job.sh"}, env: []string{"SLURM_PROCID=0"}},
+				{pid: 502, ppid: 501, comm: "srun", cmdline: []string{"srun", "bash", "-c", "python train.py"}},
+				{pid: 510, ppid: 1, comm: "slurmstepd", cmdline: []string{"slurmstepd: [42.0]"}},
+				{pid: 511, ppid: 510, comm: "bash", cmdline: []string{"bash", "-c", "python train.py"}, env: []string{"SLURM_PROCID=3", "SLURM_LOCALID=1", "SLURM_NTASKS=4"}},
+				{pid: 512, ppid: 511, comm: "python", cmdline: python("train.py"), env: []string{"SLURM_PROCID=3", "SLURM_LOCALID=1", "SLURM_NTASKS=4"}},
+			},
+			want: []LauncherProcess{
+				{PID: 502, PPID: 501, Kind: LauncherSlurm},
+				{
+					PID: 510, PPID: 1, Kind: LauncherSlurm,
+					Trainers: []TrainerProcess{{PID: 512, PPID: 511, Rank: 3, LocalRank: 1, WorldSize: 4}},
+				},
+			},
+		},
+		{
+			name: "torchrun under srun",
+			procs: []fakeProc{
+				{pid: 600, ppid: 1, comm: "slurmstepd", cmdline: []string{"slurmstepd: [43.0]"}},
+				{pid: 601, ppid: 600, comm: "torchrun", cmdline: []string{"/usr/bin/python", "/usr/bin/torchrun", "train.py"}, env: []string{"SLURM_PROCID=0"}},
+				{pid: 602, ppid: 601, comm: "python", cmdline: python("train.py"), env: []string{"SLURM_PROCID=0", "RANK=5", "LOCAL_RANK=1", "WORLD_SIZE=8"}},
+			},
+			want: []LauncherProcess{{
+				PID: 601, PPID: 600, Kind: LauncherTorchrun,
+				Trainers: []TrainerProcess{{PID: 602, PPID: 601, Rank: 5, LocalRank: 1, WorldSize: 8}},
+			}},
+		},
+		{
+			name: "comm with spaces and parentheses",
+			procs: []fakeProc{
+				{pid: 700, ppid: 1, comm: "torchrun", cmdline: []string{"torchrun", "train.py"}},
+				{pid: 701, ppid: 700, comm: "my (trainer) 1", cmdline: python("train.py"), env: []string{"RANK=0"}},
+			},
+			want: []LauncherProcess{{
+				PID: 700, PPID: 1, Kind: LauncherTorchrun,
+				Trainers: []TrainerProcess{{PID: 701, PPID: 700, Rank: 0}},
+			}},
+		},
+		{
+			name: "no launcher",
+			procs: []fakeProc{
+				{pid: 800, ppid: 1, comm: "python", cmdline: python("train.py"), env: []string{"RANK=0"}},
+			},
+		},
+		{
+			name: "launcher without trainers",
+			procs: []fakeProc{
+				{pid: 900, ppid: 1, comm: "torchrun", cmdline: []string{"torchrun", "train.py"}},
+				{pid: 901, ppid: 900, comm: "python", cmdline: python("train.py")},
+			},
+			want: []LauncherProcess{{PID: 900, PPID: 1, Kind: LauncherTorchrun}},
+		},
+		{
+			name: "torchrun in rendezvous under srun",
+			procs: []fakeProc{
+				{pid: 1000, ppid: 1, comm: "slurmstepd", cmdline: []string{"slurmstepd: [44.0]"}},
+				{pid: 1001, ppid: 1000, comm: "bash", cmdline: []string{"bash", "-c", "torchrun train.py"}},
+				{pid: 1002, ppid: 1001, comm: "torchrun", cmdline: []string{"/usr/bin/python", "/usr/bin/torchrun", "train.py"}},
+			},
+			want: []LauncherProcess{{PID: 1002, PPID: 1001, Kind: LauncherTorchrun}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := NewProcFS(writeFakeProcFS(t, tt.procs))
+			got, err := fs.FindTrainingProcesses(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindTrainingProcesses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindTrainingProcesses() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcFS_FindTrainingProcesses_canceled(t *testing.T) {
+	fs := NewProcFS(writeFakeProcFS(t, []fakeProc{
+		{pid: 100, ppid: 1, comm: "torchrun", cmdline: []string{"torchrun", "train.py"}},
+		{pid: 101, ppid: 100, comm: "python", cmdline: python("train.py"), env: []string{"RANK=0"}},
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fs.FindTrainingProcesses(ctx); err != context.Canceled {
+		t.Errorf("FindTrainingProcesses() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func Test_flattenProcesses(t *testing.T) {
+	launchers := []LauncherProcess{{
+		PID: 100, Kind: LauncherTorchrun,
+		Trainers: []TrainerProcess{
+			{PID: 101, PPID: 100, Rank: 2, LocalRank: 0, WorldSize: 4, DataLoaders: []int{103}},
+			{PID: 102, PPID: 100, Rank: 3, LocalRank: 1, WorldSize: 4},
+		},
+	}}
+	want := []ProcessInfo{
+		{Type: TypeLauncher, PID: 100, Launcher: LauncherTorchrun, Ranks: []int{2, 3}},
+		{Type: TypeTrainer, PID: 101, PPID: 100, Rank: 2, LocalRank: 0, WorldSize: 4, Launcher: LauncherTorchrun},
+		{Type: TypeDataLoader, PID: 103, PPID: 101, Rank: 2, LocalRank: 0, WorldSize: 4, Launcher: LauncherTorchrun},
+		{Type: TypeTrainer, PID: 102, PPID: 100, Rank: 3, LocalRank: 1, WorldSize: 4, Launcher: LauncherTorchrun},
+	}
+	if got := flattenProcesses(launchers); !reflect.DeepEqual(got, want) {
+		t.Errorf("flattenProcesses() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_parseStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		stat    string
+		want    *procEntry
+		wantErr bool
+	}{
+		{name: "simple", stat: "42 (python3) S 7 42 42 0 -1", want: &procEntry{pid: 42, ppid: 7, comm: "python3"}},
+		{name: "comm with parentheses", stat: "42 (a) b (c)) R 9 42", want: &procEntry{pid: 42, ppid: 9, comm: "a) b (c)"}},
+		{name: "truncated", stat: "42 (python3) S", wantErr: true},
+		{name: "garbage", stat: "garbage", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStat(tt.stat)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcFS_ZombieExitStatus(t *testing.T) {
+	root := t.TempDir()
+	// Fields 3 to 52 of stat, the state first and the exit code last
+	stat := func(pid int, state string, wstatus int) {
+		fields := []string{state}
+		for i := 4; i < 52; i++ {
+			fields = append(fields, "0")
+		}
+		fields = append(fields, strconv.Itoa(wstatus))
+		dir := filepath.Join(root, strconv.Itoa(pid))
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "stat"), []byte(strconv.Itoa(pid)+" (python3 (x)) "+strings.Join(fields, " ")), 0644)
+	}
+	stat(100, "S", 0)
+	stat(101, "Z", 1<<8)
+	stat(102, "Z", 9)
+
+	tests := []struct {
+		name   string
+		pid    int
+		want   ExitStatus
+		wantOk bool
+	}{
+		{name: "running", pid: 100},
+		{name: "gone", pid: 200},
+		{name: "exit code", pid: 101, want: ExitStatus{Code: 1}, wantOk: true},
+		{name: "killed", pid: 102, want: ExitStatus{Code: -1, Signal: 9}, wantOk: true},
+	}
+	fs := NewProcFS(root)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := fs.ZombieExitStatus(tt.pid)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ZombieExitStatus() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
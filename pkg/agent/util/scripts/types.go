@@ -3,9 +3,13 @@
 package scripts
 
 type ProcessInfo struct {
-	Type      string `json:"type"` // "trainer" / "dataloader"
+	Type      string `json:"type"` // "launcher" / "trainer" / "dataloader"
 	PID       int    `json:"pid"`
 	PPID      int    `json:"ppid"`
 	Rank      int    `json:"rank"`
 	LocalRank int    `json:"local_rank"`
+	WorldSize int    `json:"world_size"`
+	Launcher  string `json:"launcher"` // Kind of launcher that started the trainer
+	// Ranks of the trainers started by a launcher, only set for launchers
+	Ranks []int `json:"ranks,omitempty"`
 }
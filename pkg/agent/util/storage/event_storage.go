@@ -360,7 +360,7 @@ func (s *EventStorage) getCandidateFiles(filter EventFilter) []string {
 		}
 
 		// Event type check
-		if filter.Type != "" && !idx.EventTypes[filter.Type] {
+		if !filter.anyType(idx.EventTypes) {
 			continue
 		}
 
@@ -397,7 +397,7 @@ func (s *EventStorage) loadFile(path string, filter EventFilter) ([]EventEntry,
 			continue
 		}
 
-		if filter.Type != "" && event.Type != filter.Type {
+		if !filter.matchType(event.Type) {
 			continue
 		}
 
@@ -414,7 +414,9 @@ func (s *EventStorage) loadFile(path string, filter EventFilter) ([]EventEntry,
 		}
 
 		// Add to pending update list (do not update file immediately)
-		s.MarkPendingProcessed(event.ID, path)
+		if !filter.Peek {
+			s.MarkPendingProcessed(event.ID, path)
+		}
 		filtered = append(filtered, event)
 	}
 
@@ -428,3 +430,32 @@ func (s *EventStorage) MarkPendingProcessed(eventID, filePath string) {
 		ProcessedAt: time.Now().UnixMilli(),
 	})
 }
+
+// Whether the filter selects events of the type
+func (f EventFilter) matchType(eventType string) bool {
+	if f.Type == "" && len(f.Types) == 0 {
+		return true
+	}
+	if eventType == f.Type {
+		return true
+	}
+	for _, t := range f.Types {
+		if eventType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the filter selects any of the types of an indexed file
+func (f EventFilter) anyType(types map[string]bool) bool {
+	if f.Type == "" && len(f.Types) == 0 {
+		return true
+	}
+	for t := range types {
+		if f.matchType(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,41 @@ import (
 	"time"
 )
 
+func TestEventStorage_LoadEvents_types(t *testing.T) {
+	storage, err := NewEventStorage(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create EventStorage: %v", err)
+	}
+	for _, typ := range []string{"alert", "hang", "audit"} {
+		if _, err := storage.StoreEvent(EventEntry{Type: typ, Message: typ}); err != nil {
+			t.Fatalf("EventStorage.StoreEvent() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   int
+	}{
+		{name: "all types", filter: EventFilter{}, want: 3},
+		{name: "single type", filter: EventFilter{Type: "hang"}, want: 1},
+		{name: "any of types", filter: EventFilter{Types: []string{"alert", "hang"}}, want: 2},
+		{name: "unknown type", filter: EventFilter{Types: []string{"metric"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.EndTime = time.Now().UnixMilli()
+			events, err := storage.LoadEvents(tt.filter)
+			if err != nil {
+				t.Fatalf("EventStorage.LoadEvents() error = %v", err)
+			}
+			if len(events) != tt.want {
+				t.Errorf("EventStorage.LoadEvents() = %d events, want %d", len(events), tt.want)
+			}
+		})
+	}
+}
+
 func TestEventStorage_StoreEvent(t *testing.T) {
 	type args struct {
 		event EventEntry
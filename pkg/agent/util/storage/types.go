@@ -57,7 +57,9 @@ type EventFilter struct {
 	EndTime     int64
 	MinSeverity int32
 	Type        string
+	Types       []string // Any of the types, in addition to Type
 	Source      string
 	JobID       string
 	Unprocessed bool
+	Peek        bool // Leave the loaded events unprocessed
 }
@@ -0,0 +1,142 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"regexp"
+	"strings"
+
+	pb "deeptrace/v1"
+)
+
+// Kinds of fatal errors found in rank logs
+const (
+	ErrorTraceback        = "traceback"
+	ErrorNCCLTimeout      = "nccl_timeout"
+	ErrorCUDAOOM          = "cuda_oom"
+	ErrorCUDA             = "cuda_error"
+	ErrorSegfault         = "segfault"
+	ErrorDistBackendError = "dist_backend_error"
+)
+
+const (
+	tracebackHeader = "Traceback (most recent call last):"
+	// Summaries longer than this are cut
+	maxErrorSummary = 300
+)
+
+type fatalSignature struct {
+	kind string
+	reg  *regexp.Regexp
+}
+
+// Known fatal errors, the first matching signature names the error
+var fatalSignatures = []fatalSignature{
+	{ErrorNCCLTimeout, regexp.MustCompile(`(?i)watchdog caught collective operation timeout|ProcessGroupNCCL.*tim(?:e|ed) ?out|NCCL (?:operation|communicator).*tim(?:e|ed) ?out`)},
+	{ErrorCUDAOOM, regexp.MustCompile(`CUDA out of memory|OutOfMemoryError`)},
+	{ErrorDistBackendError, regexp.MustCompile(`\bDistBackendError\b`)},
+	{ErrorCUDA, regexp.MustCompile(`\bCUDA error\b|\bcudaError\w+`)},
+	{ErrorSegfault, regexp.MustCompile(`Segmentation fault|SIGSEGV`)},
+}
+
+var (
+	// File "/workspace/train.py", line 120, in forward
+	tracebackFrameReg = regexp.MustCompile(`File "([^"]+)", line (\d+), in (\S+)`)
+	// torch.OutOfMemoryError: CUDA out of memory
+	exceptionReg = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?::|$)`)
+)
+
+// ExtractLogErrors finds the Python tracebacks and the lines matching a known fatal
+// signature in log lines, oldest first. A traceback ending in a known signature takes
+// its kind. The same error repeated is reported once with its count.
+func ExtractLogErrors(lines []string) []*pb.LogError {
+	var errs []*pb.LogError
+	seen := make(map[string]*pb.LogError)
+	add := func(e *pb.LogError) {
+		key := e.Kind + "\x00" + e.Summary
+		if prev, ok := seen[key]; ok {
+			prev.Count++
+			return
+		}
+		e.Count = 1
+		seen[key] = e
+		errs = append(errs, e)
+	}
+
+	for i := 0; i < len(lines); {
+		if idx := strings.Index(lines[i], tracebackHeader); idx >= 0 {
+			block := readTraceback(lines[i:], lines[i][:idx])
+			add(newTracebackError(block, lines[i][:idx]))
+			i += len(block)
+			continue
+		}
+		if kind := matchFatalSignature(lines[i]); kind != "" {
+			add(&pb.LogError{Kind: kind, Summary: cutSummary(strings.TrimSpace(lines[i])), Lines: []string{lines[i]}})
+		}
+		i++
+	}
+	return errs
+}
+
+// Lines of the traceback starting at lines[0]. Every line carries the prefix of the
+// header, such as "[rank0]: ", the frames are indented and the exception line ends it.
+func readTraceback(lines []string, prefix string) []string {
+	end := 1
+	for end < len(lines) {
+		if !strings.HasPrefix(lines[end], prefix) {
+			break
+		}
+		body := strings.TrimPrefix(lines[end], prefix)
+		if strings.TrimSpace(body) == "" {
+			break
+		}
+		end++
+		if body[0] != ' ' && body[0] != '\t' {
+			break
+		}
+	}
+	return lines[:end]
+}
+
+func newTracebackError(block []string, prefix string) *pb.LogError {
+	e := &pb.LogError{Kind: ErrorTraceback, Lines: block, Context: make(map[string]string)}
+	body := strings.TrimPrefix(block[len(block)-1], prefix)
+	last := strings.TrimSpace(body)
+	// The exception line is the only one not indented
+	if len(block) > 1 && body[0] != ' ' && body[0] != '\t' {
+		e.Summary = cutSummary(last)
+		if m := exceptionReg.FindStringSubmatch(last); m != nil {
+			e.Context["exception"] = m[1]
+		}
+		if kind := matchFatalSignature(last); kind != "" {
+			e.Kind = kind
+		}
+	} else {
+		// Cut off before the exception line
+		e.Summary = "incomplete traceback"
+	}
+	// The innermost frame is where the exception was raised
+	for i := len(block) - 1; i > 0; i-- {
+		if m := tracebackFrameReg.FindStringSubmatch(block[i]); m != nil {
+			e.Context["location"] = m[1] + ":" + m[2] + " in " + m[3]
+			break
+		}
+	}
+	return e
+}
+
+func matchFatalSignature(line string) string {
+	for _, sig := range fatalSignatures {
+		if sig.reg.MatchString(line) {
+			return sig.kind
+		}
+	}
+	return ""
+}
+
+func cutSummary(s string) string {
+	if len(s) <= maxErrorSummary {
+		return s
+	}
+	return s[:maxErrorSummary] + "..."
+}
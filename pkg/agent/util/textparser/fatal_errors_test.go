@@ -0,0 +1,111 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractLogErrors(t *testing.T) {
+	type want struct {
+		kind      string
+		summary   string
+		lines     int
+		count     int32
+		exception string
+		location  string
+	}
+	tests := []struct {
+		name  string
+		lines []string
+		want  []want
+	}{
+		{
+			name:  "no errors",
+			lines: []string{"[XTuner][RANK 0][2025-07-11 02:32:52][INFO] [Step 10/1000] loss: 2.3"},
+		},
+		{
+			name: "traceback ending in CUDA OOM",
+			lines: []string{
+				"[XTuner][RANK 0][2025-07-11 02:32:52][INFO] [Step 10/1000] loss: 2.3",
+				"Traceback (most recent call last):",
+				`  File "/workspace/train.py", line 120, in <module>`,
+				"    main()",
+				`  File "/workspace/model.py", line 42, in forward`,
+				"    x = self.proj(x)",
+				"torch.OutOfMemoryError: CUDA out of memory. Tried to allocate 2.00 GiB.",
+				"[XTuner][RANK 0][2025-07-11 02:33:00][INFO] exiting",
+			},
+			want: []want{{
+				kind:      ErrorCUDAOOM,
+				summary:   "torch.OutOfMemoryError: CUDA out of memory. Tried to allocate 2.00 GiB.",
+				lines:     6,
+				count:     1,
+				exception: "torch.OutOfMemoryError",
+				location:  "/workspace/model.py:42 in forward",
+			}},
+		},
+		{
+			name: "prefixed tracebacks repeated",
+			lines: []string{
+				"[rank3]: Traceback (most recent call last):",
+				`[rank3]:   File "/workspace/train.py", line 7, in step`,
+				"[rank3]: ValueError: bad batch",
+				"[rank3]: Traceback (most recent call last):",
+				`[rank3]:   File "/workspace/train.py", line 7, in step`,
+				"[rank3]: ValueError: bad batch",
+			},
+			want: []want{{
+				kind:      ErrorTraceback,
+				summary:   "ValueError: bad batch",
+				lines:     3,
+				count:     2,
+				exception: "ValueError",
+				location:  "/workspace/train.py:7 in step",
+			}},
+		},
+		{
+			name: "traceback cut off",
+			lines: []string{
+				"Traceback (most recent call last):",
+				`  File "/workspace/train.py", line 7, in step`,
+			},
+			want: []want{{kind: ErrorTraceback, summary: "incomplete traceback", lines: 2, count: 1, location: "/workspace/train.py:7 in step"}},
+		},
+		{
+			name: "fatal signatures",
+			lines: []string{
+				"[E ProcessGroupNCCL.cpp:563] [Rank 1] Watchdog caught collective operation timeout: WorkNCCL(SeqNum=1201, OpType=ALLREDUCE) ran for 600000 milliseconds before timing out.",
+				"torch.distributed.DistBackendError: NCCL error in: ProcessGroupNCCL.cpp:1275, unhandled system error",
+				"RuntimeError: CUDA error: an illegal memory access was encountered",
+				"Fatal Python error: Segmentation fault",
+			},
+			want: []want{
+				{kind: ErrorNCCLTimeout, lines: 1, count: 1},
+				{kind: ErrorDistBackendError, lines: 1, count: 1},
+				{kind: ErrorCUDA, lines: 1, count: 1},
+				{kind: ErrorSegfault, summary: "Fatal Python error: Segmentation fault", lines: 1, count: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractLogErrors(tt.lines)
+			if !assert.Len(t, got, len(tt.want)) {
+				return
+			}
+			for i, w := range tt.want {
+				assert.Equal(t, w.kind, got[i].Kind)
+				if w.summary != "" {
+					assert.Equal(t, w.summary, got[i].Summary)
+				}
+				assert.Len(t, got[i].Lines, w.lines)
+				assert.Equal(t, w.count, got[i].Count)
+				assert.Equal(t, w.exception, got[i].Context["exception"])
+				assert.Equal(t, w.location, got[i].Context["location"])
+			}
+		})
+	}
+}
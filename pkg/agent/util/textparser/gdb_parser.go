@@ -0,0 +1,76 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"bufio"
+	"context"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "deeptrace/v1"
+)
+
+// GDBParser parses the output of "thread apply all bt" in gdb batch mode
+type GDBParser struct{}
+
+var (
+	// Thread 2 (Thread 0x7f3a2b7fe640 (LWP 1236) "pt_autograd_0"):
+	gdbThreadRegexp = regexp.MustCompile(`^Thread \d+ \(.*?LWP (\d+)\)(?: "(.*)")?\):`)
+	// #1  0x00007f3a4c2b1d5e in ncclGroupEnd () from /usr/lib/libnccl.so.2
+	// #2  main (argc=1, argv=0x7ffd) at main.c:12
+	gdbFrameRegexp    = regexp.MustCompile(`^#\d+\s+(?:0x[0-9a-f]+ in )?([^\s(]+)`)
+	gdbLocationRegexp = regexp.MustCompile(` at (\S+):(\d+)$`)
+	gdbLibraryRegexp  = regexp.MustCompile(` from (\S+)$`)
+)
+
+func (p *GDBParser) Parse(ctx context.Context, inputs []string) ([]*pb.ThreadStack, error) {
+	threads := []*pb.ThreadStack{}
+	for _, input := range inputs {
+		current := (*pb.ThreadStack)(nil)
+		scanner := bufio.NewScanner(strings.NewReader(input))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if matches := gdbThreadRegexp.FindStringSubmatch(line); matches != nil {
+				if current != nil {
+					threads = append(threads, finishGDBThread(current))
+				}
+				tid, _ := strconv.Atoi(matches[1])
+				current = &pb.ThreadStack{ThreadId: int32(tid), ThreadName: matches[2], StackFrames: []string{}}
+				if current.ThreadName == "" {
+					current.ThreadName = "unknown"
+				}
+				continue
+			}
+			if current == nil {
+				continue
+			}
+			if matches := gdbFrameRegexp.FindStringSubmatch(line); matches != nil {
+				frame := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, Function: matches[1]}
+				if loc := gdbLocationRegexp.FindStringSubmatch(line); loc != nil {
+					lineNo, _ := strconv.Atoi(loc[2])
+					frame.File, frame.Line = loc[1], int32(lineNo)
+				}
+				if lib := gdbLibraryRegexp.FindStringSubmatch(line); lib != nil {
+					frame.Module = filepath.Base(lib[1])
+				}
+				current.Frames = append(current.Frames, frame)
+			}
+		}
+		if current != nil {
+			threads = append(threads, finishGDBThread(current))
+		}
+	}
+	return threads, nil
+}
+
+// gdb lists the most recent call first, pystack last
+func finishGDBThread(thread *pb.ThreadStack) *pb.ThreadStack {
+	frames := thread.Frames
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return finishThread(thread)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"context"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGDBParser_Parse(t *testing.T) {
+	output := `[New LWP 1700]
+[Thread debugging using libthread_db enabled]
+0x00007f3a4c2b1d5e in __futex_abstimed_wait_common () from /lib/x86_64-linux-gnu/libc.so.6
+
+Thread 2 (Thread 0x7f3a2b7fe640 (LWP 1700) "pt_autograd_0"):
+#0  0x00007f3a4c2b1d5e in __futex_abstimed_wait_common () from /lib/x86_64-linux-gnu/libc.so.6
+#1  0x00007f3a4c2b4a40 in pthread_cond_wait@@GLIBC_2.3.2 () from /lib/x86_64-linux-gnu/libc.so.6
+#2  0x00007f3a4c6f8b2c in start_thread (arg=<optimized out>) at ./nptl/pthread_create.c:442
+
+Thread 1 (Thread 0x7f3a4c5e1740 (LWP 1654) "python"):
+#0  0x00007f3a4c2e8b7b in sched_yield () from /lib/x86_64-linux-gnu/libc.so.6
+#1  0x00007f3a1a2c3d4e in ncclGroupEnd () at src/group.cc:120
+#2  0x000055d0c1a2b3c4 in main (argc=2, argv=0x7ffd) at main.c:12
+[Inferior 1 (process 1654) detached]`
+
+	got, err := (&GDBParser{}).Parse(context.Background(), []string{output})
+	assert.NoError(t, err)
+	want := []*pb.ThreadStack{
+		{
+			ThreadId:    1700,
+			ThreadName:  "pt_autograd_0",
+			StackFrames: []string{},
+			Idle:        true,
+			Frames: []*pb.StackFrame{
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "start_thread", File: "./nptl/pthread_create.c", Line: 442},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "pthread_cond_wait@@GLIBC_2.3.2", Module: "libc.so.6"},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "__futex_abstimed_wait_common", Module: "libc.so.6"},
+			},
+		},
+		{
+			ThreadId:    1654,
+			ThreadName:  "python",
+			StackFrames: []string{},
+			Frames: []*pb.StackFrame{
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "main", File: "main.c", Line: 12},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "ncclGroupEnd", File: "src/group.cc", Line: 120},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "sched_yield", Module: "libc.so.6"},
+			},
+		},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParseKernelStack(t *testing.T) {
+	stack := "[<0>] futex_wait_queue+0x60/0xa0\n[<0>] futex_wait+0x175/0x260\ngarbage\n"
+	want := []*pb.StackFrame{
+		{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "futex_wait", Module: "kernel"},
+		{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "futex_wait_queue", Module: "kernel"},
+	}
+	assert.Equal(t, want, ParseKernelStack(stack))
+}
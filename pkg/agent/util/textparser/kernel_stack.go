@@ -0,0 +1,33 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+
+	pb "deeptrace/v1"
+)
+
+// Line of /proc/<pid>/task/<tid>/stack, e.g. "[<0>] futex_wait_queue+0x60/0xa0"
+var kernelFrameRegexp = regexp.MustCompile(`^\[<[0-9a-fx]+>\]\s+([^+\s]+)`)
+
+// ParseKernelStack parses a kernel stack from procfs into native frames, ordered like
+// pystack with the most recent call last
+func ParseKernelStack(stack string) []*pb.StackFrame {
+	var frames []*pb.StackFrame
+	scanner := bufio.NewScanner(strings.NewReader(stack))
+	for scanner.Scan() {
+		matches := kernelFrameRegexp.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if matches == nil {
+			continue
+		}
+		frames = append([]*pb.StackFrame{{
+			Language: pb.FrameLanguage_FRAME_NATIVE,
+			Function: matches[1],
+			Module:   "kernel",
+		}}, frames...)
+	}
+	return frames
+}
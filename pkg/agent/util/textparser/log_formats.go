@@ -0,0 +1,292 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "deeptrace/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Names of the built-in log formats
+const (
+	DefaultLogFormat = "xtuner"
+	PythonLogFormat  = "python"
+	LoguruLogFormat  = "loguru"
+	GlogLogFormat    = "glog"
+	JSONLogFormat    = "jsonl"
+)
+
+// Capture group names understood by RegexLogParser
+const (
+	FieldTimestamp = "timestamp"
+	FieldLevel     = "level"
+	FieldMessage   = "message"
+	FieldEpoch     = "epoch"
+)
+
+var (
+	_ LogFormat = &RegexLogParser{}
+	_ LogFormat = &JSONLogParser{}
+)
+
+// RegexFormatConfig defines a log format by a regular expression, loaded from agent config
+type RegexFormatConfig struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+	// Go time layout of the timestamp field, e.g. "2006-01-02 15:04:05"
+	TimeLayout string `mapstructure:"time_layout"`
+	// Maps timestamp/level/message/epoch to capture group names, defaults to the same names
+	Fields map[string]string `mapstructure:"fields"`
+}
+
+// RegexLogParser parses lines with named capture groups
+type RegexLogParser struct {
+	name       string
+	reg        *regexp.Regexp
+	timeLayout string
+	groups     map[string]int
+}
+
+func NewRegexLogParser(cfg RegexFormatConfig) (*RegexLogParser, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("log format name is empty")
+	}
+	reg, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("log format %s: invalid pattern: %v", cfg.Name, err)
+	}
+
+	p := &RegexLogParser{
+		name:       cfg.Name,
+		reg:        reg,
+		timeLayout: cfg.TimeLayout,
+		groups:     make(map[string]int),
+	}
+	for _, field := range []string{FieldTimestamp, FieldLevel, FieldMessage, FieldEpoch} {
+		group := field
+		if mapped, ok := cfg.Fields[field]; ok {
+			group = mapped
+		}
+		if idx := reg.SubexpIndex(group); idx > 0 {
+			p.groups[field] = idx
+		} else if _, ok := cfg.Fields[field]; ok {
+			return nil, fmt.Errorf("log format %s: pattern has no group %q for field %s", cfg.Name, group, field)
+		}
+	}
+	if _, ok := p.groups[FieldTimestamp]; ok && p.timeLayout == "" {
+		return nil, fmt.Errorf("log format %s: time_layout is required with a timestamp field", cfg.Name)
+	}
+	return p, nil
+}
+
+func (p *RegexLogParser) Name() string {
+	return p.name
+}
+
+func (p *RegexLogParser) Match(line string) bool {
+	return p.reg.MatchString(line)
+}
+
+func (p *RegexLogParser) Parse(ctx context.Context, inputs []string) ([]*pb.LogEntry, error) {
+	entries := make([]*pb.LogEntry, 0, len(inputs))
+	for _, line := range inputs {
+		entry := &pb.LogEntry{
+			Message: line,
+		}
+		extractProgress(entry)
+		matches := p.reg.FindStringSubmatch(line)
+		if matches == nil {
+			entries = append(entries, entry)
+			continue
+		}
+
+		if idx, ok := p.groups[FieldTimestamp]; ok {
+			if ts, err := parseLogTime(p.timeLayout, matches[idx]); err == nil {
+				entry.Timestamp = timestamppb.New(ts)
+			}
+		}
+		if idx, ok := p.groups[FieldLevel]; ok {
+			entry.Level = ParseLevel(matches[idx])
+		}
+		if idx, ok := p.groups[FieldEpoch]; ok {
+			if epoch, err := strconv.Atoi(matches[idx]); err == nil {
+				entry.Epoch = int32(epoch)
+			}
+		} else if idx, ok := p.groups[FieldMessage]; ok {
+			entry.Epoch = extractEpoch(matches[idx])
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Parse a log timestamp in local time. Fractional seconds after the seconds field
+// are accepted even if the layout has none. Layouts without a year (glog) get the
+// current year.
+func parseLogTime(layout, value string) (time.Time, error) {
+	ts, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return ts, err
+	}
+	if ts.Year() == 0 {
+		now := time.Now()
+		ts = ts.AddDate(now.Year(), 0, 0)
+		// Around new year a December line is from the previous year
+		if ts.After(now.Add(24 * time.Hour)) {
+			ts = ts.AddDate(-1, 0, 0)
+		}
+	}
+	return ts, nil
+}
+
+// JSONLogParser parses one JSON object per line
+type JSONLogParser struct{}
+
+var (
+	jsonTimeKeys     = []string{"timestamp", "time", "ts", "asctime", "@timestamp"}
+	jsonLevelKeys    = []string{"level", "levelname", "severity", "lvl"}
+	jsonMessageKeys  = []string{"message", "msg", "text"}
+	jsonTimeLayouts  = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+	jsonProgressKeys = map[string][]string{
+		ProgressStep:         {"step", "global_step", "iter", "iteration"},
+		ProgressLoss:         {"loss", "train_loss"},
+		ProgressLR:           {"lr", "learning_rate"},
+		ProgressTokensPerSec: {"tokens_per_sec", "tgs"},
+		ProgressTFLOPS:       {"tflops"},
+	}
+)
+
+func (p *JSONLogParser) Name() string {
+	return JSONLogFormat
+}
+
+func (p *JSONLogParser) Match(line string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return false
+	}
+	var obj map[string]json.RawMessage
+	return json.Unmarshal([]byte(line), &obj) == nil
+}
+
+func (p *JSONLogParser) Parse(ctx context.Context, inputs []string) ([]*pb.LogEntry, error) {
+	entries := make([]*pb.LogEntry, 0, len(inputs))
+	for _, line := range inputs {
+		entry := &pb.LogEntry{
+			Message: line,
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &obj); err != nil {
+			entries = append(entries, entry)
+			continue
+		}
+
+		if v, ok := lookupJSON(obj, jsonTimeKeys); ok {
+			if ts, ok := parseJSONTime(v); ok {
+				entry.Timestamp = timestamppb.New(ts)
+			}
+		}
+		if v, ok := lookupJSON(obj, jsonLevelKeys); ok {
+			if level, ok := v.(string); ok {
+				entry.Level = ParseLevel(level)
+			}
+		}
+		if v, ok := lookupJSON(obj, jsonMessageKeys); ok {
+			if msg, ok := v.(string); ok {
+				entry.Epoch = extractEpoch(msg)
+			}
+		}
+		if v, ok := obj["epoch"].(float64); ok {
+			entry.Epoch = int32(v)
+		}
+		for field, keys := range jsonProgressKeys {
+			if v, ok := lookupJSON(obj, keys); ok {
+				setProgressField(entry, field, jsonValueString(v))
+			}
+		}
+		extractProgress(entry)
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func lookupJSON(obj map[string]interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		if v, ok := obj[key]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Text form of a JSON number or string, other values give an empty string
+func jsonValueString(v interface{}) string {
+	switch t := v.(type) {
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case string:
+		return t
+	}
+	return ""
+}
+
+// Accept RFC3339 and common layouts, or unix seconds as a number
+func parseJSONTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9)), true
+	case string:
+		for _, layout := range jsonTimeLayouts {
+			if ts, err := time.ParseInLocation(layout, t, time.Local); err == nil {
+				return ts, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func mustRegexLogParser(cfg RegexFormatConfig) *RegexLogParser {
+	p, err := NewRegexLogParser(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Built-in formats other than the default one, in detection order. More specific
+// formats come first since detection ties go to the earlier format.
+func builtinLogFormats() []LogFormat {
+	return []LogFormat{
+		// 2025-07-11 02:32:52.123 | INFO     | module:function:42 - message
+		mustRegexLogParser(RegexFormatConfig{
+			Name:       LoguruLogFormat,
+			Pattern:    `^(?P<timestamp>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}) \| (?P<level>[A-Z]+)\s*\| \S+ - (?P<message>.*)$`,
+			TimeLayout: "2006-01-02 15:04:05",
+		}),
+		// I0711 02:32:52.123456 12345 file.cc:42] message
+		mustRegexLogParser(RegexFormatConfig{
+			Name:       GlogLogFormat,
+			Pattern:    `^(?P<level>[IWEF])(?P<timestamp>\d{4} \d{2}:\d{2}:\d{2}\.\d{6})\s+\d+ \S+:\d+\] (?P<message>.*)$`,
+			TimeLayout: "0102 15:04:05",
+		}),
+		// 2025-07-11 02:32:52,123 - name - INFO - message
+		mustRegexLogParser(RegexFormatConfig{
+			Name:       PythonLogFormat,
+			Pattern:    `^(?P<timestamp>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:[,.]\d{3})?)\s*[-|]?\s*(?:\S+\s*-\s*)?(?P<level>DEBUG|INFO|WARNING|ERROR|CRITICAL)\s*[-:|]?\s*(?P<message>.*)$`,
+			TimeLayout: "2006-01-02 15:04:05",
+		}),
+		&JSONLogParser{},
+	}
+}
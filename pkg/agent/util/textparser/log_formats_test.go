@@ -0,0 +1,216 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "deeptrace/v1"
+)
+
+func TestBuiltinLogFormats_Parse(t *testing.T) {
+	year := time.Now().Year()
+	tests := []struct {
+		name      string
+		format    string
+		line      string
+		wantTime  time.Time
+		wantLevel pb.LogLevel
+		wantEpoch int32
+	}{
+		{
+			name:      "xtuner",
+			format:    DefaultLogFormat,
+			line:      "[XTuner][RANK 15][2025-07-11 02:32:52][WARNING] [Train] (Epoch 3) loss 1.2",
+			wantTime:  time.Date(2025, 7, 11, 2, 32, 52, 0, time.UTC),
+			wantLevel: pb.LogLevel_LOG_WARNING,
+			wantEpoch: 3,
+		},
+		{
+			name:      "python logging",
+			format:    PythonLogFormat,
+			line:      "2025-07-11 02:32:52,123 - train - ERROR - CUDA out of memory",
+			wantTime:  time.Date(2025, 7, 11, 2, 32, 52, 123e6, time.Local),
+			wantLevel: pb.LogLevel_LOG_ERROR,
+		},
+		{
+			name:      "python logging without name",
+			format:    PythonLogFormat,
+			line:      "2025-07-11 02:32:52 INFO step 10",
+			wantTime:  time.Date(2025, 7, 11, 2, 32, 52, 0, time.Local),
+			wantLevel: pb.LogLevel_LOG_INFO,
+		},
+		{
+			name:      "loguru",
+			format:    LoguruLogFormat,
+			line:      "2025-07-11 02:32:52.456 | SUCCESS  | trainer:fit:42 - [Train] (Epoch 7) done",
+			wantTime:  time.Date(2025, 7, 11, 2, 32, 52, 456e6, time.Local),
+			wantLevel: pb.LogLevel_LOG_INFO,
+			wantEpoch: 7,
+		},
+		{
+			name:      "glog",
+			format:    GlogLogFormat,
+			line:      "W0711 02:32:52.123456  4242 ProcessGroupNCCL.cpp:1234] Watchdog caught collective operation timeout",
+			wantTime:  time.Date(year, 7, 11, 2, 32, 52, 123456e3, time.Local),
+			wantLevel: pb.LogLevel_LOG_WARNING,
+		},
+		{
+			name:      "json lines",
+			format:    JSONLogFormat,
+			line:      `{"time": "2025-07-11T02:32:52Z", "level": "critical", "msg": "nccl timeout", "epoch": 2}`,
+			wantTime:  time.Date(2025, 7, 11, 2, 32, 52, 0, time.UTC),
+			wantLevel: pb.LogLevel_LOG_CRITICAL,
+			wantEpoch: 2,
+		},
+		{
+			name:      "json lines unix time",
+			format:    JSONLogFormat,
+			line:      `{"ts": 1752201172, "levelname": "INFO", "message": "hello"}`,
+			wantTime:  time.Unix(1752201172, 0),
+			wantLevel: pb.LogLevel_LOG_INFO,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := LookupLogFormat(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !format.Match(tt.line) {
+				t.Errorf("%s.Match() = false", tt.format)
+			}
+			entries, err := format.Parse(context.TODO(), []string{tt.line})
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("%s.Parse() = %v, %v", tt.format, entries, err)
+			}
+			entry := entries[0]
+			if entry.Message != tt.line {
+				t.Errorf("Message = %q, want the raw line", entry.Message)
+			}
+			if entry.Timestamp == nil || !entry.Timestamp.AsTime().Equal(tt.wantTime) {
+				t.Errorf("Timestamp = %v, want %v", entry.Timestamp.AsTime(), tt.wantTime)
+			}
+			if entry.Level != tt.wantLevel {
+				t.Errorf("Level = %v, want %v", entry.Level, tt.wantLevel)
+			}
+			if entry.Epoch != tt.wantEpoch {
+				t.Errorf("Epoch = %d, want %d", entry.Epoch, tt.wantEpoch)
+			}
+		})
+	}
+}
+
+func TestDetectLogFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{
+			name:  "no lines",
+			lines: nil,
+			want:  DefaultLogFormat,
+		},
+		{
+			name:  "unknown lines",
+			lines: []string{"hello", "world"},
+			want:  DefaultLogFormat,
+		},
+		{
+			name: "loguru wins over python",
+			lines: []string{
+				"2025-07-11 02:32:52.456 | INFO     | trainer:fit:42 - start",
+				"some banner",
+				"2025-07-11 02:32:53.456 | INFO     | trainer:fit:43 - step",
+			},
+			want: LoguruLogFormat,
+		},
+		{
+			name: "mostly glog",
+			lines: []string{
+				"[XTuner][RANK 0][2025-07-11 02:32:52][INFO] one xtuner line",
+				"I0711 02:32:52.123456  4242 a.cc:1] a",
+				"I0711 02:32:53.123456  4242 a.cc:2] b",
+			},
+			want: GlogLogFormat,
+		},
+		{
+			name:  "json",
+			lines: []string{`{"msg": "a"}`, `{"msg": "b"}`},
+			want:  JSONLogFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLogFormat(tt.lines); got.Name() != tt.want {
+				t.Errorf("DetectLogFormat() = %s, want %s", got.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRegexLogParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RegexFormatConfig
+		wantErr bool
+	}{
+		{
+			name: "named groups",
+			cfg: RegexFormatConfig{
+				Name:       "custom",
+				Pattern:    `^\[(?P<timestamp>[^\]]+)\] (?P<level>\w+): (?P<message>.*)$`,
+				TimeLayout: "2006-01-02 15:04:05",
+			},
+		},
+		{
+			name: "mapped groups",
+			cfg: RegexFormatConfig{
+				Name:       "mapped",
+				Pattern:    `^(?P<ts>\S+ \S+) (?P<lvl>\w+) ep=(?P<ep>\d+) (?P<msg>.*)$`,
+				TimeLayout: "2006-01-02 15:04:05",
+				Fields:     map[string]string{"timestamp": "ts", "level": "lvl", "epoch": "ep", "message": "msg"},
+			},
+		},
+		{name: "no name", cfg: RegexFormatConfig{Pattern: `.*`}, wantErr: true},
+		{name: "invalid pattern", cfg: RegexFormatConfig{Name: "bad", Pattern: `(`}, wantErr: true},
+		{name: "missing mapped group", cfg: RegexFormatConfig{Name: "bad", Pattern: `(?P<a>.*)`, Fields: map[string]string{"level": "lvl"}}, wantErr: true},
+		{name: "missing time layout", cfg: RegexFormatConfig{Name: "bad", Pattern: `(?P<timestamp>.*)`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegexLogParser(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRegexLogParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterRegexFormats(t *testing.T) {
+	err := RegisterRegexFormats([]RegexFormatConfig{{
+		Name:       "test-mapped",
+		Pattern:    `^(?P<ts>\S+ \S+) (?P<lvl>\w+) ep=(?P<ep>\d+) (?P<msg>.*)$`,
+		TimeLayout: "2006-01-02 15:04:05",
+		Fields:     map[string]string{"timestamp": "ts", "level": "lvl", "epoch": "ep", "message": "msg"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := "2025-07-11 02:32:52 WARN ep=4 slow step"
+	if got := DetectLogFormat([]string{line}); got.Name() != "test-mapped" {
+		t.Errorf("DetectLogFormat() = %s, want test-mapped", got.Name())
+	}
+	format, _ := LookupLogFormat("test-mapped")
+	entries, _ := format.Parse(context.TODO(), []string{line})
+	if entries[0].Level != pb.LogLevel_LOG_WARNING || entries[0].Epoch != 4 || entries[0].Timestamp == nil {
+		t.Errorf("Parse() = %v", entries[0])
+	}
+
+	if _, err := LookupLogFormat("missing"); err == nil {
+		t.Error("LookupLogFormat() should fail for unknown formats")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	pb "deeptrace/v1"
@@ -13,39 +14,57 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// Log parser
+var (
+	// [XTuner][RANK 15][2025-07-11 02:32:52][INFO] message
+	xtunerLineReg = regexp.MustCompile(`\[([^\]]+)\]\[RANK (\d+)\]\[([^\]]+)\]\[([^\]]+)\] (.*)`)
+	epochReg      = regexp.MustCompile(`\[Train\] \(Epoch (\d+)\)`)
+)
+
+var _ LogFormat = &LogParser{}
+
+// Log parser for the XTuner format, the default format
 type LogParser struct{}
 
+func (p *LogParser) Name() string {
+	return DefaultLogFormat
+}
+
+// A line is in the format when its timestamp parses too
+func (p *LogParser) Match(line string) bool {
+	_, _, ok := parseXTunerLine(line)
+	return ok
+}
+
+// Fields of an XTuner line and its timestamp
+func parseXTunerLine(line string) ([]string, time.Time, bool) {
+	matches := xtunerLineReg.FindStringSubmatch(line)
+	if len(matches) < 6 {
+		return nil, time.Time{}, false
+	}
+	timestamp, err := time.Parse("2006-01-02 15:04:05", matches[3])
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	return matches, timestamp, true
+}
+
 func (p *LogParser) Parse(ctx context.Context, inputs []string) ([]*pb.LogEntry, error) {
 	entries := make([]*pb.LogEntry, 0, len(inputs))
 	for _, line := range inputs {
 		entry := &pb.LogEntry{
 			Message: line,
 		}
-		// Basic structure matching
-		baseReg := regexp.MustCompile(`\[([^\]]+)\]\[RANK (\d+)\]\[([^\]]+)\]\[([^\]]+)\] (.*)`)
-		baseMatches := baseReg.FindStringSubmatch(line)
-		if len(baseMatches) < 6 {
+		extractProgress(entry)
+		// Lines not in the format, or with a malformed timestamp, are kept as plain messages
+		baseMatches, timestamp, ok := parseXTunerLine(line)
+		if !ok {
 			entries = append(entries, entry)
 			continue
 		}
 
-		// Parse timestamp
-		timestamp, err := time.Parse("2006-01-02 15:04:05", baseMatches[3])
-		if err != nil {
-			continue
-		}
-
 		entry.Timestamp = timestamppb.New(timestamp)
-		entry.Level = pb.LogLevel(pb.LogLevel_value[baseMatches[4]])
-
-		msgBody := baseMatches[5]
-		epochReg := regexp.MustCompile(`\[Train\] \(Epoch (\d+)\)`)
-		epochMatch := epochReg.FindStringSubmatch(msgBody)
-
-		if epochMatch != nil {
-			entry.Epoch = extractNumber(epochMatch[1], "(\\d+)")
-		}
+		entry.Level = ParseLevel(baseMatches[4])
+		entry.Epoch = extractEpoch(baseMatches[5])
 
 		entries = append(entries, entry)
 	}
@@ -53,6 +72,37 @@ func (p *LogParser) Parse(ctx context.Context, inputs []string) ([]*pb.LogEntry,
 	return entries, nil
 }
 
+// Extract the epoch from an XTuner style "[Train] (Epoch n)" message
+func extractEpoch(msg string) int32 {
+	epochMatch := epochReg.FindStringSubmatch(msg)
+	if epochMatch != nil {
+		return extractNumber(epochMatch[1], "(\\d+)")
+	}
+	return 0
+}
+
+// Convert a level name such as "INFO", "warn" or "LOG_ERROR" to LogLevel
+func ParseLevel(s string) pb.LogLevel {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if level, ok := pb.LogLevel_value[name]; ok {
+		return pb.LogLevel(level)
+	}
+	switch name {
+	case "DEBUG", "TRACE", "D":
+		return pb.LogLevel_LOG_DEBUG
+	case "INFO", "SUCCESS", "I":
+		return pb.LogLevel_LOG_INFO
+	case "WARN", "WARNING", "W":
+		return pb.LogLevel_LOG_WARNING
+	case "ERROR", "E":
+		return pb.LogLevel_LOG_ERROR
+	case "CRITICAL", "FATAL", "F":
+		return pb.LogLevel_LOG_CRITICAL
+	default:
+		return pb.LogLevel_LOG_UNSPECIFIED
+	}
+}
+
 func extractNumber(s, pattern string) int32 {
 	re := regexp.MustCompile(pattern)
 	match := re.FindStringSubmatch(s)
@@ -56,6 +56,23 @@ func TestLogParser_Parse(t *testing.T) {
 				{
 					Message:   "[XTuner][RANK 15][2025-07-11 02:32:52][INFO] Gradient Accumulation: 4, Compile: True, CPU Offload: False",
 					Timestamp: timestamppb.New(logtime),
+					Level:     pb.LogLevel_LOG_INFO,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed time",
+			p:    &LogParser{},
+			args: args{
+				ctx: context.TODO(),
+				inputs: []string{
+					"[XTuner][RANK 15][2025-13-41 02:32:52][INFO] Compile: True",
+				},
+			},
+			want: []*pb.LogEntry{
+				{
+					Message: "[XTuner][RANK 15][2025-13-41 02:32:52][INFO] Compile: True",
 				},
 			},
 			wantErr: false,
@@ -75,3 +92,24 @@ func TestLogParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want pb.LogLevel
+	}{
+		{in: "INFO", want: pb.LogLevel_LOG_INFO},
+		{in: "warn", want: pb.LogLevel_LOG_WARNING},
+		{in: "WARNING", want: pb.LogLevel_LOG_WARNING},
+		{in: "LOG_ERROR", want: pb.LogLevel_LOG_ERROR},
+		{in: "FATAL", want: pb.LogLevel_LOG_CRITICAL},
+		{in: "unknown", want: pb.LogLevel_LOG_UNSPECIFIED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ParseLevel(tt.in); got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
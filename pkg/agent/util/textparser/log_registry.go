@@ -0,0 +1,98 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"fmt"
+	"sync"
+
+	pb "deeptrace/v1"
+)
+
+// Number of leading lines used to detect the log format
+const DetectLines = 50
+
+// LogFormat is a named log parser that can tell whether a line is in its format
+type LogFormat interface {
+	Interface[[]*pb.LogEntry]
+	Name() string
+	Match(line string) bool
+}
+
+var (
+	logFormatsMu sync.RWMutex
+	logFormats   = map[string]LogFormat{}
+	// Registration order, earlier formats win detection ties
+	logFormatOrder []string
+)
+
+func init() {
+	RegisterLogFormat(&LogParser{})
+	for _, format := range builtinLogFormats() {
+		RegisterLogFormat(format)
+	}
+}
+
+// RegisterLogFormat adds a format, replacing any format of the same name
+func RegisterLogFormat(format LogFormat) {
+	logFormatsMu.Lock()
+	defer logFormatsMu.Unlock()
+
+	if _, ok := logFormats[format.Name()]; !ok {
+		logFormatOrder = append(logFormatOrder, format.Name())
+	}
+	logFormats[format.Name()] = format
+}
+
+// RegisterRegexFormats registers the formats defined in agent config
+func RegisterRegexFormats(cfgs []RegexFormatConfig) error {
+	for _, cfg := range cfgs {
+		p, err := NewRegexLogParser(cfg)
+		if err != nil {
+			return err
+		}
+		RegisterLogFormat(p)
+	}
+	return nil
+}
+
+// LookupLogFormat returns the format registered under name
+func LookupLogFormat(name string) (LogFormat, error) {
+	logFormatsMu.RLock()
+	defer logFormatsMu.RUnlock()
+
+	format, ok := logFormats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown log format %q, available: %v", name, logFormatOrder)
+	}
+	return format, nil
+}
+
+// LogFormatNames lists registered formats in registration order
+func LogFormatNames() []string {
+	logFormatsMu.RLock()
+	defer logFormatsMu.RUnlock()
+	return append([]string(nil), logFormatOrder...)
+}
+
+// DetectLogFormat picks the format matching most of the given lines, the default
+// format is returned when nothing matches
+func DetectLogFormat(lines []string) LogFormat {
+	logFormatsMu.RLock()
+	defer logFormatsMu.RUnlock()
+
+	best, bestCount := logFormats[DefaultLogFormat], 0
+	for _, name := range logFormatOrder {
+		format := logFormats[name]
+		count := 0
+		for _, line := range lines {
+			if format.Match(line) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = format, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,154 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	pb "deeptrace/v1"
+)
+
+// Training progress fields of LogEntry
+const (
+	ProgressStep         = "step"
+	ProgressLoss         = "loss"
+	ProgressLR           = "lr"
+	ProgressTokensPerSec = "tokens_per_sec"
+	ProgressTFLOPS       = "tflops"
+)
+
+// Float value such as 1.5, 2e-5, nan or inf
+const floatPattern = `([-+]?(?:nan|inf(?:inity)?|\d+(?:\.\d*)?(?:e[-+]?\d+)?|\.\d+(?:e[-+]?\d+)?))`
+
+// ProgressExtractorConfig extracts one progress field with the first capture group of
+// the pattern, loaded from agent config
+type ProgressExtractorConfig struct {
+	Field   string `mapstructure:"field"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+type progressExtractor struct {
+	field string
+	reg   *regexp.Regexp
+}
+
+var (
+	progressMu         sync.RWMutex
+	progressExtractors = defaultProgressExtractors()
+)
+
+// Default extractors, matching lines such as
+// "[Step 10/1000] lr: 1.0e-05 loss: 2.345 tgs: 3120.5 tflops: 150.2"
+// or "iter 10 | loss=2.345 | learning_rate=1e-5 | tokens/s=3120"
+func defaultProgressExtractors() []progressExtractor {
+	defaults := []ProgressExtractorConfig{
+		{Field: ProgressStep, Pattern: `(?i)\b(?:global_step|step|iter|iteration)\b\s*[:=]?\s*\[?\s*(\d+)`},
+		{Field: ProgressLoss, Pattern: `(?i)\b(?:loss|train_loss|reduced_loss|lm_loss)\b\s*[:=]?\s*` + floatPattern},
+		{Field: ProgressLR, Pattern: `(?i)\b(?:lr|learning_rate|learning rate)\b\s*[:=]?\s*` + floatPattern},
+		{Field: ProgressTokensPerSec, Pattern: `(?i)(?:\btgs|\btokens/s(?:ec)?|\btokens_per_sec(?:ond)?|\btok/s)\s*[:=]?\s*` + floatPattern},
+		{Field: ProgressTFLOPS, Pattern: `(?i)\btflops\b\s*[:=]?\s*` + floatPattern},
+	}
+	extractors := make([]progressExtractor, 0, len(defaults))
+	for _, cfg := range defaults {
+		e, err := newProgressExtractor(cfg)
+		if err != nil {
+			panic(err)
+		}
+		extractors = append(extractors, e)
+	}
+	return extractors
+}
+
+func newProgressExtractor(cfg ProgressExtractorConfig) (progressExtractor, error) {
+	switch cfg.Field {
+	case ProgressStep, ProgressLoss, ProgressLR, ProgressTokensPerSec, ProgressTFLOPS:
+	default:
+		return progressExtractor{}, fmt.Errorf("unknown progress field %q", cfg.Field)
+	}
+	reg, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return progressExtractor{}, fmt.Errorf("progress field %s: invalid pattern: %v", cfg.Field, err)
+	}
+	if reg.NumSubexp() < 1 {
+		return progressExtractor{}, fmt.Errorf("progress field %s: pattern has no capture group", cfg.Field)
+	}
+	return progressExtractor{field: cfg.Field, reg: reg}, nil
+}
+
+// Add extractors from agent config. They are tried before the defaults of the same field.
+func RegisterProgressExtractors(cfgs []ProgressExtractorConfig) error {
+	extractors := make([]progressExtractor, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		e, err := newProgressExtractor(cfg)
+		if err != nil {
+			return err
+		}
+		extractors = append(extractors, e)
+	}
+
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progressExtractors = append(extractors, progressExtractors...)
+	return nil
+}
+
+// Fill the progress fields of the entry from its message. The first extractor that
+// matches wins for each field.
+func extractProgress(entry *pb.LogEntry) {
+	progressMu.RLock()
+	defer progressMu.RUnlock()
+
+	for _, e := range progressExtractors {
+		if hasProgressField(entry, e.field) {
+			continue
+		}
+		matches := e.reg.FindStringSubmatch(entry.Message)
+		if matches == nil {
+			continue
+		}
+		setProgressField(entry, e.field, matches[1])
+	}
+}
+
+func hasProgressField(entry *pb.LogEntry, field string) bool {
+	switch field {
+	case ProgressStep:
+		return entry.Step != nil
+	case ProgressLoss:
+		return entry.Loss != nil
+	case ProgressLR:
+		return entry.Lr != nil
+	case ProgressTokensPerSec:
+		return entry.TokensPerSec != nil
+	case ProgressTFLOPS:
+		return entry.Tflops != nil
+	}
+	return false
+}
+
+// Set a progress field from its text value, invalid values are ignored
+func setProgressField(entry *pb.LogEntry, field, value string) {
+	if field == ProgressStep {
+		if step, err := strconv.ParseInt(value, 10, 64); err == nil {
+			entry.Step = &step
+		}
+		return
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	switch field {
+	case ProgressLoss:
+		entry.Loss = &v
+	case ProgressLR:
+		entry.Lr = &v
+	case ProgressTokensPerSec:
+		entry.TokensPerSec = &v
+	case ProgressTFLOPS:
+		entry.Tflops = &v
+	}
+}
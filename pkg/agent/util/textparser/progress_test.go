@@ -0,0 +1,106 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	pb "deeptrace/v1"
+)
+
+type progress struct {
+	step         *int64
+	loss         *float64
+	lr           *float64
+	tokensPerSec *float64
+	tflops       *float64
+}
+
+func i64(v int64) *int64     { return &v }
+func f64(v float64) *float64 { return &v }
+
+func sameFloat(got, want *float64) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return *got == *want || (math.IsNaN(*got) && math.IsNaN(*want))
+}
+
+func TestExtractProgress(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want progress
+	}{
+		{
+			name: "xtuner",
+			line: "[XTuner][RANK 0][2025-07-11 02:32:52][INFO] [Step 120/5000] lr: 1.9e-05 loss: 1.832 grad_norm: 2.1 tgs: 3125.4 e2e_tgs: 3000.1 tflops: 152.3",
+			want: progress{step: i64(120), loss: f64(1.832), lr: f64(1.9e-05), tokensPerSec: f64(3125.4), tflops: f64(152.3)},
+		},
+		{
+			name: "key=value",
+			line: "iter 42 | loss=nan | learning_rate=1e-4 | tokens/s=800",
+			want: progress{step: i64(42), loss: f64(math.NaN()), lr: f64(1e-4), tokensPerSec: f64(800)},
+		},
+		{
+			name: "ignore similar names",
+			line: "steps_per_epoch: 10 loss_scale: 65536 e2e_tflops: 3",
+			want: progress{},
+		},
+		{
+			name: "no progress",
+			line: "NCCL WARN connection closed",
+			want: progress{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &pb.LogEntry{Message: tt.line}
+			extractProgress(entry)
+			if (entry.Step == nil) != (tt.want.step == nil) || (entry.Step != nil && *entry.Step != *tt.want.step) {
+				t.Errorf("Step = %v, want %v", entry.Step, tt.want.step)
+			}
+			for _, f := range []struct {
+				name      string
+				got, want *float64
+			}{
+				{"Loss", entry.Loss, tt.want.loss},
+				{"Lr", entry.Lr, tt.want.lr},
+				{"TokensPerSec", entry.TokensPerSec, tt.want.tokensPerSec},
+				{"Tflops", entry.Tflops, tt.want.tflops},
+			} {
+				if !sameFloat(f.got, f.want) {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONLogParser_Progress(t *testing.T) {
+	p := &JSONLogParser{}
+	entries, _ := p.Parse(context.TODO(), []string{`{"msg": "train", "step": 1000000, "loss": "NaN", "lr": 0.0001}`})
+	entry := entries[0]
+	if entry.GetStep() != 1000000 || !math.IsNaN(entry.GetLoss()) || entry.GetLr() != 0.0001 {
+		t.Errorf("Parse() = %v", entry)
+	}
+}
+
+func TestRegisterProgressExtractors(t *testing.T) {
+	if err := RegisterProgressExtractors([]ProgressExtractorConfig{{Field: "speed", Pattern: `(\d+)`}}); err == nil {
+		t.Error("RegisterProgressExtractors() should reject unknown fields")
+	}
+	if err := RegisterProgressExtractors([]ProgressExtractorConfig{{Field: ProgressStep, Pattern: `global_batch=\d+`}}); err == nil {
+		t.Error("RegisterProgressExtractors() should reject patterns without a group")
+	}
+	if err := RegisterProgressExtractors([]ProgressExtractorConfig{{Field: ProgressStep, Pattern: `global_batch=(\d+)`}}); err != nil {
+		t.Fatal(err)
+	}
+	entry := &pb.LogEntry{Message: "step 3 global_batch=96"}
+	extractProgress(entry)
+	if entry.GetStep() != 96 {
+		t.Errorf("Step = %d, want the configured extractor to win", entry.GetStep())
+	}
+}
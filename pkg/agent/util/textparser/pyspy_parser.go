@@ -0,0 +1,83 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	pb "deeptrace/v1"
+)
+
+// PySpyParser parses the output of "py-spy dump --json"
+type PySpyParser struct{}
+
+type pySpyThread struct {
+	ThreadID   uint64       `json:"thread_id"`
+	ThreadName *string      `json:"thread_name"`
+	OSThreadID *int64       `json:"os_thread_id"`
+	Active     bool         `json:"active"`
+	OwnsGIL    bool         `json:"owns_gil"`
+	Frames     []pySpyFrame `json:"frames"`
+}
+
+type pySpyFrame struct {
+	Name     string  `json:"name"`
+	Filename string  `json:"filename"`
+	Module   *string `json:"module"`
+	Line     int32   `json:"line"`
+}
+
+func (p *PySpyParser) Parse(ctx context.Context, inputs []string) ([]*pb.ThreadStack, error) {
+	threads := []*pb.ThreadStack{}
+	for _, input := range inputs {
+		var dump []pySpyThread
+		if err := json.Unmarshal([]byte(input), &dump); err != nil {
+			return nil, fmt.Errorf("invalid py-spy output: %v", err)
+		}
+		for _, t := range dump {
+			thread := &pb.ThreadStack{
+				ThreadId:    int32(t.ThreadID),
+				ThreadName:  "unknown",
+				StackFrames: []string{},
+				HoldsGil:    t.OwnsGIL,
+				Idle:        !t.Active,
+			}
+			// The OS thread id is what pystack and gdb report
+			if t.OSThreadID != nil {
+				thread.ThreadId = int32(*t.OSThreadID)
+			}
+			if t.ThreadName != nil {
+				thread.ThreadName = *t.ThreadName
+			}
+			// py-spy lists the most recent call first, pystack last
+			for i := len(t.Frames) - 1; i >= 0; i-- {
+				frame := t.Frames[i].toFrame()
+				thread.Frames = append(thread.Frames, frame)
+				if frame.Language == pb.FrameLanguage_FRAME_PYTHON {
+					thread.StackFrames = append(thread.StackFrames, fmt.Sprintf("File %q, line %d, in %s", frame.File, frame.Line, frame.Function))
+				}
+			}
+			threads = append(threads, thread)
+		}
+	}
+	return threads, nil
+}
+
+// Native frames have the shared object or C source as their file
+func (f pySpyFrame) toFrame() *pb.StackFrame {
+	frame := &pb.StackFrame{Function: f.Name, File: f.Filename, Line: f.Line}
+	if strings.HasSuffix(f.Filename, ".py") || strings.HasPrefix(f.Filename, "<") {
+		frame.Language = pb.FrameLanguage_FRAME_PYTHON
+		frame.Module = pythonModule(f.Filename)
+		return frame
+	}
+	frame.Language = pb.FrameLanguage_FRAME_NATIVE
+	if f.Module != nil {
+		frame.Module = filepath.Base(*f.Module)
+	}
+	return frame
+}
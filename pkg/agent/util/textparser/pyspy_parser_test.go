@@ -0,0 +1,79 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package textparser
+
+import (
+	"context"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPySpyParser_Parse(t *testing.T) {
+	dump := `[
+  {"pid": 1654, "thread_id": 139872, "thread_name": "MainThread", "os_thread_id": 1654, "active": true, "owns_gil": true,
+   "frames": [
+     {"name": "ncclGroupEnd", "filename": "libnccl.so.2", "module": "/usr/lib/libnccl.so.2", "short_filename": "libnccl.so.2", "line": 0, "locals": null, "is_entry": false},
+     {"name": "step", "filename": "/workspace/train.py", "module": null, "short_filename": "train.py", "line": 88, "locals": null, "is_entry": false},
+     {"name": "<module>", "filename": "/workspace/train.py", "module": null, "short_filename": "train.py", "line": 120, "locals": null, "is_entry": true}
+   ]},
+  {"pid": 1654, "thread_id": 139900, "thread_name": null, "os_thread_id": 1700, "active": false, "owns_gil": false,
+   "frames": [
+     {"name": "wait", "filename": "/usr/lib/python3.12/threading.py", "module": null, "short_filename": "threading.py", "line": 355, "locals": null, "is_entry": false}
+   ]}
+]`
+	tests := []struct {
+		name    string
+		inputs  []string
+		want    []*pb.ThreadStack
+		wantErr bool
+	}{
+		{
+			name:   "normal",
+			inputs: []string{dump},
+			want: []*pb.ThreadStack{
+				{
+					ThreadId:   1654,
+					ThreadName: "MainThread",
+					HoldsGil:   true,
+					StackFrames: []string{
+						"File \"/workspace/train.py\", line 120, in <module>",
+						"File \"/workspace/train.py\", line 88, in step",
+					},
+					Frames: []*pb.StackFrame{
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 120, Function: "<module>", Module: "train"},
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step", Module: "train"},
+						{Language: pb.FrameLanguage_FRAME_NATIVE, File: "libnccl.so.2", Function: "ncclGroupEnd", Module: "libnccl.so.2"},
+					},
+				},
+				{
+					ThreadId:    1700,
+					ThreadName:  "unknown",
+					Idle:        true,
+					StackFrames: []string{"File \"/usr/lib/python3.12/threading.py\", line 355, in wait"},
+					Frames: []*pb.StackFrame{
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/lib/python3.12/threading.py", Line: 355, Function: "wait", Module: "threading"},
+					},
+				},
+			},
+		},
+		{
+			name:    "not json",
+			inputs:  []string{"Error: Permission Denied"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&PySpyParser{}).Parse(context.Background(), tt.inputs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -27,7 +27,7 @@ func (p *StackParser) Parse(ctx context.Context, inputs []string) ([]*pb.ThreadS
 			switch {
 			case isThreadStart(line):
 				if current != nil {
-					threads = append(threads, current)
+					threads = append(threads, finishThread(current))
 				}
 				current = parseThreadHeader(line)
 
@@ -35,17 +35,28 @@ func (p *StackParser) Parse(ctx context.Context, inputs []string) ([]*pb.ThreadS
 				if current != nil {
 					frame := cleanFrame(line)
 					current.StackFrames = append(current.StackFrames, frame)
+					current.Frames = append(current.Frames, parseFrame(line, pb.FrameLanguage_FRAME_PYTHON))
+				}
+
+			case isNativeFrame(line):
+				if current != nil {
+					current.Frames = append(current.Frames, parseFrame(line, pb.FrameLanguage_FRAME_NATIVE))
 				}
 
 			case isCodeLine(line):
-				if current != nil && len(current.StackFrames) > 0 {
-					current.StackFrames[len(current.StackFrames)-1] += "\n" + strings.TrimSpace(line)
+				// Only Python frames are followed by their source line
+				if current != nil && len(current.Frames) > 0 {
+					last := current.Frames[len(current.Frames)-1]
+					if last.Language == pb.FrameLanguage_FRAME_PYTHON {
+						last.Code = strings.TrimSpace(line)
+						current.StackFrames[len(current.StackFrames)-1] += "\n" + last.Code
+					}
 				}
 			}
 		}
 
 		if current != nil {
-			threads = append(threads, current)
+			threads = append(threads, finishThread(current))
 		}
 	}
 
@@ -60,6 +71,99 @@ func isPythonFrame(line string) bool {
 	return strings.Contains(line, "(Python) File")
 }
 
+func isNativeFrame(line string) bool {
+	return strings.Contains(line, "(C) File")
+}
+
+// Frame lines such as
+// (Python) File "/workspace/train.py", line 10, in main
+// (C) File "../csu/libc-start.c", line 392, in __libc_start_main_impl (libc.so.6)
+var frameRegexp = regexp.MustCompile(`\((?:Python|C)\) File "(.*)", line (\d+), in (.*)$`)
+
+// Native frames end with the shared object, e.g. "ncclGroupEnd (libnccl.so.2)"
+var libraryRegexp = regexp.MustCompile(`^(.*) \(([^()]+)\)$`)
+
+// Thread header such as "Traceback for thread 2843 (pt_autograd_0) [Has the GIL] (most recent call last):"
+var threadHeaderRegexp = regexp.MustCompile(`Traceback for thread (\d+) \((.*?)\)(?: \[([^\]]*)\])?`)
+
+// Standard library directory such as python3.12
+var pythonLibRegexp = regexp.MustCompile(`^python\d+(?:\.\d+)*$`)
+
+// Innermost frames of a thread blocked waiting. Busy waits such as sched_yield or
+// cudaStreamSynchronize spinning are not idle.
+var (
+	idlePythonFrames = map[string]bool{
+		"threading.wait": true, "threading._wait_for_tstate_lock": true,
+		"selectors.select": true, "queue.get": true, "multiprocessing.connection._poll": true,
+	}
+	idleNativeFrames = map[string]bool{
+		"pthread_cond_wait": true, "pthread_cond_timedwait": true, "__futex_abstimed_wait_common": true,
+		"futex_wait": true, "do_futex_wait": true, "sem_wait": true, "sem_timedwait": true,
+		"epoll_wait": true, "poll": true, "__poll": true, "select": true, "__select": true,
+		"nanosleep": true, "clock_nanosleep": true, "__clock_nanosleep": true, "accept": true,
+	}
+)
+
+func parseFrame(line string, lang pb.FrameLanguage) *pb.StackFrame {
+	frame := &pb.StackFrame{Language: lang}
+	matches := frameRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if matches == nil {
+		frame.Function = strings.TrimSpace(line)
+		return frame
+	}
+	lineNo, _ := strconv.Atoi(matches[2])
+	frame.File = matches[1]
+	frame.Line = int32(lineNo)
+	frame.Function = matches[3]
+	if lang == pb.FrameLanguage_FRAME_NATIVE {
+		if m := libraryRegexp.FindStringSubmatch(frame.Function); m != nil {
+			frame.Function, frame.Module = m[1], m[2]
+		}
+	} else {
+		frame.Module = pythonModule(frame.File)
+	}
+	return frame
+}
+
+// Dotted module name of a Python source file, relative to site-packages or the standard
+// library when the path is below them
+func pythonModule(file string) string {
+	if strings.HasPrefix(file, "<frozen ") {
+		return strings.TrimSuffix(strings.TrimPrefix(file, "<frozen "), ">")
+	}
+	if strings.HasPrefix(file, "<") {
+		return ""
+	}
+	path := strings.TrimSuffix(file, ".py")
+	for _, dir := range []string{"/site-packages/", "/dist-packages/"} {
+		if i := strings.LastIndex(path, dir); i >= 0 {
+			return strings.ReplaceAll(path[i+len(dir):], "/", ".")
+		}
+	}
+	// Standard library, e.g. /usr/lib/python3.12/multiprocessing/context.py
+	parts := strings.Split(path, "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if pythonLibRegexp.MatchString(parts[i]) {
+			return strings.Join(parts[i+1:], ".")
+		}
+	}
+	return parts[len(parts)-1]
+}
+
+// Set the fields that depend on all frames of the thread
+func finishThread(thread *pb.ThreadStack) *pb.ThreadStack {
+	if len(thread.Frames) > 0 {
+		top := thread.Frames[len(thread.Frames)-1]
+		switch top.Language {
+		case pb.FrameLanguage_FRAME_PYTHON:
+			thread.Idle = thread.Idle || idlePythonFrames[top.Module+"."+top.Function]
+		case pb.FrameLanguage_FRAME_NATIVE:
+			thread.Idle = thread.Idle || idleNativeFrames[top.Function]
+		}
+	}
+	return thread
+}
+
 func isCodeLine(line string) bool {
 	return strings.HasPrefix(line, "    ") &&
 		!strings.Contains(line, "File") &&
@@ -72,8 +176,7 @@ func cleanFrame(line string) string {
 }
 
 func parseThreadHeader(line string) *pb.ThreadStack {
-	re := regexp.MustCompile(`Traceback for thread (\d+) \((.*?)\)`)
-	matches := re.FindStringSubmatch(line)
+	matches := threadHeaderRegexp.FindStringSubmatch(line)
 
 	if len(matches) < 3 {
 		return &pb.ThreadStack{
@@ -88,9 +191,19 @@ func parseThreadHeader(line string) *pb.ThreadStack {
 		tid = -1
 	}
 
-	return &pb.ThreadStack{
+	thread := &pb.ThreadStack{
 		ThreadId:    int32(tid),
 		ThreadName:  matches[2],
 		StackFrames: []string{},
 	}
+	// Status flags, e.g. "Has the GIL,Garbage collecting"
+	for _, flag := range strings.Split(matches[3], ",") {
+		switch strings.ToLower(strings.TrimSpace(flag)) {
+		case "has the gil":
+			thread.HoldsGil = true
+		case "idle":
+			thread.Idle = true
+		}
+	}
+	return thread
 }
@@ -26,6 +26,12 @@ Traceback for thread 1654 (python) [] (most recent call last):
         self._target(*self._args, **self._kwargs)
     (Python) File "/usr/lib/python3.12/threading.py", line 355, in wait
         waiter.acquire()`
+	stack_case_native := `Traceback for thread 3021 (python) [Has the GIL] (most recent call last):
+    (C) File "???", line 0, in _start (python3.10)
+    (Python) File "/workspace/train.py", line 88, in step
+        dist.all_reduce(grad)
+    (C) File "???", line 0, in ncclGroupEnd (libnccl.so.2)
+    (C) File "../sysdeps/unix/sysv/linux/sched_yield.c", line 5, in sched_yield (libc.so.6)`
 	type args struct {
 		ctx    context.Context
 		inputs []string
@@ -50,14 +56,45 @@ Traceback for thread 1654 (python) [] (most recent call last):
 					StackFrames: []string{
 						"File \"/usr/local/lib/python3.12/dist-packages/torch/autograd/function.py\", line 307, in apply\nreturn user_fn(self, *args)",
 						"File \"/tmp/torchinductor_root/yy/asdhjfkjahdkfhakjhfdkj.py\", line 101, in call\nbuf0.copy_(primals_2, False)"},
+					Frames: []*pb.StackFrame{
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/local/lib/python3.12/dist-packages/torch/autograd/function.py", Line: 307, Function: "apply", Module: "torch.autograd.function", Code: "return user_fn(self, *args)"},
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/tmp/torchinductor_root/yy/asdhjfkjahdkfhakjhfdkj.py", Line: 101, Function: "call", Module: "asdhjfkjahdkfhakjhfdkj", Code: "buf0.copy_(primals_2, False)"},
+					},
 				},
 				{
 					ThreadId:   1654,
 					ThreadName: "python",
+					Idle:       true,
 					StackFrames: []string{
 						"File \"/usr/lib/python3.12/threading.py\", line 1030, in _bootstrap\nself._bootstrap_inner()",
 						"File \"/usr/lib/python3.12/threading.py\", line 1010, in run\nself._target(*self._args, **self._kwargs)",
 						"File \"/usr/lib/python3.12/threading.py\", line 355, in wait\nwaiter.acquire()"},
+					Frames: []*pb.StackFrame{
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/lib/python3.12/threading.py", Line: 1030, Function: "_bootstrap", Module: "threading", Code: "self._bootstrap_inner()"},
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/lib/python3.12/threading.py", Line: 1010, Function: "run", Module: "threading", Code: "self._target(*self._args, **self._kwargs)"},
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/lib/python3.12/threading.py", Line: 355, Function: "wait", Module: "threading", Code: "waiter.acquire()"},
+					},
+				},
+			},
+		},
+		{
+			name: "native frames",
+			args: args{
+				inputs: []string{stack_case_native},
+			},
+			want: []*pb.ThreadStack{
+				{
+					ThreadId:   3021,
+					ThreadName: "python",
+					HoldsGil:   true,
+					StackFrames: []string{
+						"File \"/workspace/train.py\", line 88, in step\ndist.all_reduce(grad)"},
+					Frames: []*pb.StackFrame{
+						{Language: pb.FrameLanguage_FRAME_NATIVE, File: "???", Line: 0, Function: "_start", Module: "python3.10"},
+						{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step", Module: "train", Code: "dist.all_reduce(grad)"},
+						{Language: pb.FrameLanguage_FRAME_NATIVE, File: "???", Line: 0, Function: "ncclGroupEnd", Module: "libnccl.so.2"},
+						{Language: pb.FrameLanguage_FRAME_NATIVE, File: "../sysdeps/unix/sysv/linux/sched_yield.c", Line: 5, Function: "sched_yield", Module: "libc.so.6"},
+					},
 				},
 			},
 		},
@@ -75,3 +112,24 @@ Traceback for thread 1654 (python) [] (most recent call last):
 		})
 	}
 }
+
+func Test_pythonModule(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "/usr/local/lib/python3.12/dist-packages/torch/autograd/function.py", want: "torch.autograd.function"},
+		{file: "/opt/conda/lib/python3.10/site-packages/deepspeed/runtime/engine.py", want: "deepspeed.runtime.engine"},
+		{file: "/usr/lib/python3.12/multiprocessing/context.py", want: "multiprocessing.context"},
+		{file: "/home/user/python_projects/train.py", want: "train"},
+		{file: "<frozen runpy>", want: "runpy"},
+		{file: "<string>", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := pythonModule(tt.file); got != tt.want {
+				t.Errorf("pythonModule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"deeptrace/logger"
+	"deeptrace/pkg/agent/util/scripts"
+	"deeptrace/pkg/agent/util/storage"
+	pb "deeptrace/v1"
+
+	"go.uber.org/zap"
+)
+
+// Event types of the process tracker
+const (
+	EventProcessExit   = "process_exit"
+	EventWorkerRestart = "worker_restart"
+)
+
+// Swapped in tests
+var (
+	listProcesses    = scripts.GetProcessInfo
+	zombieExitStatus = scripts.GetZombieExitStatus
+)
+
+// ProcessTracker follows the trainer processes of the node across checks. A trainer that
+// disappears or turns zombie is recorded as a process_exit event, a rank whose trainer
+// comes back with another PID as a worker_restart event.
+type ProcessTracker struct {
+	workDir string
+	store   EventStore
+	// Trainers of the last check by rank, nil before the first check
+	trainers map[int]scripts.ProcessInfo
+	// Ranks whose trainer exited and was not restarted yet
+	exited map[int]scripts.ProcessInfo
+}
+
+// NewProcessTracker creates a tracker reading the last log lines of exited ranks from workDir
+func NewProcessTracker(workDir string, store EventStore) *ProcessTracker {
+	return &ProcessTracker{workDir: workDir, store: store, exited: make(map[int]scripts.ProcessInfo)}
+}
+
+// Check discovers the training processes and records the trainers that exited or were
+// restarted since the last check. A node without training processes left has every known
+// trainer exited.
+func (t *ProcessTracker) Check(ctx context.Context) error {
+	processes, err := listProcesses(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list training processes: %w", err)
+	}
+	current := make(map[int]scripts.ProcessInfo)
+	for _, proc := range processes {
+		if proc.Type == scripts.TypeTrainer {
+			current[proc.Rank] = proc
+		}
+	}
+	if t.trainers == nil {
+		t.trainers = current
+		return nil
+	}
+
+	var events []storage.EventEntry
+	for _, rank := range sortedRanks(t.trainers) {
+		prev := t.trainers[rank]
+		cur, ok := current[rank]
+		if ok && cur.PID == prev.PID {
+			continue
+		}
+		status, zombie := zombieExitStatus(prev.PID)
+		if ok {
+			events = append(events, restartEvent(prev, cur, status, zombie))
+			continue
+		}
+		t.exited[rank] = prev
+		events = append(events, exitEvent(prev, status, zombie))
+	}
+	// Ranks back after an exit recorded in an earlier check
+	for _, rank := range sortedRanks(current) {
+		if _, ok := t.trainers[rank]; ok {
+			continue
+		}
+		if prev, ok := t.exited[rank]; ok {
+			delete(t.exited, rank)
+			events = append(events, restartEvent(prev, current[rank], scripts.ExitStatus{}, false))
+		}
+	}
+	t.trainers = current
+	if len(events) == 0 {
+		return nil
+	}
+
+	// The last lines a rank logged usually tell why it exited
+	lastLines := make(map[string][]string)
+	if rankLogs, err := readRankLogs(ctx, t.workDir); err != nil {
+		logger.Logger.Warn("Failed to read logs of exited ranks", zap.Error(err))
+	} else {
+		for _, rankLog := range rankLogs {
+			for _, entry := range rankLog.Entries {
+				lastLines[rankLog.Rank] = append(lastLines[rankLog.Rank], entry.Message)
+			}
+		}
+	}
+
+	for _, event := range events {
+		if lines, ok := lastLines[event.Metadata["rank"].(string)]; ok {
+			event.Metadata["last_log_lines"] = lines
+		}
+		if _, err := t.store.StoreEvent(event); err != nil {
+			return fmt.Errorf("failed to store %s event: %w", event.Type, err)
+		}
+		logger.Logger.Info("Trainer process changed", zap.String("type", event.Type), zap.String("message", event.Message))
+	}
+	return nil
+}
+
+func exitEvent(proc scripts.ProcessInfo, status scripts.ExitStatus, zombie bool) storage.EventEntry {
+	metadata := processMetadata(proc, status, zombie)
+	severity := pb.Severity_ERROR
+	// A trainer finishing normally is not a failure
+	if zombie && status.Signal == 0 && status.Code == 0 {
+		severity = pb.Severity_INFO
+	}
+	return storage.EventEntry{
+		Source:   EventSource,
+		Type:     EventProcessExit,
+		Message:  fmt.Sprintf("Trainer RANK%d (PID %d) %s", proc.Rank, proc.PID, describeExit(status, zombie)),
+		Severity: int32(severity),
+		Metadata: metadata,
+	}
+}
+
+func restartEvent(prev, cur scripts.ProcessInfo, status scripts.ExitStatus, zombie bool) storage.EventEntry {
+	metadata := processMetadata(prev, status, zombie)
+	metadata["new_pid"] = cur.PID
+	return storage.EventEntry{
+		Source:   EventSource,
+		Type:     EventWorkerRestart,
+		Message:  fmt.Sprintf("Trainer RANK%d restarted: PID %d replaced by PID %d", prev.Rank, prev.PID, cur.PID),
+		Severity: int32(pb.Severity_WARNING),
+		Metadata: metadata,
+	}
+}
+
+func processMetadata(proc scripts.ProcessInfo, status scripts.ExitStatus, zombie bool) storage.Metadata {
+	metadata := storage.Metadata{
+		"rank":       fmt.Sprintf("RANK%d", proc.Rank),
+		"local_rank": fmt.Sprintf("RANK%d", proc.LocalRank),
+		"pid":        proc.PID,
+		"launcher":   proc.Launcher,
+		"zombie":     zombie,
+	}
+	// The exit status can only be read while the process is a zombie
+	if zombie {
+		if status.Signal != 0 {
+			metadata["signal"] = status.Signal
+		} else if status.Code >= 0 {
+			metadata["exit_code"] = status.Code
+		}
+	}
+	return metadata
+}
+
+func describeExit(status scripts.ExitStatus, zombie bool) string {
+	switch {
+	case !zombie:
+		return "exited, exit code unavailable"
+	case status.Signal != 0:
+		return fmt.Sprintf("was killed by signal %d", status.Signal)
+	case status.Code < 0:
+		return "exited, exit code unavailable"
+	default:
+		return fmt.Sprintf("exited with code %d", status.Code)
+	}
+}
+
+func sortedRanks(trainers map[int]scripts.ProcessInfo) []int {
+	ranks := make([]int, 0, len(trainers))
+	for rank := range trainers {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	return ranks
+}
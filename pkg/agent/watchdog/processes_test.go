@@ -0,0 +1,165 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package watchdog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"deeptrace/pkg/agent/util/scripts"
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessTracker_Check(t *testing.T) {
+	origList, origZombie, origRead := listProcesses, zombieExitStatus, readRankLogs
+	defer func() { listProcesses, zombieExitStatus, readRankLogs = origList, origZombie, origRead }()
+
+	var processes []scripts.ProcessInfo
+	listProcesses = func(ctx context.Context) ([]scripts.ProcessInfo, error) {
+		return processes, nil
+	}
+	zombies := map[int]scripts.ExitStatus{101: {Code: 1}}
+	zombieExitStatus = func(pid int) (scripts.ExitStatus, bool) {
+		status, ok := zombies[pid]
+		return status, ok
+	}
+	readRankLogs = func(ctx context.Context, workDir string) ([]*pb.RankLog, error) {
+		return []*pb.RankLog{{Rank: "RANK1", Entries: []*pb.LogEntry{{Message: "CUDA error: out of memory"}}}}, nil
+	}
+	trainer := func(rank, pid int) scripts.ProcessInfo {
+		return scripts.ProcessInfo{Type: scripts.TypeTrainer, PID: pid, Rank: rank, LocalRank: rank, Launcher: "torchrun"}
+	}
+	launcher := scripts.ProcessInfo{Type: scripts.TypeLauncher, PID: 99, Ranks: []int{0, 1}}
+
+	store := &fakeStore{}
+	tracker := NewProcessTracker("", store)
+	rounds := []struct {
+		name       string
+		processes  []scripts.ProcessInfo
+		wantType   string
+		wantEvents int
+		wantMeta   map[string]interface{}
+	}{
+		{
+			name:      "first check only records the trainers",
+			processes: []scripts.ProcessInfo{launcher, trainer(0, 100), trainer(1, 101)},
+		},
+		{
+			name:       "unchanged",
+			processes:  []scripts.ProcessInfo{launcher, trainer(0, 100), trainer(1, 101)},
+			wantEvents: 0,
+		},
+		{
+			name:       "rank 1 exits",
+			processes:  []scripts.ProcessInfo{launcher, trainer(0, 100)},
+			wantType:   EventProcessExit,
+			wantEvents: 1,
+			wantMeta:   map[string]interface{}{"rank": "RANK1", "pid": 101, "exit_code": 1, "last_log_lines": []string{"CUDA error: out of memory"}},
+		},
+		{
+			name:       "rank 1 comes back",
+			processes:  []scripts.ProcessInfo{launcher, trainer(0, 100), trainer(1, 201)},
+			wantType:   EventWorkerRestart,
+			wantEvents: 2,
+			wantMeta:   map[string]interface{}{"rank": "RANK1", "pid": 101, "new_pid": 201},
+		},
+		{
+			name:       "rank 0 replaced between checks",
+			processes:  []scripts.ProcessInfo{launcher, trainer(0, 300), trainer(1, 201)},
+			wantType:   EventWorkerRestart,
+			wantEvents: 3,
+			wantMeta:   map[string]interface{}{"rank": "RANK0", "pid": 100, "new_pid": 300},
+		},
+	}
+	for _, round := range rounds {
+		processes = round.processes
+		assert.NoError(t, tracker.Check(context.TODO()), round.name)
+		assert.Len(t, store.events, round.wantEvents, round.name)
+		if round.wantType == "" {
+			continue
+		}
+		event := store.events[len(store.events)-1]
+		assert.Equal(t, round.wantType, event.Type, round.name)
+		for key, want := range round.wantMeta {
+			assert.Equal(t, want, event.Metadata[key], "%s: %s", round.name, key)
+		}
+	}
+	assert.Equal(t, int32(pb.Severity_ERROR), store.events[0].Severity)
+}
+
+// Write a process to a fake procfs root
+func writeFakeProc(t *testing.T, root string, pid, ppid int, comm string, cmdline, env []string) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "stat"), []byte(strconv.Itoa(pid)+" ("+comm+") S "+strconv.Itoa(ppid)+" 1 1 0 -1"), 0644)
+	os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(cmdline, "\x00")+"\x00"), 0644)
+	os.WriteFile(filepath.Join(dir, "environ"), []byte(strings.Join(env, "\x00")+"\x00"), 0644)
+}
+
+func TestProcessTracker_Check_allTrainersGone(t *testing.T) {
+	origList, origZombie, origRead := listProcesses, zombieExitStatus, readRankLogs
+	defer func() { listProcesses, zombieExitStatus, readRankLogs = origList, origZombie, origRead }()
+
+	root := t.TempDir()
+	procFS := scripts.NewProcFS(root)
+	listProcesses = procFS.ProcessInfo
+	zombieExitStatus = procFS.ZombieExitStatus
+	readRankLogs = func(ctx context.Context, workDir string) ([]*pb.RankLog, error) {
+		return nil, nil
+	}
+	writeFakeProc(t, root, 100, 1, "torchrun", []string{"/usr/bin/python", "/usr/bin/torchrun", "train.py"}, nil)
+	writeFakeProc(t, root, 101, 100, "python", []string{"python", "train.py"}, []string{"RANK=0", "LOCAL_RANK=0"})
+	writeFakeProc(t, root, 102, 100, "python", []string{"python", "train.py"}, []string{"RANK=1", "LOCAL_RANK=1"})
+
+	store := &fakeStore{}
+	tracker := NewProcessTracker("", store)
+	assert.NoError(t, tracker.Check(context.TODO()))
+	assert.Empty(t, store.events)
+
+	// Both trainers crashed and were reaped, torchrun is still alive
+	os.RemoveAll(filepath.Join(root, "101"))
+	os.RemoveAll(filepath.Join(root, "102"))
+	assert.NoError(t, tracker.Check(context.TODO()))
+	if assert.Len(t, store.events, 2) {
+		for i, rank := range []string{"RANK0", "RANK1"} {
+			assert.Equal(t, EventProcessExit, store.events[i].Type)
+			assert.Equal(t, rank, store.events[i].Metadata["rank"])
+		}
+	}
+
+	// torchrun gave up, nothing is left to report
+	os.RemoveAll(filepath.Join(root, "100"))
+	assert.NoError(t, tracker.Check(context.TODO()))
+	assert.Len(t, store.events, 2)
+}
+
+func TestExitEvent_severity(t *testing.T) {
+	proc := scripts.ProcessInfo{Type: scripts.TypeTrainer, PID: 100}
+	tests := []struct {
+		name        string
+		status      scripts.ExitStatus
+		zombie      bool
+		want        pb.Severity
+		wantMessage string
+	}{
+		{name: "finished", status: scripts.ExitStatus{Code: 0}, zombie: true, want: pb.Severity_INFO, wantMessage: "Trainer RANK0 (PID 100) exited with code 0"},
+		{name: "killed", status: scripts.ExitStatus{Code: -1, Signal: 9}, zombie: true, want: pb.Severity_ERROR, wantMessage: "Trainer RANK0 (PID 100) was killed by signal 9"},
+		{name: "already reaped", want: pb.Severity_ERROR, wantMessage: "Trainer RANK0 (PID 100) exited, exit code unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := exitEvent(proc, tt.status, tt.zombie)
+			assert.Equal(t, int32(tt.want), event.Severity)
+			assert.Equal(t, tt.wantMessage, event.Message)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package watchdog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"deeptrace/logger"
+	"deeptrace/pkg/agent/logtail"
+	"deeptrace/pkg/agent/stacktrace"
+	"deeptrace/pkg/agent/util/scripts"
+	"deeptrace/pkg/agent/util/storage"
+	pb "deeptrace/v1"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultThreshold = 600 * time.Second
+	defaultInterval  = 60 * time.Second
+	// Log lines read per rank, only the latest time matters
+	logLines = 20
+	// Processes dumped in parallel for the stack snapshot
+	maxConcurrent = 72
+	// Type and source of the stored events
+	EventType   = "hang"
+	EventSource = "watchdog"
+)
+
+// Config of the hang watchdog, loaded from the agent config
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Seconds a rank log may stay unchanged before the rank is reported, 600 if unset
+	Threshold int32 `mapstructure:"threshold"`
+	// Seconds between checks, 60 if unset
+	Interval int32 `mapstructure:"interval"`
+	// Directory of the rank logs, $WORK_DIR if empty
+	WorkDir string `mapstructure:"work_dir"`
+	// Include native frames in the stack snapshot
+	Native bool `mapstructure:"native"`
+	// Record trainers that exit or are restarted by their launcher
+	TrackProcesses bool `mapstructure:"track_processes"`
+}
+
+// EventStore stores the hang events, implemented by storage.EventStorage
+type EventStore interface {
+	StoreEvent(event storage.EventEntry) (string, error)
+}
+
+// Watchdog checks the rank logs of the node periodically, like "deeptracex check-hang"
+// does from the client. When a rank log stops for longer than the threshold, the stacks
+// of the node are dumped and stored with a hang event. Optionally it also tracks the
+// trainer processes with a ProcessTracker.
+type Watchdog struct {
+	threshold time.Duration
+	interval  time.Duration
+	workDir   string
+	native    bool
+	store     EventStore
+	// Ranks already reported, until their log moves again
+	hung map[string]bool
+	// Nil unless processes are tracked
+	processes *ProcessTracker
+}
+
+// Swapped in tests
+var (
+	readRankLogs = func(ctx context.Context, workDir string) ([]*pb.RankLog, error) {
+		return logtail.NewFileReader(ctx, &pb.GetRecentLogsRequest{WorkDir: workDir}).GetRecentLogs(ctx, logLines)
+	}
+	dumpStacks = func(ctx context.Context, req *pb.GetProcessStacksRequest) ([]*pb.ProcessInfo, error) {
+		return stacktrace.NewPythonStack(ctx, maxConcurrent, req).GetProcessStacks(ctx)
+	}
+)
+
+// New creates a watchdog storing its events in store
+func New(cfg Config, store EventStore) *Watchdog {
+	w := &Watchdog{
+		threshold: defaultThreshold,
+		interval:  defaultInterval,
+		workDir:   cfg.WorkDir,
+		native:    cfg.Native,
+		store:     store,
+		hung:      make(map[string]bool),
+	}
+	if cfg.Threshold > 0 {
+		w.threshold = time.Duration(cfg.Threshold) * time.Second
+	}
+	if cfg.Interval > 0 {
+		w.interval = time.Duration(cfg.Interval) * time.Second
+	}
+	if cfg.TrackProcesses {
+		w.processes = NewProcessTracker(cfg.WorkDir, store)
+	}
+	return w
+}
+
+// Run checks the rank logs every interval until ctx is done
+func (w *Watchdog) Run(ctx context.Context) {
+	logger.Logger.Info("Hang watchdog started", zap.Duration("threshold", w.threshold), zap.Duration("interval", w.interval))
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		// Exits first, a crashed rank's log stops too
+		if w.processes != nil {
+			if err := w.processes.Check(ctx); err != nil {
+				logger.Logger.Warn("Process tracker check failed", zap.Error(err))
+			}
+		}
+		if err := w.Check(ctx); err != nil {
+			logger.Logger.Warn("Hang watchdog check failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reads the rank logs once and stores a hang event for the ranks that stopped
+// since the last check
+func (w *Watchdog) Check(ctx context.Context) error {
+	rankLogs, err := readRankLogs(ctx, w.workDir)
+	if err != nil {
+		return fmt.Errorf("failed to read rank logs: %w", err)
+	}
+
+	threshold := int32(w.threshold.Seconds())
+	suspended := make(map[string]int32)
+	for _, rankLog := range rankLogs {
+		// Ranks without a readable log can't be judged
+		if rankLog.Status == pb.RankLogStatus_RANK_LOG_FILE_MISSING || rankLog.Status == pb.RankLogStatus_RANK_LOG_READ_ERROR {
+			continue
+		}
+		if rankLog.SuspendSeconds <= threshold {
+			delete(w.hung, rankLog.Rank)
+			continue
+		}
+		if !w.hung[rankLog.Rank] {
+			suspended[rankLog.Rank] = rankLog.SuspendSeconds
+		}
+	}
+	if len(suspended) == 0 {
+		return nil
+	}
+
+	ranks := make([]string, 0, len(suspended))
+	var maxSuspend int32
+	for rank, seconds := range suspended {
+		ranks = append(ranks, rank)
+		maxSuspend = max(maxSuspend, seconds)
+	}
+	sort.Strings(ranks)
+
+	// Logs also stop when the job finished, and the last run is still found after an agent
+	// restart. Without trainers there is nothing hanging.
+	if running, err := w.hasTrainers(ctx); err != nil {
+		logger.Logger.Warn("Failed to list training processes", zap.Error(err))
+	} else if !running {
+		for _, rank := range ranks {
+			w.hung[rank] = true
+		}
+		logger.Logger.Info("Rank logs stopped without training processes", zap.Strings("ranks", ranks))
+		return nil
+	}
+
+	metadata := storage.Metadata{
+		"ranks":           ranks,
+		"suspend_seconds": suspended,
+		"threshold":       threshold,
+	}
+	// All processes of the node: in a collective hang the other ranks matter as much
+	if stacks, err := w.snapshot(ctx); err != nil {
+		metadata["stack_error"] = err.Error()
+	} else {
+		metadata["stacks"] = stacks
+	}
+
+	_, err = w.store.StoreEvent(storage.EventEntry{
+		Source:   EventSource,
+		Type:     EventType,
+		Message:  fmt.Sprintf("Suspected hang: log of %s not updated for %d seconds (threshold %d)", strings.Join(ranks, ", "), maxSuspend, threshold),
+		Severity: int32(pb.Severity_ERROR),
+		Metadata: metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store hang event: %w", err)
+	}
+	for _, rank := range ranks {
+		w.hung[rank] = true
+	}
+	logger.Logger.Info("Hang detected", zap.Strings("ranks", ranks), zap.Int32("suspend_seconds", maxSuspend))
+	return nil
+}
+
+// Whether trainer processes run on the node. The trainers of the last check of the process
+// tracker are used when processes are tracked.
+func (w *Watchdog) hasTrainers(ctx context.Context) (bool, error) {
+	if w.processes != nil && w.processes.trainers != nil {
+		return len(w.processes.trainers) > 0, nil
+	}
+	processes, err := listProcesses(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, proc := range processes {
+		if proc.Type == scripts.TypeTrainer {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Stacks of all processes of the node as a ProcessInfoList in JSON
+func (w *Watchdog) snapshot(ctx context.Context) (json.RawMessage, error) {
+	req := &pb.GetProcessStacksRequest{}
+	if w.native {
+		req.NativeMode = pb.NativeMode_NATIVE
+	}
+	processes, err := dumpStacks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := protojson.Marshal(&pb.ProcessInfoList{
+		Processes:  processes,
+		TotalCount: int32(len(processes)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
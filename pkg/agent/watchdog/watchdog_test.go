@@ -0,0 +1,167 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package watchdog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"deeptrace/pkg/agent/util/scripts"
+	"deeptrace/pkg/agent/util/storage"
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	events []storage.EventEntry
+}
+
+func (s *fakeStore) StoreEvent(event storage.EventEntry) (string, error) {
+	s.events = append(s.events, event)
+	return "events.json", nil
+}
+
+// Trainers running on the node
+func runningTrainers(ctx context.Context) ([]scripts.ProcessInfo, error) {
+	return []scripts.ProcessInfo{{Type: scripts.TypeTrainer, PID: 100, Rank: 0}}, nil
+}
+
+func TestWatchdog_Check(t *testing.T) {
+	origRead, origDump, origList := readRankLogs, dumpStacks, listProcesses
+	defer func() { readRankLogs, dumpStacks, listProcesses = origRead, origDump, origList }()
+	listProcesses = runningTrainers
+
+	var rankLogs []*pb.RankLog
+	readRankLogs = func(ctx context.Context, workDir string) ([]*pb.RankLog, error) {
+		return rankLogs, nil
+	}
+	dumps := 0
+	dumpStacks = func(ctx context.Context, req *pb.GetProcessStacksRequest) ([]*pb.ProcessInfo, error) {
+		dumps++
+		return []*pb.ProcessInfo{{Pid: 100, Rank: "RANK0", Type: pb.ProcessType_PROCESS_TRAINER}}, nil
+	}
+
+	store := &fakeStore{}
+	w := New(Config{Threshold: 300}, store)
+	rounds := []struct {
+		name       string
+		rankLogs   []*pb.RankLog
+		wantEvents int
+		wantRanks  []string
+	}{
+		{
+			name: "all ranks writing",
+			rankLogs: []*pb.RankLog{
+				{Rank: "RANK0", SuspendSeconds: 10},
+				{Rank: "RANK1", SuspendSeconds: 20},
+			},
+		},
+		{
+			name: "rank 1 stopped, missing logs are not judged",
+			rankLogs: []*pb.RankLog{
+				{Rank: "RANK0", SuspendSeconds: 10},
+				{Rank: "RANK1", SuspendSeconds: 400},
+				{Rank: "RANK2", Status: pb.RankLogStatus_RANK_LOG_FILE_MISSING, SuspendSeconds: 1000},
+			},
+			wantEvents: 1,
+			wantRanks:  []string{"RANK1"},
+		},
+		{
+			name: "still stopped, reported once",
+			rankLogs: []*pb.RankLog{
+				{Rank: "RANK0", SuspendSeconds: 10},
+				{Rank: "RANK1", SuspendSeconds: 460},
+			},
+			wantEvents: 1,
+		},
+		{
+			name: "rank 0 stops too",
+			rankLogs: []*pb.RankLog{
+				{Rank: "RANK0", SuspendSeconds: 301},
+				{Rank: "RANK1", SuspendSeconds: 520},
+			},
+			wantEvents: 2,
+			wantRanks:  []string{"RANK0"},
+		},
+		{
+			name: "both resume and stop again",
+			rankLogs: []*pb.RankLog{
+				{Rank: "RANK0", SuspendSeconds: 5},
+				{Rank: "RANK1", SuspendSeconds: 5},
+			},
+			wantEvents: 2,
+		},
+		{
+			name: "both stopped again",
+			rankLogs: []*pb.RankLog{
+				{Rank: "RANK0", SuspendSeconds: 310},
+				{Rank: "RANK1", SuspendSeconds: 320},
+			},
+			wantEvents: 3,
+			wantRanks:  []string{"RANK0", "RANK1"},
+		},
+	}
+	for _, round := range rounds {
+		rankLogs = round.rankLogs
+		assert.NoError(t, w.Check(context.TODO()), round.name)
+		assert.Len(t, store.events, round.wantEvents, round.name)
+		if round.wantRanks != nil {
+			event := store.events[len(store.events)-1]
+			assert.Equal(t, EventType, event.Type, round.name)
+			assert.Equal(t, round.wantRanks, event.Metadata["ranks"], round.name)
+			assert.Contains(t, string(event.Metadata["stacks"].(json.RawMessage)), `"pid":100`, round.name)
+		}
+	}
+	assert.Equal(t, 3, dumps)
+}
+
+func TestWatchdog_Check_stackError(t *testing.T) {
+	origRead, origDump, origList := readRankLogs, dumpStacks, listProcesses
+	defer func() { readRankLogs, dumpStacks, listProcesses = origRead, origDump, origList }()
+	listProcesses = runningTrainers
+
+	readRankLogs = func(ctx context.Context, workDir string) ([]*pb.RankLog, error) {
+		return []*pb.RankLog{{Rank: "RANK0", SuspendSeconds: 700}}, nil
+	}
+	dumpStacks = func(ctx context.Context, req *pb.GetProcessStacksRequest) ([]*pb.ProcessInfo, error) {
+		return nil, errors.New("no stack backend available")
+	}
+
+	store := &fakeStore{}
+	assert.NoError(t, New(Config{}, store).Check(context.TODO()))
+	assert.Len(t, store.events, 1)
+	assert.Equal(t, "no stack backend available", store.events[0].Metadata["stack_error"])
+	assert.Equal(t, int32(600), store.events[0].Metadata["threshold"])
+}
+
+func TestWatchdog_Check_noTrainers(t *testing.T) {
+	origRead, origDump, origList := readRankLogs, dumpStacks, listProcesses
+	defer func() { readRankLogs, dumpStacks, listProcesses = origRead, origDump, origList }()
+
+	readRankLogs = func(ctx context.Context, workDir string) ([]*pb.RankLog, error) {
+		return []*pb.RankLog{{Rank: "RANK0", SuspendSeconds: 700}}, nil
+	}
+	dumpStacks = func(ctx context.Context, req *pb.GetProcessStacksRequest) ([]*pb.ProcessInfo, error) {
+		t.Error("stacks dumped without trainers")
+		return nil, nil
+	}
+	var processes []scripts.ProcessInfo
+	listProcesses = func(ctx context.Context) ([]scripts.ProcessInfo, error) {
+		return processes, nil
+	}
+
+	// The job finished, its logs stay behind
+	store := &fakeStore{}
+	w := New(Config{}, store)
+	assert.NoError(t, w.Check(context.TODO()))
+	assert.Empty(t, store.events)
+
+	// Same after an agent restart, with the tracker seeing no trainers either
+	w = New(Config{TrackProcesses: true}, store)
+	assert.NoError(t, w.processes.Check(context.TODO()))
+	assert.NoError(t, w.Check(context.TODO()))
+	assert.Empty(t, store.events)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -31,7 +32,8 @@ func NewCmdAlerts() *cobra.Command {
 		Use:   "alerts",
 		Short: "Get alert information",
 		Long: `Get alert information for the specified job.
-Support filtering by time range and severity level.
+Support filtering by time range and severity level. Hang events detected by the agent
+watchdog are included, their stack snapshots are saved under alerts/.
 
 Usage:
   client alerts --job-id <job name> -w clusterx [--interval-alert <interval minutes>][--min-severity <minimum severity level>] [--port <server port>]
@@ -365,6 +367,18 @@ func PrintAllNodeAlerts(results []NodeAlerts) []bool {
 				t.Local().Format("2006-01-02 15:04:05.000"), // millisecond
 				alert.Severity.String(),
 				alert.Message)
+
+			// Hang events of the agent watchdog carry the stacks of the node at detection time
+			if stacks, ok := alert.Metadata["stacks"]; ok {
+				fileName := fmt.Sprintf("%s_hang_stacks_%s.json", result.NodeAddr, t.Local().Format("2006-01-02_15-04-05"))
+				if err := utils.AppendWithTimestamp("alerts", fileName, []byte(stacks)); err != nil {
+					fmt.Printf("    Failed to save stack snapshot: %v\n", err)
+				} else {
+					fmt.Printf("    Stack snapshot saved to %s\n", filepath.Join("alerts", fileName))
+				}
+			} else if stackErr, ok := alert.Metadata["stack_error"]; ok {
+				fmt.Printf("    No stack snapshot: %s\n", stackErr)
+			}
 		}
 		fmt.Println()
 
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"deeptrace/pkg/client/consensus"
 	"deeptrace/pkg/client/logs"
 	"deeptrace/pkg/client/utils"
 	"deeptrace/pkg/rules"
@@ -43,8 +44,23 @@ func NewCmdCheckHang() *cobra.Command {
 		Use:   "check-hang",
 		Short: "Intelligent hang detection",
 		Long: `Intelligently detect if the specified job is in a hang state.
+A rank is suspicious when its log stops for longer than the threshold, when its log keeps
+moving but the training step has not advanced for longer than the threshold, when its step
+falls more than --max-step-lag steps behind the most advanced rank, or when its loss becomes
+NaN. During evaluation and checkpoint saving, recognized from the log lines, the threshold is
+raised to the grace of the phase; "hang-phases" in the configuration file adds or overrides
+phases. The stacks of a suspicious node are then sampled several times and
+compared; "stack-normalization" in the configuration file sets which differences are ignored.
+Each round ends with a job verdict combining log staleness, stack stability across samples,
+cross-rank stack divergence, trainer process liveness and the trainer exits and restarts
+recorded by the agent watchdog; a trainer that exited with an error and was not restarted
+makes the job crashed rather than hung. Log lines and trainer stacks are matched against
+known failure signatures, such as NCCL timeouts or shared memory exhaustion, printed per rank
+with a suggested remediation; "signature-files" in the configuration file or --signature-file
+add signatures. When run once, the exit code
+reflects the verdict: 0 healthy, 2 suspected hang, 3 confirmed hang, 4 crashed.
 Usage:
-  client check-hang --job-id <job name> -w clusterx [--work-dir <working directory>] [--max-line <maximum lines>] [--threshold <preliminary judgment threshold for hang time>] [--interval-hang <automatic execution interval in minutes>] [--port <server port>]
+  client check-hang --job-id <job name> -w clusterx [--work-dir <working directory>] [--max-line <maximum lines>] [--threshold <preliminary judgment threshold for hang time>] [--interval-hang <automatic execution interval in minutes>] [--max-step-lag <steps>] [--diff-format text|json|html] [--verdict-file <file>] [--signature-file <file>] [--port <server port>]
 
 Examples:
   client check-hang --job-id my_job -w clusterx --threshold 100 --interval-hang 5 --port 50051`,
@@ -138,27 +154,42 @@ Examples:
 				fmt.Printf("Using time interval specified on command line: %d minutes\n", pollInterval)
 			}
 
+			// Phases, step lag, stack normalization and signatures, resolved like diagnose does
+			opts, err := ResolveOptions(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			opts.WorkDir, opts.MaxLines, opts.Threshold = workDir, maxLines, threshold
+			checker, err := NewChecker(opts)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+
 			// Convert minutes to time.Duration type
 			pollDuration := time.Duration(pollInterval) * time.Minute
 
+			verdictFile, _ := cmd.Flags().GetString("verdict-file")
+
 			if pollInterval == 0 {
 				fmt.Println("Execute detection only once, no polling")
-				runCheckHang(jobName, workDir, maxLines, threshold, addressList, port)
-				return
+				verdict := runCheckHang(checker, jobName, addressList, port, verdictFile)
+				os.Exit(verdict.ExitCode)
 			}
 
 			fmt.Printf("Starting intelligent detection, will automatically execute every %v...\n", pollDuration)
 			fmt.Println("Press Ctrl+C to stop detection")
 
 			// Execute first detection immediately
-			runCheckHang(jobName, workDir, maxLines, threshold, addressList, port)
+			runCheckHang(checker, jobName, addressList, port, verdictFile)
 
 			// Use ticker to implement timed polling
 			ticker := time.NewTicker(pollDuration)
 			defer ticker.Stop()
 
 			for range ticker.C {
-				runCheckHang(jobName, workDir, maxLines, threshold, addressList, port)
+				runCheckHang(checker, jobName, addressList, port, verdictFile)
 			}
 		},
 	}
@@ -168,24 +199,42 @@ Examples:
 	cmd.Flags().Int32Var(&maxLines, "max-line", 0, "Specify maximum log lines")
 	cmd.Flags().Int32Var(&threshold, "threshold", 0, "Specify preliminary judgment threshold for hang time")
 	cmd.Flags().IntP("interval-hang", "i", 0, "Automatic execution interval (minutes), 0 means execute only once")
+	cmd.Flags().Bool("ignore-line-numbers", false, "Ignore line numbers when comparing stack samples")
+	cmd.Flags().Int("top-frames", 0, "Compare only the innermost frames of stack samples, 0 compares all")
+	cmd.Flags().Int64("max-step-lag", defaultMaxStepLag, "Steps a rank may fall behind the most advanced rank, 0 disables the check")
+	cmd.Flags().String("verdict-file", "", "Write the job verdict with its evidence as JSON to this file")
+	cmd.Flags().StringSlice("signature-file", nil, "YAML file of failure signatures added to the built-in ones, can be repeated")
+	cmd.Flags().String("diff-format", diffFormatText, "Format of stack sample differences: text, json or html (written to checkStacks)")
 
 	return cmd
 }
 
-func runCheckHang(jobName, workDir string, maxLines, threshold int32, addressList []string, port string) {
+func runCheckHang(checker *Checker, jobName string, addressList []string, port, verdictFile string) *Verdict {
 	if len(addressList) == 0 {
 		os.Exit(1)
 	}
 
-	errorNodes := CheckLogs(jobName, addressList, workDir, maxLines, threshold, port)
-	if len(errorNodes) > 0 {
-		fmt.Printf("Nodes with errors: %v\n", errorNodes)
+	verdict := checker.CheckLogs(jobName, addressList, port)
+	printVerdict(verdict)
+	if verdictFile != "" {
+		if err := writeVerdictFile(verdict, verdictFile); err != nil {
+			fmt.Println("Error:", err)
+		} else {
+			fmt.Printf("Verdict saved to %s\n", verdictFile)
+		}
 	}
+	return verdict
 }
 
-func CheckLogs(job string, addressList []string, workDir string, maxLines int32, threshold int32, port string) []string {
-	// Used to store nodes with suspendSeconds exceeding threshold
-	suspiciousNodes := make(map[string]struct{})
+// CheckLogs runs a round: it checks the rank logs of all nodes, samples the stacks of
+// suspicious nodes and concludes the state of the job. The logs and stack samples are saved
+// to the checkLogs and checkStacks directories under the output dir of the options.
+func (c *Checker) CheckLogs(job string, addressList []string, port string) *Verdict {
+	opts := c.opts
+	obs := &observations{nodes: len(addressList), stacks: make(map[string]stackFinding), signatures: rules.NewSignatureReport(), normalizer: opts.Normalizer}
+	// Nodes whose stacks are sampled, and the latest step of every rank
+	suspiciousNodes := make(map[string]bool)
+	var positions []rankPosition
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	results := make(chan Result, len(addressList))
@@ -212,8 +261,8 @@ func CheckLogs(job string, addressList []string, workDir string, maxLines int32,
 			defer cancel()
 
 			req := &pb.GetRecentLogsRequest{
-				MaxLines: maxLines,
-				WorkDir:  workDir,
+				MaxLines: opts.MaxLines,
+				WorkDir:  opts.WorkDir,
 			}
 
 			resp, err := client.GetRecentLogs(ctx, req)
@@ -223,6 +272,9 @@ func CheckLogs(job string, addressList []string, workDir string, maxLines int32,
 				return
 			}
 			results <- Result{node, resp, err}
+			mu.Lock()
+			obs.totalRanks += len(resp.Ranklogs)
+			mu.Unlock()
 			// Check suspendSeconds for each rank
 			for _, rankLog := range resp.Ranklogs {
 				// Clean invalid UTF-8 strings
@@ -230,15 +282,51 @@ func CheckLogs(job string, addressList []string, workDir string, maxLines int32,
 					entry.Message = utils.CleanUTF8(entry.Message)
 				}
 
-				// If suspendSeconds exceeds threshold, record the node
-				if rankLog.SuspendSeconds > threshold {
+				messages := make([]string, 0, len(rankLog.Entries))
+				for _, entry := range rankLog.Entries {
+					messages = append(messages, entry.Message)
+				}
+				obs.signatures.Add(node, rankLog.Rank, opts.Signatures.MatchLog(messages))
+
+				// Ranks without a readable log can't be judged
+				if rankLog.Status == pb.RankLogStatus_RANK_LOG_FILE_MISSING || rankLog.Status == pb.RankLogStatus_RANK_LOG_READ_ERROR {
+					fmt.Printf("Node %s %s: %s (%s)\n", node, rankLog.Rank, rankLog.Status, rankLog.StatusMessage)
 					mu.Lock()
-					suspiciousNodes[node] = struct{}{}
+					obs.unreadable = append(obs.unreadable, rankFinding{node, rankLog.Rank, fmt.Sprintf("%s: %s", rankLog.Status, rankLog.StatusMessage)})
+					mu.Unlock()
+					continue
+				}
+
+				// If suspendSeconds exceeds the threshold, raised during eval or checkpoint phases, record the rank
+				limit, phase := c.tracker.threshold(rankLog, opts.Threshold)
+				if rankLog.SuspendSeconds > limit {
+					detail := fmt.Sprintf("log not updated for %ds (threshold %ds)", rankLog.SuspendSeconds, limit)
+					if phase != "" {
+						detail = fmt.Sprintf("log not updated for %ds during %s (grace %ds)", rankLog.SuspendSeconds, phase, limit)
+					}
+					fmt.Printf("Suspicious node %s found: rank %s %s\n", node, rankLog.Rank, detail)
+					mu.Lock()
+					obs.stale = append(obs.stale, rankFinding{node, rankLog.Rank, detail})
+					suspiciousNodes[node] = true
+					mu.Unlock()
+					continue
+				}
+				if entry := latestStep(rankLog); entry != nil && phase == "" {
+					mu.Lock()
+					positions = append(positions, rankPosition{node, rankLog.Rank, entry.Epoch, entry.GetStep()})
+					mu.Unlock()
+				}
+				// The log is moving, check whether training still makes progress
+				if reason, hang := c.tracker.check(node, rankLog, opts.Threshold, time.Now()); reason != "" {
+					fmt.Printf("Suspicious node %s found: rank %s %s\n", node, rankLog.Rank, reason)
+					mu.Lock()
+					if hang {
+						obs.stalled = append(obs.stalled, rankFinding{node, rankLog.Rank, reason})
+						suspiciousNodes[node] = true
+					} else {
+						obs.anomalies = append(obs.anomalies, rankFinding{node, rankLog.Rank, reason})
+					}
 					mu.Unlock()
-					fmt.Printf("Suspicious node %s found: suspendSeconds is %d (exceeds threshold %d)\n",
-						node, rankLog.SuspendSeconds, threshold)
-					CheckHangStacks(node, rankLog.Rank, port)
-					break
 				}
 			}
 
@@ -255,29 +343,44 @@ func CheckLogs(job string, addressList []string, workDir string, maxLines int32,
 		res := <-results
 		if res.err != nil {
 			fmt.Printf("Failed to get logs from node %s: %v\n", res.node, res.err)
+			obs.unreachable = append(obs.unreachable, rankFinding{node: res.node, detail: res.err.Error()})
 			continue
 		}
 		// If rank is empty, add all logs
 		finalResponse.Ranklogs = append(finalResponse.Ranklogs, res.logs.Ranklogs...)
 	}
 	close(results)
+
+	// Ranks falling behind the rest of the job, unless they are already stuck
+	flagged := make(map[string]bool)
+	for _, f := range append(append([]rankFinding{}, obs.stale...), obs.stalled...) {
+		flagged[f.node+"/"+f.rank] = true
+	}
+	for _, f := range laggingRanks(positions, opts.MaxStepLag) {
+		if flagged[f.node+"/"+f.rank] {
+			continue
+		}
+		fmt.Printf("Suspicious node %s found: rank %s %s\n", f.node, f.rank, f.detail)
+		obs.stalled = append(obs.stalled, f)
+		suspiciousNodes[f.node] = true
+	}
+
+	// Sample the stacks of every suspicious node in parallel
+	for node := range suspiciousNodes {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			stable, err := CheckHangStacks(node, port, opts)
+			mu.Lock()
+			obs.stacks[node] = stackFinding{stable: stable, err: err}
+			mu.Unlock()
+		}(node)
+	}
+	wg.Wait()
+
 	customResponse := logs.CustomLogResponse{}
 	for _, rankLog := range finalResponse.Ranklogs {
-		var customEntries []logs.CustomLogEntry
-		for _, entry := range rankLog.Entries {
-			customEntries = append(customEntries, logs.CustomLogEntry{
-				Timestamp: utils.FormatTimestamp(entry.Timestamp),
-				Level:     entry.Level,
-				Epoch:     entry.Epoch,
-				Message:   entry.Message,
-			})
-		}
-		customResponse.Ranklogs = append(customResponse.Ranklogs, logs.CustomRankLog{
-			Rank:           rankLog.Rank,
-			Entries:        customEntries,
-			SuspendSeconds: rankLog.SuspendSeconds,
-			TailTime:       utils.FormatTimestamp(rankLog.TailTime),
-		})
+		customResponse.Ranklogs = append(customResponse.Ranklogs, logs.NewCustomRankLog(rankLog))
 	}
 	jsonData, err := json.MarshalIndent(customResponse, "", "  ")
 	if customResponse.Ranklogs != nil {
@@ -287,22 +390,43 @@ func CheckLogs(job string, addressList []string, workDir string, maxLines int32,
 			fmt.Printf("Failed to convert to JSON: %v\n", err)
 		}
 		fileName := fmt.Sprintf("%s_checkLogs_%s.json", job, formattedTime)
-		err = utils.AppendWithTimestamp("checkLogs", fileName, jsonData)
+		err = utils.AppendWithTimestamp(filepath.Join(opts.OutputDir, checkLogsDir), fileName, jsonData)
 		if err != nil {
 			fmt.Println("Error:", err)
 		} else {
 			fmt.Printf("Log information successfully saved to %s\n", fileName)
 		}
 	}
-	// Convert map to slice
-	result := make([]string, 0, len(suspiciousNodes))
-	for node := range suspiciousNodes {
-		result = append(result, node)
+
+	// Trainers that exited or were restarted, a crash may leave the remaining ranks stuck
+	obs.exits, obs.restarts = fetchProcessEvents(addressList, port, c.processEventsSince(time.Now()))
+
+	// Process liveness and cross-rank divergence of the whole job, only needed when ranks are stuck
+	if len(obs.stale)+len(obs.stalled) > 0 {
+		fmt.Println("Dumping trainer stacks of all nodes to check process liveness and divergence")
+		obs.trainers = consensus.FetchTrainerStacks(addressList, port, "", stackSampleTimeout)
+		if obs.trainers == nil {
+			obs.trainers = []rules.NodeProcesses{}
+		}
+		obs.signatures.AddStacks(opts.Signatures, obs.trainers)
 	}
 
-	return result
+	if ranks := obs.signatures.Ranks(); len(ranks) > 0 {
+		fmt.Println("Known failure signatures:")
+		for _, rank := range ranks {
+			fmt.Print(rank.Text())
+		}
+	}
+
+	return judge(obs)
 }
 
+// Directories the rank logs and the stack samples of each round are saved to
+const (
+	checkLogsDir   = "checkLogs"
+	checkStacksDir = "checkStacks"
+)
+
 // Save process information to file
 func saveProcessesToFile(processes []*pb.ProcessInfo, filePath string, dir string) error {
 
@@ -349,94 +473,141 @@ func saveProcessesToFile(processes []*pb.ProcessInfo, filePath string, dir strin
 	return nil
 }
 
-func CheckHangStacks(node string, rank string, port string) {
-	currentProcesses := make([][]*pb.ProcessInfo, 5)
-	datee := make([]time.Time, 5)
-
-	for i := 0; i < 5; i++ {
-		conn, err := grpc.Dial(
-			node+":"+port,
-			grpc.WithInsecure(),
-			grpc.WithTimeout(5*time.Second), // Increase connection timeout
-		)
-		if err != nil {
-			fmt.Printf("Failed to connect to node %s: %v\n", node, err)
-			continue
-		}
-		defer conn.Close()
-
-		client := pb.NewDeepTraceServiceClient(conn)
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+// Number of stack samples taken on a suspicious node and their interval
+const (
+	stackSampleCount    = 5
+	stackSampleInterval = 5
+	stackSampleTimeout  = 20
+)
 
-		req := &pb.GetProcessStacksRequest{}
+// CheckHangStacks samples the stacks of a node several times and compares consecutive
+// samples after the stack normalization of opts, saving them to the checkStacks directory
+// under its output dir. stable reports whether no sample differed from the previous one.
+func CheckHangStacks(node string, port string, opts Options) (stable bool, err error) {
+	stacksDir := filepath.Join(opts.OutputDir, checkStacksDir)
+	conn, err := grpc.Dial(
+		node+":"+port,
+		grpc.WithInsecure(),
+		grpc.WithTimeout(5*time.Second), // Increase connection timeout
+	)
+	if err != nil {
+		fmt.Printf("Failed to connect to node %s: %v\n", node, err)
+		return false, err
+	}
+	defer conn.Close()
+
+	client := pb.NewDeepTraceServiceClient(conn)
+	// The agent samples for count*interval plus the time of each dump
+	timeout := time.Duration(stackSampleCount*(stackSampleInterval+stackSampleTimeout)+10) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := client.SampleProcessStacks(ctx, &pb.SampleProcessStacksRequest{
+		Count:           stackSampleCount,
+		IntervalSeconds: stackSampleInterval,
+		TimeoutSeconds:  stackSampleTimeout,
+	})
+	if err != nil {
+		fmt.Printf("Failed to get type stack information from node %s: %v\n", node, err)
+		return false, err
+	}
+	if len(resp.Samples) < 2 {
+		return false, fmt.Errorf("got %d stack samples, at least 2 are needed", len(resp.Samples))
+	}
 
-		resp, err := client.GetProcessStacks(ctx, req)
-		if err != nil {
-			fmt.Printf("Failed to get type stack information from node %s: %v\n", node, err)
-			continue
+	stable = true
+	// Processes compared in at least one pair of samples
+	compared := make(map[int32]bool)
+	var previous []*pb.ProcessInfo
+	var previousTime time.Time
+	for i, sample := range resp.Samples {
+		// Processes that could not be dumped have no stacks to save
+		dumped := make([]*pb.ProcessInfo, 0, len(sample.Processes))
+		for _, proc := range sample.Processes {
+			if proc.Status != pb.StackStatus_STACK_OK {
+				fmt.Printf("Failed to dump stacks of PID %d %s on node %s: %s: %s\n", proc.Pid, proc.Rank, node, proc.Status, proc.Error)
+				continue
+			}
+			dumped = append(dumped, proc)
 		}
-
 		customResult := CustomStackResult{
 			ProcessType: 0,
-			Processes:   resp.Processes,
-		}
-
-		if currentProcesses[i] == nil {
-			currentProcesses[i] = make([]*pb.ProcessInfo, 0)
+			Processes:   dumped,
 		}
-		currentProcesses[i] = append(currentProcesses[i], customResult.Processes...)
+		sampleTime := sample.Time.AsTime().Local()
 
-		ctx = context.Background()
 		if i == 0 {
-			datee[i] = time.Now()
-			formattedTime := datee[i].Format("2006-01-02_15-04-05")
+			formattedTime := sampleTime.Format("2006-01-02_15-04-05")
 			fileName := fmt.Sprintf("node%s_processInfo_%s.json", node, formattedTime)
-			err1 := saveProcessesToFile(customResult.Processes, fileName, "checkStacks")
+			err1 := saveProcessesToFile(customResult.Processes, fileName, stacksDir)
 			if err1 != nil {
 				fmt.Println("Error:", err1)
 			} else {
 				fmt.Printf("Process data successfully saved to %s\n", fileName)
 			}
 		} else {
-			datee[i] = time.Now()
-			fmt.Println("Start comparing", node, "at", datee[i].Format("2006-01-02 15:04:05"), "with", datee[i-1].Format("2006-01-02 15:04:05"), "training process stack information.")
+			fmt.Println("Start comparing", node, "at", sampleTime.Format("2006-01-02 15:04:05"), "with", previousTime.Format("2006-01-02 15:04:05"), "training process stack information.")
 			fmt.Println()
-			b, oo, error := rules.PstreeEqual(ctx, datee[i].Format("2006-01-02 15:04:05"), datee[i-1].Format("2006-01-02 15:04:05"), currentProcesses[i], currentProcesses[i-1])
+			b, oo, undumpable, error := rules.PstreeEqual(ctx, opts.Normalizer, sampleTime.Format("2006-01-02 15:04:05"), previousTime.Format("2006-01-02 15:04:05"), sample.Processes, previous)
 			if error != nil {
 				fmt.Println(error)
 			}
+			stable = stable && b && error == nil
+			// A process not dumped in both samples is not a difference, a hung rank often times out
+			if len(undumpable) > 0 {
+				fmt.Printf("Not compared, stacks not dumped in both samples: PIDs %v\n", undumpable)
+			}
+			skipped := make(map[int32]bool, len(undumpable))
+			for _, pid := range undumpable {
+				skipped[pid] = true
+			}
+			for _, proc := range sample.Processes {
+				if !skipped[proc.Pid] {
+					compared[proc.Pid] = true
+				}
+			}
 			fmt.Println("Detection results:")
+			suffix := "noDiff"
 			if !b {
-				for _, diffline := range oo {
-					fmt.Println(diffline.Diff)
-					fmt.Println("--------------------------------------------------")
-				}
-				// If there's an issue, save JSON file
-				formattedTime := datee[i].Format("2006-01-02_15-04-05")
-				fileName := fmt.Sprintf("node%s_processInfo_%s_haveDiff.json", node, formattedTime)
-				err1 := saveProcessesToFile(customResult.Processes, fileName, "checkStacks")
-				if err1 != nil {
-					fmt.Println("Error:", err1)
-				} else {
-					fmt.Printf("Process data successfully saved to %s\n", fileName)
-				}
+				printStackDiffs(opts.DiffFormat, stacksDir, node, sampleTime.Format("2006-01-02_15-04-05"), oo)
+				suffix = "haveDiff"
 			} else {
 				fmt.Println("No anomalies detected")
-				formattedTime := datee[i].Format("2006-01-02_15-04-05")
-				fileName := fmt.Sprintf("node%s_processInfo_%s_noDiff.json", node, formattedTime)
-				err1 := saveProcessesToFile(customResult.Processes, fileName, "checkStacks")
-				if err1 != nil {
-					fmt.Println("Error:", err1)
-				} else {
-					fmt.Printf("Process data successfully saved to %s\n", fileName)
-				}
-
+			}
+			formattedTime := sampleTime.Format("2006-01-02_15-04-05")
+			fileName := fmt.Sprintf("node%s_processInfo_%s_%s.json", node, formattedTime, suffix)
+			err1 := saveProcessesToFile(customResult.Processes, fileName, stacksDir)
+			if err1 != nil {
+				fmt.Println("Error:", err1)
+			} else {
+				fmt.Printf("Process data successfully saved to %s\n", fileName)
 			}
 			fmt.Println("--------------------------------------------------")
 		}
+		previous, previousTime = sample.Processes, sampleTime
+	}
 
-		// Sleep for 5 seconds
-		time.Sleep(5 * time.Second)
+	printUnchangedThreads(node, resp.Threads)
+	if len(compared) == 0 {
+		return false, fmt.Errorf("no process was dumped in two consecutive samples")
+	}
+	return stable, nil
+}
+
+// Print the threads whose stack did not change in any sample
+func printUnchangedThreads(node string, threads []*pb.ThreadSummary) {
+	var unchanged []*pb.ThreadSummary
+	for _, thread := range threads {
+		if thread.Unchanged {
+			unchanged = append(unchanged, thread)
+		}
+	}
+	if len(unchanged) == 0 {
+		return
+	}
+	fmt.Printf("Threads on node %s with the same stack in every sample:\n", node)
+	for _, thread := range unchanged {
+		fmt.Printf("  PID %d %s %s thread %d (%s), %d samples\n", thread.Pid, thread.Type, thread.Rank, thread.ThreadId, thread.ThreadName, thread.Samples)
 	}
+	fmt.Println("--------------------------------------------------")
 }
@@ -0,0 +1,102 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+
+	"deeptrace/pkg/rules"
+)
+
+// Formats of the stack differences printed by check-hang
+const (
+	diffFormatText = "text"
+	diffFormatJSON = "json"
+	diffFormatHTML = "html"
+)
+
+func validDiffFormat(format string) bool {
+	switch format {
+	case diffFormatText, diffFormatJSON, diffFormatHTML:
+		return true
+	}
+	return false
+}
+
+// Print the differences between two stack samples of a node in format. The HTML report is
+// written to a file in dir, next to the saved samples.
+func printStackDiffs(format, dir, node, formattedTime string, diffs []rules.ProccessInfoDiff) {
+	switch format {
+	case diffFormatJSON:
+		if err := writeDiffsJSON(os.Stdout, diffs); err != nil {
+			fmt.Println("Error:", err)
+		}
+	case diffFormatHTML:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fileName := filepath.Join(dir, fmt.Sprintf("node%s_diff_%s.html", node, formattedTime))
+		file, err := os.Create(fileName)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		defer file.Close()
+		if err := writeDiffsHTML(file, node, diffs); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("Stack differences saved to %s\n", fileName)
+	default:
+		for _, diffline := range diffs {
+			fmt.Println(diffline.Diff)
+			fmt.Println("--------------------------------------------------")
+		}
+	}
+}
+
+func writeDiffsJSON(w io.Writer, diffs []rules.ProccessInfoDiff) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diffs)
+}
+
+var diffTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Stack differences on node {{.Node}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td pre { margin: 0; white-space: pre-wrap; }
+.before { background: #fdecea; }
+.after { background: #e8f5e9; }
+</style>
+</head>
+<body>
+<h1>Stack differences on node {{.Node}}</h1>
+{{range .Diffs}}
+<h2>{{.Rank}} {{.PType}} PID {{.Pid}}</h2>
+<table>
+<tr><th>Kind</th><th>Thread</th><th>Frame</th><th>{{.Time1}}</th><th>{{.Time2}}</th></tr>
+{{range .Diffs}}<tr><td>{{.Kind}}</td><td>{{if .ThreadID}}{{.ThreadID}} {{.ThreadName}}{{end}}</td><td>{{if .FrameIndex}}{{.FrameIndex}}{{end}}</td><td class="before"><pre>{{.Before}}</pre></td><td class="after"><pre>{{.After}}</pre></td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+func writeDiffsHTML(w io.Writer, node string, diffs []rules.ProccessInfoDiff) error {
+	return diffTemplate.Execute(w, struct {
+		Node  string
+		Diffs []rules.ProccessInfoDiff
+	}{node, diffs})
+}
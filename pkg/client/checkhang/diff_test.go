@@ -0,0 +1,48 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"deeptrace/pkg/rules"
+	pb "deeptrace/v1"
+)
+
+func TestWriteDiffs(t *testing.T) {
+	diffs := []rules.ProccessInfoDiff{{
+		Rank:  "RANK0",
+		PType: pb.ProcessType_PROCESS_TRAINER,
+		Pid:   100,
+		Time1: "time1",
+		Time2: "time2",
+		Diffs: []rules.StackDiff{
+			{Kind: rules.DiffFrameContent, ThreadID: 100, ThreadName: "python", FrameIndex: 3, Before: "<module>", After: "step"},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeDiffsJSON(&buf, diffs); err != nil {
+		t.Fatalf("writeDiffsJSON() error = %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+	}
+	if len(got) != 1 || got[0]["rank"] != "RANK0" || got[0]["diffs"].([]any)[0].(map[string]any)["kind"] != "frame_content" {
+		t.Errorf("writeDiffsJSON() got = %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeDiffsHTML(&buf, "node1", diffs); err != nil {
+		t.Fatalf("writeDiffsHTML() error = %v", err)
+	}
+	for _, want := range []string{"node1", "PROCESS_TRAINER", "&lt;module&gt;", "frame_content"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeDiffsHTML() missing %q", want)
+		}
+	}
+}
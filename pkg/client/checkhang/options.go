@@ -0,0 +1,112 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"fmt"
+	"time"
+
+	"deeptrace/pkg/rules"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Steps a rank may fall behind the most advanced rank of the job when neither --max-step-lag
+// nor the configuration file sets it
+const defaultMaxStepLag = 10
+
+// Options of check-hang rounds, shared by the check-hang and diagnose commands
+type Options struct {
+	WorkDir   string
+	MaxLines  int32
+	Threshold int32
+	// Steps a rank may fall behind the most advanced rank, 0 disables the check
+	MaxStepLag int64
+	// Phases with a longer grace, e.g. evaluation and checkpoint saving
+	Phases []Phase
+	// Stack differences ignored when comparing samples
+	Normalizer *rules.Normalizer
+	// Known failure signatures matched in the logs and stacks
+	Signatures *rules.SignatureLibrary
+	// Format of the stack differences: text, json or html
+	DiffFormat string
+	// The checkLogs and checkStacks directories are saved under OutputDir
+	OutputDir string
+}
+
+// ResolveOptions resolves the hang phases, the step lag, the stack normalization, the
+// failure signatures and the diff format: a flag given on the command line wins over the
+// configuration file, which wins over the default. Flags the command does not define are
+// skipped. The work dir, log lines, threshold and output dir are left to the command.
+func ResolveOptions(cmd *cobra.Command) (Options, error) {
+	opts := Options{MaxStepLag: defaultMaxStepLag, DiffFormat: diffFormatText, OutputDir: "."}
+	if err := viper.UnmarshalKey("hang-phases", &opts.Phases); err != nil {
+		return opts, fmt.Errorf("invalid hang-phases in configuration file: %v", err)
+	}
+	if flagChanged(cmd, "max-step-lag") {
+		opts.MaxStepLag, _ = cmd.Flags().GetInt64("max-step-lag")
+	} else if viper.IsSet("max-step-lag") {
+		opts.MaxStepLag = viper.GetInt64("max-step-lag")
+	}
+
+	var normalization rules.NormalizeConfig
+	if err := viper.UnmarshalKey("stack-normalization", &normalization); err != nil {
+		return opts, fmt.Errorf("invalid stack-normalization in configuration file: %v", err)
+	}
+	if flagChanged(cmd, "ignore-line-numbers") {
+		normalization.IgnoreLineNumbers, _ = cmd.Flags().GetBool("ignore-line-numbers")
+	}
+	if flagChanged(cmd, "top-frames") {
+		normalization.TopFrames, _ = cmd.Flags().GetInt("top-frames")
+	}
+	var err error
+	if opts.Normalizer, err = rules.NewNormalizer(normalization); err != nil {
+		return opts, fmt.Errorf("invalid stack normalization: %v", err)
+	}
+
+	// The built-in signatures extended by the user's files
+	var signatureFiles []string
+	if flagChanged(cmd, "signature-file") {
+		signatureFiles, _ = cmd.Flags().GetStringSlice("signature-file")
+	} else {
+		signatureFiles = viper.GetStringSlice("signature-files")
+	}
+	if opts.Signatures, err = rules.LoadSignatures(signatureFiles); err != nil {
+		return opts, fmt.Errorf("invalid failure signatures: %v", err)
+	}
+
+	if flagChanged(cmd, "diff-format") {
+		opts.DiffFormat, _ = cmd.Flags().GetString("diff-format")
+	} else if viper.IsSet("diff-format") {
+		opts.DiffFormat = viper.GetString("diff-format")
+	}
+	if !validDiffFormat(opts.DiffFormat) {
+		return opts, fmt.Errorf("invalid diff format %q, expected text, json or html", opts.DiffFormat)
+	}
+	return opts, nil
+}
+
+// Whether the command defines the flag and it was given on the command line
+func flagChanged(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	return flag != nil && flag.Changed
+}
+
+// Checker runs the check-hang rounds of a job, the training steps seen and the start of
+// the previous round are kept across rounds
+type Checker struct {
+	opts    Options
+	tracker *progressTracker
+	// Start of the previous round, zero before the first round
+	lastRound time.Time
+}
+
+// NewChecker validates the hang phases of the options
+func NewChecker(opts Options) (*Checker, error) {
+	tracker := newProgressTracker()
+	if err := tracker.setPhases(opts.Phases); err != nil {
+		return nil, fmt.Errorf("invalid hang phases: %v", err)
+	}
+	return &Checker{opts: opts, tracker: tracker}, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestResolveOptions(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("max-step-lag", 20)
+	viper.Set("diff-format", "json")
+	viper.Set("hang-phases", []map[string]any{{"name": "eval", "pattern": "Running eval", "grace": 600}})
+
+	tests := []struct {
+		name       string
+		flags      bool
+		args       []string
+		wantMaxLag int64
+	}{
+		{name: "configuration file", flags: true, wantMaxLag: 20},
+		{name: "flag", flags: true, args: []string{"--max-step-lag", "5"}, wantMaxLag: 5},
+		// diagnose defines no check-hang flags
+		{name: "command without the flag", wantMaxLag: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			if tt.flags {
+				cmd.Flags().Int64("max-step-lag", defaultMaxStepLag, "")
+			}
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			opts, err := ResolveOptions(cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.MaxStepLag != tt.wantMaxLag {
+				t.Errorf("MaxStepLag = %d, want %d", opts.MaxStepLag, tt.wantMaxLag)
+			}
+			if len(opts.Phases) != 1 || opts.Phases[0].Grace != 600 {
+				t.Errorf("Phases = %v, want the configured eval phase", opts.Phases)
+			}
+			if opts.Signatures == nil {
+				t.Error("Signatures not loaded")
+			}
+			if opts.DiffFormat != diffFormatJSON {
+				t.Errorf("DiffFormat = %q, want %q", opts.DiffFormat, diffFormatJSON)
+			}
+		})
+	}
+
+	viper.Reset()
+	opts, err := ResolveOptions(&cobra.Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.MaxStepLag != defaultMaxStepLag || opts.DiffFormat != diffFormatText {
+		t.Errorf("default MaxStepLag, DiffFormat = %d, %q, want %d, %q", opts.MaxStepLag, opts.DiffFormat, defaultMaxStepLag, diffFormatText)
+	}
+	viper.Set("diff-format", "xml")
+	if _, err := ResolveOptions(&cobra.Command{}); err == nil {
+		t.Error("ResolveOptions() accepted an invalid diff format")
+	}
+	if _, err := NewChecker(Options{Phases: []Phase{{Name: "bad", Pattern: "("}}}); err == nil {
+		t.Error("NewChecker() accepted an invalid phase pattern")
+	}
+}
+
+func TestProcessEventsSince(t *testing.T) {
+	first, second := time.Now(), time.Now().Add(5*time.Minute)
+	c, err := NewChecker(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since := c.processEventsSince(first); !since.Equal(first.Add(-processEventWindow)) {
+		t.Errorf("first round since = %v, want %v", since, first.Add(-processEventWindow))
+	}
+	if since := c.processEventsSince(second); !since.Equal(first) {
+		t.Errorf("second round since = %v, want the previous round %v", since, first)
+	}
+
+	// Each checker keeps its own window
+	other, _ := NewChecker(Options{})
+	if since := other.processEventsSince(second); !since.Equal(second.Add(-processEventWindow)) {
+		t.Errorf("other checker since = %v, want %v", since, second.Add(-processEventWindow))
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Event types recorded by the process tracker of the agent watchdog
+const (
+	eventProcessExit   = "process_exit"
+	eventWorkerRestart = "worker_restart"
+)
+
+// How far back the first round looks for trainer exits, later rounds look back to the
+// previous round
+const processEventWindow = time.Hour
+
+// Start of the process event window of the round starting at now, the previous round is
+// remembered as the start of the next window
+func (c *Checker) processEventsSince(now time.Time) time.Time {
+	since := c.lastRound
+	if since.IsZero() {
+		since = now.Add(-processEventWindow)
+	}
+	c.lastRound = now
+	return since
+}
+
+// Fetch the trainer exits and restarts the agents recorded since the given time. The events
+// are peeked, so they are still reported by the alerts command.
+func fetchProcessEvents(addressList []string, port string, since time.Time) (exits, restarts []processFinding) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, node := range addressList {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			conn, err := grpc.Dial(
+				node+":"+port,
+				grpc.WithInsecure(),
+				grpc.WithTimeout(5*time.Second),
+			)
+			if err != nil {
+				fmt.Printf("Failed to connect to node %s: %v\n", node, err)
+				return
+			}
+			defer conn.Close()
+
+			client := pb.NewAlertServiceClient(conn)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			resp, err := client.GetAlerts(ctx, &pb.GetAlertsRequest{
+				StartTime: timestamppb.New(since),
+				Types:     []string{eventProcessExit, eventWorkerRestart},
+				Peek:      true,
+			})
+			if err != nil {
+				fmt.Printf("Failed to get process events from node %s: %v\n", node, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, alert := range resp.Alerts {
+				f := processFinding{
+					rankFinding: rankFinding{node, alert.Metadata["rank"], alert.Message},
+					time:        alert.Timestamp.AsTime(),
+				}
+				switch alert.Type {
+				case eventProcessExit:
+					f.clean = alert.Metadata["exit_code"] == "0" && alert.Metadata["signal"] == ""
+					fmt.Printf("Node %s: %s\n", node, alert.Message)
+					exits = append(exits, f)
+				case eventWorkerRestart:
+					fmt.Printf("Node %s: %s\n", node, alert.Message)
+					restarts = append(restarts, f)
+				}
+			}
+		}(node)
+	}
+	wg.Wait()
+	return exits, restarts
+}
@@ -0,0 +1,206 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+
+	pb "deeptrace/v1"
+)
+
+// Last step seen for a rank and when it was first seen
+type stepState struct {
+	epoch int32
+	step  int64
+	since time.Time
+}
+
+// Phase is a training phase, e.g. evaluation or checkpoint saving, during which the log
+// and the step counter may legitimately stand still for longer than the threshold
+type Phase struct {
+	Name string `mapstructure:"name"`
+	// Log lines starting the phase, the phase lasts until the next step line
+	Pattern string `mapstructure:"pattern"`
+	// Seconds allowed without progress during the phase
+	Grace int32 `mapstructure:"grace"`
+}
+
+// Phases recognized without configuration
+var defaultPhases = []Phase{
+	{Name: "checkpoint", Pattern: `(?i)(sav(e|ing)|writ(e|ing)) (the )?checkpoint|checkpoint sav(e|ing)`, Grace: 1800},
+	{Name: "eval", Pattern: `(?i)\b(evaluat(e|ing|ion)|validat(e|ing|ion)|running eval)\b`, Grace: 1800},
+}
+
+type phaseMatcher struct {
+	name  string
+	reg   *regexp.Regexp
+	grace int32
+}
+
+// progressTracker remembers training steps across detection rounds, so a rank whose
+// log keeps moving while its step counter stands still is still detected
+type progressTracker struct {
+	mu     sync.Mutex
+	steps  map[string]stepState
+	phases []phaseMatcher
+}
+
+func newProgressTracker() *progressTracker {
+	t := &progressTracker{steps: make(map[string]stepState)}
+	if err := t.setPhases(nil); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Set the recognized phases, the configured ones are tried before the defaults and
+// replace a default of the same name
+func (t *progressTracker) setPhases(phases []Phase) error {
+	var matchers []phaseMatcher
+	names := make(map[string]bool)
+	for _, phase := range append(append([]Phase{}, phases...), defaultPhases...) {
+		if names[phase.Name] {
+			continue
+		}
+		names[phase.Name] = true
+		reg, err := regexp.Compile(phase.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern of phase %q: %v", phase.Name, err)
+		}
+		if phase.Grace < 0 {
+			return fmt.Errorf("grace of phase %q must not be negative", phase.Name)
+		}
+		matchers = append(matchers, phaseMatcher{name: phase.Name, reg: reg, grace: phase.Grace})
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases = matchers
+	return nil
+}
+
+// Phase the rank is in: a phase line logged after the last step line
+func (t *progressTracker) phase(rankLog *pb.RankLog) *phaseMatcher {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(rankLog.Entries) - 1; i >= 0; i-- {
+		entry := rankLog.Entries[i]
+		if entry.Step != nil {
+			return nil
+		}
+		for j := range t.phases {
+			if t.phases[j].reg.MatchString(entry.Message) {
+				return &t.phases[j]
+			}
+		}
+	}
+	return nil
+}
+
+// Threshold of a rank, raised to the grace of the phase it is in. Returns the phase
+// name, empty outside of a phase.
+func (t *progressTracker) threshold(rankLog *pb.RankLog, threshold int32) (int32, string) {
+	phase := t.phase(rankLog)
+	if phase == nil {
+		return threshold, ""
+	}
+	return max(threshold, phase.grace), phase.name
+}
+
+// Check the training progress of a rank whose log is not suspended. Returns why the
+// rank is abnormal, or an empty string, and whether it looks like a hang.
+func (t *progressTracker) check(node string, rankLog *pb.RankLog, threshold int32, now time.Time) (string, bool) {
+	lastStep := latestStep(rankLog)
+	var lastLoss *pb.LogEntry
+	for i := len(rankLog.Entries) - 1; i >= 0; i-- {
+		if entry := rankLog.Entries[i]; entry.Loss != nil {
+			lastLoss = entry
+			break
+		}
+	}
+
+	if lastLoss != nil && (math.IsNaN(lastLoss.GetLoss()) || math.IsInf(lastLoss.GetLoss(), 0)) {
+		if lastLoss.Step != nil {
+			return fmt.Sprintf("loss is %v at step %d", lastLoss.GetLoss(), lastLoss.GetStep()), false
+		}
+		return fmt.Sprintf("loss is %v", lastLoss.GetLoss()), false
+	}
+	if lastStep == nil {
+		return "", false
+	}
+	epoch, step := lastStep.Epoch, lastStep.GetStep()
+	threshold, phase := t.threshold(rankLog, threshold)
+	limit := time.Duration(threshold) * time.Second
+	during := ""
+	if phase != "" {
+		during = fmt.Sprintf(" during %s (grace %ds)", phase, threshold)
+	}
+
+	// The log went on long after the last step line
+	if lastStep.Timestamp != nil && rankLog.TailTime != nil {
+		if stalled := rankLog.TailTime.AsTime().Sub(lastStep.Timestamp.AsTime()); stalled > limit {
+			return fmt.Sprintf("step %d has not advanced for %ds while the log is still being written%s", step, int(stalled.Seconds()), during), true
+		}
+	}
+
+	// The same step was reported in earlier rounds
+	key := node + "/" + rankLog.Rank
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.steps[key]
+	if !ok || state.step != step || state.epoch != epoch {
+		t.steps[key] = stepState{epoch: epoch, step: step, since: now}
+		return "", false
+	}
+	if stalled := now.Sub(state.since); stalled > limit {
+		return fmt.Sprintf("step %d has not advanced for %ds while the log is still being written%s", step, int(stalled.Seconds()), during), true
+	}
+	return "", false
+}
+
+// Latest entry of a rank log with a step, nil if none
+func latestStep(rankLog *pb.RankLog) *pb.LogEntry {
+	for i := len(rankLog.Entries) - 1; i >= 0; i-- {
+		if entry := rankLog.Entries[i]; entry.Step != nil {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Latest step of a rank in one detection round
+type rankPosition struct {
+	node, rank string
+	epoch      int32
+	step       int64
+}
+
+// Ranks more than maxLag steps behind the most advanced rank of the job. In synchronous
+// training all ranks run the same step, a rank falling behind is stuck or straggling.
+// A rank in an earlier epoch always lags.
+func laggingRanks(positions []rankPosition, maxLag int64) []rankFinding {
+	if len(positions) < 2 || maxLag <= 0 {
+		return nil
+	}
+	lead := positions[0]
+	for _, p := range positions[1:] {
+		if p.epoch > lead.epoch || (p.epoch == lead.epoch && p.step > lead.step) {
+			lead = p
+		}
+	}
+	var lagging []rankFinding
+	for _, p := range positions {
+		if p.epoch == lead.epoch && lead.step-p.step <= maxLag {
+			continue
+		}
+		detail := fmt.Sprintf("at step %d, %d steps behind %s on %s at step %d", p.step, lead.step-p.step, lead.rank, lead.node, lead.step)
+		if p.epoch != lead.epoch {
+			detail = fmt.Sprintf("at epoch %d step %d, behind %s on %s at epoch %d step %d", p.epoch, p.step, lead.rank, lead.node, lead.epoch, lead.step)
+		}
+		lagging = append(lagging, rankFinding{p.node, p.rank, detail})
+	}
+	return lagging
+}
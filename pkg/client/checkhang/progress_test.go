@@ -0,0 +1,195 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "deeptrace/v1"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func stepEntry(ts time.Time, step int64, loss float64) *pb.LogEntry {
+	return &pb.LogEntry{Timestamp: timestamppb.New(ts), Step: proto.Int64(step), Loss: proto.Float64(loss)}
+}
+
+func TestProgressTracker_Check(t *testing.T) {
+	now := time.Date(2025, 7, 11, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		rankLogs   []*pb.RankLog // One per detection round, a minute apart
+		wantHang   bool
+		wantReason bool
+	}{
+		{
+			name: "no progress fields",
+			rankLogs: []*pb.RankLog{{
+				Entries:  []*pb.LogEntry{{Message: "hello"}},
+				TailTime: timestamppb.New(now),
+			}},
+		},
+		{
+			name: "advancing",
+			rankLogs: []*pb.RankLog{
+				{Entries: []*pb.LogEntry{stepEntry(now, 1, 2.0)}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{stepEntry(now.Add(time.Minute), 2, 1.9)}, TailTime: timestamppb.New(now.Add(time.Minute))},
+				{Entries: []*pb.LogEntry{stepEntry(now.Add(2*time.Minute), 3, 1.8)}, TailTime: timestamppb.New(now.Add(2 * time.Minute))},
+			},
+		},
+		{
+			name: "nan loss",
+			rankLogs: []*pb.RankLog{{
+				Entries:  []*pb.LogEntry{stepEntry(now, 1, 2.0), stepEntry(now, 2, math.NaN())},
+				TailTime: timestamppb.New(now),
+			}},
+			wantReason: true,
+		},
+		{
+			name: "log moves after the last step",
+			rankLogs: []*pb.RankLog{{
+				Entries:  []*pb.LogEntry{stepEntry(now, 5, 2.0), {Message: "NCCL WARN retry", Timestamp: timestamppb.New(now.Add(3 * time.Minute))}},
+				TailTime: timestamppb.New(now.Add(3 * time.Minute)),
+			}},
+			wantHang:   true,
+			wantReason: true,
+		},
+		{
+			name: "checkpoint saved after the last step",
+			rankLogs: []*pb.RankLog{{
+				Entries: []*pb.LogEntry{
+					stepEntry(now, 5, 2.0),
+					{Message: "Saving checkpoint to /ckpt/iter_5", Timestamp: timestamppb.New(now.Add(time.Minute))},
+				},
+				TailTime: timestamppb.New(now.Add(10 * time.Minute)),
+			}},
+		},
+		{
+			name: "new epoch restarts the step",
+			rankLogs: []*pb.RankLog{
+				{Entries: []*pb.LogEntry{{Epoch: 1, Step: proto.Int64(7), Timestamp: timestamppb.New(now)}}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{{Epoch: 1, Step: proto.Int64(7), Timestamp: timestamppb.New(now)}}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{{Epoch: 1, Step: proto.Int64(7), Timestamp: timestamppb.New(now)}}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{{Epoch: 2, Step: proto.Int64(7), Timestamp: timestamppb.New(now)}}, TailTime: timestamppb.New(now)},
+			},
+		},
+		{
+			name: "same step across rounds",
+			rankLogs: []*pb.RankLog{
+				{Entries: []*pb.LogEntry{stepEntry(now, 7, 2.0)}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{stepEntry(now, 7, 2.0)}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{stepEntry(now, 7, 2.0)}, TailTime: timestamppb.New(now)},
+				{Entries: []*pb.LogEntry{stepEntry(now, 7, 2.0)}, TailTime: timestamppb.New(now)},
+			},
+			wantHang:   true,
+			wantReason: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newProgressTracker()
+			var reason string
+			var hang bool
+			for i, rankLog := range tt.rankLogs {
+				rankLog.Rank = "0"
+				reason, hang = tracker.check("node1", rankLog, 120, now.Add(time.Duration(i)*time.Minute))
+			}
+			if (reason != "") != tt.wantReason || hang != tt.wantHang {
+				t.Errorf("check() = %q, %v, want reason %v, hang %v", reason, hang, tt.wantReason, tt.wantHang)
+			}
+		})
+	}
+}
+
+func TestProgressTracker_Threshold(t *testing.T) {
+	tracker := newProgressTracker()
+	if err := tracker.setPhases([]Phase{{Name: "eval", Pattern: `Running eval loop`, Grace: 600}}); err != nil {
+		t.Fatalf("setPhases() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		messages  []string
+		want      int32
+		wantPhase string
+	}{
+		{name: "training", messages: []string{"step 10 loss 1.2"}, want: 120},
+		{name: "configured phase", messages: []string{"Running eval loop"}, want: 600, wantPhase: "eval"},
+		{name: "default phase", messages: []string{"Saving checkpoint to /ckpt"}, want: 1800, wantPhase: "checkpoint"},
+		{name: "phase ended by a step", messages: []string{"Saving checkpoint to /ckpt", "step 11 loss 1.1"}, want: 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rankLog := &pb.RankLog{}
+			for i, msg := range tt.messages {
+				entry := &pb.LogEntry{Message: msg}
+				if strings.HasPrefix(msg, "step") {
+					entry.Step = proto.Int64(int64(10 + i))
+				}
+				rankLog.Entries = append(rankLog.Entries, entry)
+			}
+			got, phase := tracker.threshold(rankLog, 120)
+			if got != tt.want || phase != tt.wantPhase {
+				t.Errorf("threshold() = %d, %q, want %d, %q", got, phase, tt.want, tt.wantPhase)
+			}
+		})
+	}
+
+	if err := tracker.setPhases([]Phase{{Name: "bad", Pattern: "("}}); err == nil {
+		t.Error("setPhases() accepted an invalid pattern")
+	}
+}
+
+func TestLaggingRanks(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []rankPosition
+		maxLag    int64
+		want      []string
+	}{
+		{
+			name: "in step",
+			positions: []rankPosition{
+				{"node1", "RANK0", 0, 100}, {"node1", "RANK1", 0, 99}, {"node2", "RANK2", 0, 95},
+			},
+			maxLag: 10,
+		},
+		{
+			name: "one rank behind",
+			positions: []rankPosition{
+				{"node1", "RANK0", 0, 100}, {"node1", "RANK1", 0, 100}, {"node2", "RANK2", 0, 40},
+			},
+			maxLag: 10,
+			want:   []string{"RANK2"},
+		},
+		{
+			name: "earlier epoch",
+			positions: []rankPosition{
+				{"node1", "RANK0", 3, 2}, {"node2", "RANK1", 2, 500},
+			},
+			maxLag: 10,
+			want:   []string{"RANK1"},
+		},
+		{
+			name: "disabled",
+			positions: []rankPosition{
+				{"node1", "RANK0", 0, 100}, {"node2", "RANK1", 0, 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range laggingRanks(tt.positions, tt.maxLag) {
+				got = append(got, f.rank)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("laggingRanks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
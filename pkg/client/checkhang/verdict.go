@@ -0,0 +1,283 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"deeptrace/pkg/rules"
+	pb "deeptrace/v1"
+)
+
+// VerdictKind is the state of the job concluded by check-hang
+type VerdictKind string
+
+const (
+	VerdictHealthy       VerdictKind = "healthy"
+	VerdictSuspectedHang VerdictKind = "suspected_hang"
+	VerdictConfirmedHang VerdictKind = "confirmed_hang"
+	VerdictCrashed       VerdictKind = "crashed"
+)
+
+// Exit codes of check-hang per verdict, 1 is left for usage errors
+var verdictExitCodes = map[VerdictKind]int{
+	VerdictHealthy:       0,
+	VerdictSuspectedHang: 2,
+	VerdictConfirmedHang: 3,
+	VerdictCrashed:       4,
+}
+
+// Kinds of evidence
+const (
+	EvidenceLogStale         = "log_stale"
+	EvidenceProgressStalled  = "progress_stalled"
+	EvidenceLossAnomaly      = "loss_anomaly"
+	EvidenceLogUnreadable    = "log_unreadable"
+	EvidenceNodeUnreachable  = "node_unreachable"
+	EvidenceStackStable      = "stack_stable"
+	EvidenceStackChanging    = "stack_changing"
+	EvidenceStackUnavailable = "stack_unavailable"
+	EvidenceStackDivergence  = "stack_divergence"
+	EvidenceProcessMissing   = "process_missing"
+	EvidenceProcessExit      = "process_exit"
+	EvidenceWorkerRestart    = "worker_restart"
+	EvidenceSignature        = "known_signature"
+)
+
+// Evidence is one observation behind a verdict
+type Evidence struct {
+	Kind   string `json:"kind"`
+	Node   string `json:"node,omitempty"`
+	Rank   string `json:"rank,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// Verdict is the job state concluded from all nodes, with a confidence from 0 to 1
+type Verdict struct {
+	Kind       VerdictKind `json:"verdict"`
+	Confidence float64     `json:"confidence"`
+	ExitCode   int         `json:"exit_code"`
+	Evidence   []Evidence  `json:"evidence"`
+}
+
+// An observation about a rank
+type rankFinding struct {
+	node, rank, detail string
+}
+
+// A trainer exit or restart recorded by the agent
+type processFinding struct {
+	rankFinding
+	time time.Time
+	// The trainer exited with code 0
+	clean bool
+}
+
+// Stack samples of a suspicious node
+type stackFinding struct {
+	stable bool
+	err    error
+}
+
+// Everything observed in one detection round
+type observations struct {
+	nodes      int
+	totalRanks int
+	// Ranks whose log stopped, or whose step stopped advancing
+	stale     []rankFinding
+	stalled   []rankFinding
+	anomalies []rankFinding
+	// Ranks without a readable log and nodes that could not be queried
+	unreadable  []rankFinding
+	unreachable []rankFinding
+	// Stack samples by node, only suspicious nodes are sampled
+	stacks map[string]stackFinding
+	// Trainer processes of every node, nil when they were not dumped
+	trainers []rules.NodeProcesses
+	// Trainer exits and restarts recorded by the agents
+	exits    []processFinding
+	restarts []processFinding
+	// Known failure signatures matched in the logs and stacks, nil when not matched
+	signatures *rules.SignatureReport
+	// Stack normalization applied to the cross-rank consensus
+	normalizer *rules.Normalizer
+}
+
+// Combine log staleness, stack stability, cross-rank divergence and process liveness
+// into a verdict:
+//   - crashed: a reachable node has no trainer process left, or a trainer exited with an
+//     error and was not restarted
+//   - confirmed hang: ranks stopped and their stacks did not change across samples
+//   - suspected hang: ranks stopped but the stacks changed or could not be sampled
+//   - healthy: every rank is making progress
+func judge(obs *observations) *Verdict {
+	v := &Verdict{Evidence: []Evidence{}}
+	add := func(kind string, findings []rankFinding) {
+		for _, f := range findings {
+			v.Evidence = append(v.Evidence, Evidence{Kind: kind, Node: f.node, Rank: f.rank, Detail: f.detail})
+		}
+	}
+	add(EvidenceLogStale, obs.stale)
+	add(EvidenceProgressStalled, obs.stalled)
+	add(EvidenceLossAnomaly, obs.anomalies)
+	add(EvidenceLogUnreadable, obs.unreadable)
+	add(EvidenceNodeUnreachable, obs.unreachable)
+
+	nodes := make([]string, 0, len(obs.stacks))
+	for node := range obs.stacks {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	var stable, changing int
+	for _, node := range nodes {
+		f := obs.stacks[node]
+		switch {
+		case f.err != nil:
+			v.Evidence = append(v.Evidence, Evidence{Kind: EvidenceStackUnavailable, Node: node, Detail: f.err.Error()})
+		case f.stable:
+			stable++
+			v.Evidence = append(v.Evidence, Evidence{Kind: EvidenceStackStable, Node: node, Detail: "stacks unchanged across all samples"})
+		default:
+			changing++
+			v.Evidence = append(v.Evidence, Evidence{Kind: EvidenceStackChanging, Node: node, Detail: "stacks changed between samples"})
+		}
+	}
+
+	var dead int
+	var diverging bool
+	if obs.trainers != nil {
+		for _, node := range obs.trainers {
+			if !hasTrainer(node.Processes) {
+				dead++
+				v.Evidence = append(v.Evidence, Evidence{Kind: EvidenceProcessMissing, Node: node.Node, Detail: "no trainer process running"})
+			}
+		}
+		report := rules.StackConsensus(obs.trainers, obs.normalizer)
+		for _, o := range report.Outliers {
+			diverging = true
+			v.Evidence = append(v.Evidence, Evidence{
+				Kind:   EvidenceStackDivergence,
+				Node:   o.Node,
+				Rank:   o.Rank,
+				Detail: fmt.Sprintf("diverges from the majority at frame %d: %s instead of %s", o.DivergeLevel, orEnd(o.Frame), orEnd(o.MajorityFrame)),
+			})
+		}
+	}
+
+	// Exits not followed by a restart of the same rank
+	var failedExits int
+	for _, exit := range obs.exits {
+		v.Evidence = append(v.Evidence, Evidence{Kind: EvidenceProcessExit, Node: exit.node, Rank: exit.rank, Detail: exit.detail})
+		if !exit.clean && !restartedAfter(obs.restarts, exit) {
+			failedExits++
+		}
+	}
+	for _, restart := range obs.restarts {
+		v.Evidence = append(v.Evidence, Evidence{Kind: EvidenceWorkerRestart, Node: restart.node, Rank: restart.rank, Detail: restart.detail})
+	}
+
+	// Signatures explain the verdict, they do not change it
+	if obs.signatures != nil {
+		for _, rank := range obs.signatures.Ranks() {
+			for _, m := range rank.Matches {
+				v.Evidence = append(v.Evidence, Evidence{
+					Kind:   EvidenceSignature,
+					Node:   rank.Node,
+					Rank:   rank.Rank,
+					Detail: fmt.Sprintf("%s (%s, %s): %s", m.Signature.Name, m.Signature.Category, m.Signature.Severity, m.Evidence),
+				})
+			}
+		}
+	}
+
+	stuck := len(obs.stale) + len(obs.stalled)
+	stuckFraction := 0.0
+	if obs.totalRanks > 0 {
+		stuckFraction = min(1, float64(stuck)/float64(obs.totalRanks))
+	}
+	switch {
+	case dead > 0:
+		v.Kind = VerdictCrashed
+		v.Confidence = 0.6 + 0.4*float64(dead)/float64(len(obs.trainers))
+	case failedExits > 0:
+		v.Kind = VerdictCrashed
+		v.Confidence = 0.9
+	case stuck > 0 && stable > 0 && changing == 0:
+		v.Kind = VerdictConfirmedHang
+		v.Confidence = 0.7 + 0.2*stuckFraction
+		if diverging {
+			v.Confidence += 0.1
+		}
+	case stuck > 0:
+		v.Kind = VerdictSuspectedHang
+		v.Confidence = 0.3 + 0.3*stuckFraction
+		if stable > 0 {
+			v.Confidence += 0.1
+		}
+	default:
+		v.Kind = VerdictHealthy
+		// Ranks that could not be observed make the verdict less certain
+		v.Confidence = 1
+		if obs.nodes > 0 {
+			v.Confidence -= 0.5 * float64(len(obs.unreachable)) / float64(obs.nodes)
+		}
+		if obs.totalRanks > 0 {
+			v.Confidence -= 0.5 * float64(len(obs.unreadable)) / float64(obs.totalRanks)
+		}
+	}
+	v.Confidence = min(1, max(0, v.Confidence))
+	v.ExitCode = verdictExitCodes[v.Kind]
+	return v
+}
+
+func restartedAfter(restarts []processFinding, exit processFinding) bool {
+	for _, restart := range restarts {
+		if restart.node == exit.node && restart.rank == exit.rank && !restart.time.Before(exit.time) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTrainer(processes []*pb.ProcessInfo) bool {
+	for _, proc := range processes {
+		if proc.Type == pb.ProcessType_PROCESS_TRAINER {
+			return true
+		}
+	}
+	return false
+}
+
+func orEnd(frame string) string {
+	if frame == "" {
+		return "<end of stack>"
+	}
+	return frame
+}
+
+// Print the verdict and its evidence
+func printVerdict(v *Verdict) {
+	fmt.Println("==================================================")
+	fmt.Printf("Job verdict: %s (confidence %.2f, exit code %d)\n", v.Kind, v.Confidence, v.ExitCode)
+	for _, e := range v.Evidence {
+		where := e.Node
+		if e.Rank != "" {
+			where += " " + e.Rank
+		}
+		fmt.Printf("  [%s] %s: %s\n", e.Kind, where, e.Detail)
+	}
+	fmt.Println("==================================================")
+}
+
+// Write the verdict as JSON, for CI and schedulers
+func writeVerdictFile(v *Verdict, fileName string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
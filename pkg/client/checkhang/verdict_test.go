@@ -0,0 +1,161 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package checkhang
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"deeptrace/pkg/rules"
+	pb "deeptrace/v1"
+)
+
+func trainerStack(rank string, function string) *pb.ProcessInfo {
+	return &pb.ProcessInfo{Pid: 100, Type: pb.ProcessType_PROCESS_TRAINER, Rank: rank, Status: pb.StackStatus_STACK_OK, Threads: []*pb.ThreadStack{
+		{ThreadId: 100, Frames: []*pb.StackFrame{{Language: pb.FrameLanguage_FRAME_PYTHON, File: "train.py", Function: function, Module: "train"}}},
+	}}
+}
+
+func TestJudge(t *testing.T) {
+	signatures, err := rules.LoadSignatures(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeoutReport := rules.NewSignatureReport()
+	timeoutReport.Add("node1", "RANK0", signatures.MatchLog([]string{"Watchdog caught collective operation timeout"}))
+	exitTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	stale := []rankFinding{{"node1", "RANK0", "log not updated for 700s (threshold 600s)"}, {"node2", "RANK1", "log not updated for 700s (threshold 600s)"}}
+	alive := []rules.NodeProcesses{
+		{Node: "node1", Processes: []*pb.ProcessInfo{trainerStack("RANK0", "all_reduce")}},
+		{Node: "node2", Processes: []*pb.ProcessInfo{trainerStack("RANK1", "all_reduce")}},
+	}
+	tests := []struct {
+		name           string
+		obs            *observations
+		want           VerdictKind
+		wantExitCode   int
+		wantConfidence float64
+		wantEvidence   string
+	}{
+		{
+			name:           "healthy",
+			obs:            &observations{nodes: 2, totalRanks: 2},
+			want:           VerdictHealthy,
+			wantConfidence: 1,
+		},
+		{
+			name:           "healthy with an unreachable node",
+			obs:            &observations{nodes: 2, totalRanks: 1, unreachable: []rankFinding{{node: "node2", detail: "connection refused"}}},
+			want:           VerdictHealthy,
+			wantConfidence: 0.75,
+			wantEvidence:   EvidenceNodeUnreachable,
+		},
+		{
+			name: "stacks stable across samples",
+			obs: &observations{nodes: 2, totalRanks: 2, stale: stale, trainers: alive,
+				stacks: map[string]stackFinding{"node1": {stable: true}, "node2": {stable: true}}},
+			want:           VerdictConfirmedHang,
+			wantExitCode:   3,
+			wantConfidence: 0.9,
+			wantEvidence:   EvidenceStackStable,
+		},
+		{
+			name: "stacks still changing",
+			obs: &observations{nodes: 2, totalRanks: 2, stale: stale[:1], trainers: alive,
+				stacks: map[string]stackFinding{"node1": {stable: false}}},
+			want:           VerdictSuspectedHang,
+			wantExitCode:   2,
+			wantConfidence: 0.45,
+			wantEvidence:   EvidenceStackChanging,
+		},
+		{
+			name: "stacks unavailable",
+			obs: &observations{nodes: 2, totalRanks: 2, stalled: stale[:1], trainers: alive,
+				stacks: map[string]stackFinding{"node1": {err: errors.New("deadline exceeded")}}},
+			want:           VerdictSuspectedHang,
+			wantExitCode:   2,
+			wantConfidence: 0.45,
+			wantEvidence:   EvidenceStackUnavailable,
+		},
+		{
+			name: "diverging rank",
+			obs: &observations{nodes: 3, totalRanks: 3, stale: stale,
+				stacks:   map[string]stackFinding{"node1": {stable: true}},
+				trainers: append([]rules.NodeProcesses{{Node: "node3", Processes: []*pb.ProcessInfo{trainerStack("RANK2", "__next__")}}}, alive...)},
+			want:           VerdictConfirmedHang,
+			wantExitCode:   3,
+			wantConfidence: 0.7 + 0.2*2/3 + 0.1,
+			wantEvidence:   EvidenceStackDivergence,
+		},
+		{
+			name: "trainer gone",
+			obs: &observations{nodes: 2, totalRanks: 2, stale: stale,
+				stacks:   map[string]stackFinding{"node1": {stable: true}},
+				trainers: []rules.NodeProcesses{alive[0], {Node: "node2"}}},
+			want:           VerdictCrashed,
+			wantExitCode:   4,
+			wantConfidence: 0.8,
+			wantEvidence:   EvidenceProcessMissing,
+		},
+		{
+			name: "known signature",
+			obs: &observations{nodes: 2, totalRanks: 2, stale: stale, trainers: alive, signatures: timeoutReport,
+				stacks: map[string]stackFinding{"node1": {stable: true}, "node2": {stable: true}}},
+			want:           VerdictConfirmedHang,
+			wantExitCode:   3,
+			wantConfidence: 0.9,
+			wantEvidence:   EvidenceSignature,
+		},
+		{
+			name: "trainer exited with an error",
+			obs: &observations{nodes: 2, totalRanks: 2, stale: stale[1:],
+				exits: []processFinding{{rankFinding: rankFinding{"node1", "RANK0", "Trainer RANK0 (PID 100) exited with code 1"}, time: exitTime}}},
+			want:           VerdictCrashed,
+			wantExitCode:   4,
+			wantConfidence: 0.9,
+			wantEvidence:   EvidenceProcessExit,
+		},
+		{
+			name: "trainer restarted after an error",
+			obs: &observations{nodes: 2, totalRanks: 2,
+				exits:    []processFinding{{rankFinding: rankFinding{"node1", "RANK0", "Trainer RANK0 (PID 100) exited with code 1"}, time: exitTime}},
+				restarts: []processFinding{{rankFinding: rankFinding{"node1", "RANK0", "Trainer RANK0 restarted: PID 100 replaced by PID 200"}, time: exitTime.Add(time.Minute)}}},
+			want:           VerdictHealthy,
+			wantConfidence: 1,
+			wantEvidence:   EvidenceWorkerRestart,
+		},
+		{
+			name: "trainer finished",
+			obs: &observations{nodes: 1, totalRanks: 1,
+				exits: []processFinding{{rankFinding: rankFinding{"node1", "RANK0", "Trainer RANK0 (PID 100) exited with code 0"}, time: exitTime, clean: true}}},
+			want:           VerdictHealthy,
+			wantConfidence: 1,
+			wantEvidence:   EvidenceProcessExit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := judge(tt.obs)
+			if got.Kind != tt.want {
+				t.Errorf("judge() = %s, want %s", got.Kind, tt.want)
+			}
+			if got.ExitCode != tt.wantExitCode {
+				t.Errorf("judge() exit code = %d, want %d", got.ExitCode, tt.wantExitCode)
+			}
+			if diff := got.Confidence - tt.wantConfidence; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("judge() confidence = %v, want %v", got.Confidence, tt.wantConfidence)
+			}
+			if tt.wantEvidence == "" {
+				return
+			}
+			found := false
+			for _, e := range got.Evidence {
+				found = found || e.Kind == tt.wantEvidence
+			}
+			if !found {
+				t.Errorf("judge() evidence %v has no %s", got.Evidence, tt.wantEvidence)
+			}
+		})
+	}
+}
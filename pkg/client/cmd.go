@@ -7,6 +7,8 @@ import (
 
 	"deeptrace/pkg/client/alerts"
 	"deeptrace/pkg/client/checkhang"
+	"deeptrace/pkg/client/consensus"
+	"deeptrace/pkg/client/diagnose"
 	"deeptrace/pkg/client/logs"
 	"deeptrace/pkg/client/restart"
 	"deeptrace/pkg/client/stacks"
@@ -66,9 +68,11 @@ Example:
 		logs.NewCmdLogs(),
 		stacks.NewCmdStacks(),
 		checkhang.NewCmdCheckHang(),
+		consensus.NewCmdConsensus(),
 		restart.NewCmdRestart(),
 		version.NewCmdVersion(),
 		alerts.NewCmdAlerts(),
+		diagnose.NewCmdDiagnose(),
 	)
 
 	return cmds
@@ -0,0 +1,210 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"deeptrace/pkg/client/utils"
+	"deeptrace/pkg/rules"
+	pb "deeptrace/v1"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+// Ranks listed per group before the rest is summarized
+const maxListedRanks = 16
+
+// NewCmdConsensus creates a cobra command comparing trainer stacks across all ranks
+func NewCmdConsensus() *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "consensus",
+		Short: "Find ranks whose stack differs from the majority",
+		Long: `Dump the trainer stacks of all ranks of the job and group them by call path.
+In a collective hang most ranks wait in the same collective; the ranks outside the
+majority group show where the job is stuck. Line numbers are ignored when grouping,
+threads and frames are ignored and collapsed as set by stack-normalization in the
+configuration file.
+Usage:
+  deeptracex consensus --job-id <job name> -w clusterx [--backend <backend>] [--timeout <seconds>] [--port <service port>]
+
+Example:
+  deeptracex consensus --job-id my_job -w clusterx --port 50051`,
+		Run: func(cmd *cobra.Command, args []string) {
+			jobName, _ := cmd.Flags().GetString("job-id")
+			if jobName == "" {
+				jobName = viper.GetString("job-id")
+			}
+			if jobName != "" {
+				fmt.Printf("Using job name: %s\n", jobName)
+			} else {
+				fmt.Println("Note: Job name not specified")
+			}
+
+			// Get worker source
+			workSource, _ := cmd.Flags().GetString("worker-source")
+			if workSource == "" {
+				workSource = viper.GetString("worker-source")
+			}
+			if workSource == "" {
+				fmt.Println("Error: worker source must be specified")
+				os.Exit(1)
+			}
+			// Read address list
+			addressList, err := utils.GetWorkerList(workSource, jobName)
+			if err != nil {
+				fmt.Printf("Failed to read address list file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Obtained addresses: %v\n", addressList)
+
+			port, _ := cmd.Flags().GetString("port")
+			if port == "" {
+				port = viper.GetString("port")
+				if port == "" {
+					fmt.Println("Port number not specified, using default value 50051")
+					port = "50051"
+				}
+			}
+			backend, _ := cmd.Flags().GetString("backend")
+			if backend == "" {
+				backend = viper.GetString("stack-backend")
+			}
+			timeout, _ := cmd.Flags().GetInt32("timeout")
+
+			// Threads and frames to ignore, shared with check-hang
+			var normalization rules.NormalizeConfig
+			if err := viper.UnmarshalKey("stack-normalization", &normalization); err != nil {
+				fmt.Printf("Invalid stack-normalization in configuration file: %v\n", err)
+				os.Exit(1)
+			}
+			normalizer, err := rules.NewNormalizer(normalization)
+			if err != nil {
+				fmt.Printf("Invalid stack normalization: %v\n", err)
+				os.Exit(1)
+			}
+
+			nodes := FetchTrainerStacks(addressList, port, backend, timeout)
+			PrintReport(rules.StackConsensus(nodes, normalizer))
+		},
+	}
+
+	cmd.Flags().Int32("timeout", 20, "Seconds allowed to dump the stacks of one process")
+	cmd.Flags().String("backend", "", "Stack backend (auto, pystack, py-spy, gdb, proc), if not specified, the agent's default is used")
+
+	return cmd
+}
+
+// FetchTrainerStacks dumps the trainer stacks on every node, nodes that fail are reported and left out.
+// A node without trainers left is kept with no processes.
+func FetchTrainerStacks(addressList []string, port, backend string, timeout int32) []rules.NodeProcesses {
+	type Result struct {
+		address string
+		stacks  *pb.ProcessStacksResponse
+		err     error
+	}
+
+	results := make(chan Result, len(addressList))
+
+	// Start goroutines to process each node in parallel
+	for _, addr := range addressList {
+		go func(address string) {
+			conn, err := grpc.Dial(
+				address+":"+port,
+				grpc.WithInsecure(),
+				grpc.WithTimeout(5*time.Second),
+			)
+			if err != nil {
+				results <- Result{address, nil, err}
+				return
+			}
+			defer conn.Close()
+
+			client := pb.NewDeepTraceServiceClient(conn)
+			// Processes are dumped in parallel, leave the agent time beyond the per-process limit
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second+10*time.Second)
+			defer cancel()
+
+			resp, err := client.GetProcessStacks(ctx, &pb.GetProcessStacksRequest{
+				ProcessType:    pb.ProcessType_PROCESS_TRAINER,
+				Backend:        backend,
+				TimeoutSeconds: timeout,
+			})
+			results <- Result{address, resp, err}
+		}(addr)
+	}
+
+	var nodes []rules.NodeProcesses
+	for i := 0; i < len(addressList); i++ {
+		res := <-results
+		if res.err != nil {
+			fmt.Printf("Failed to get stack information from node %s: %v\n", res.address, res.err)
+			continue
+		}
+		nodes = append(nodes, rules.NodeProcesses{Node: res.address, Processes: res.stacks.Processes})
+	}
+	close(results)
+	return nodes
+}
+
+// PrintReport prints the stack groups, the majority first, and the outlier ranks
+func PrintReport(report *rules.ConsensusReport) {
+	fmt.Printf("Compared %d trainer ranks in %d stack groups\n", report.TotalRanks, len(report.Groups))
+	if len(report.Groups) == 0 {
+		fmt.Println("No trainer stacks to compare")
+	} else if report.Majority < 0 {
+		fmt.Println("No majority: the largest stack groups have the same size")
+	}
+
+	for i, group := range report.Groups {
+		label := fmt.Sprintf("Group %d", i+1)
+		if i == report.Majority {
+			label += " (majority)"
+		}
+		fmt.Printf("--------------------------------------------------\n%s: %d ranks, innermost frame %s\n", label, len(group.Ranks), group.Top())
+		fmt.Printf("  Ranks: %s\n", formatRanks(group.Ranks))
+		fmt.Println("  Call path:")
+		for _, frame := range group.CallPath {
+			fmt.Printf("    %s\n", frame)
+		}
+	}
+
+	if len(report.Outliers) > 0 {
+		fmt.Println("--------------------------------------------------")
+		fmt.Println("Outlier ranks:")
+		for _, o := range report.Outliers {
+			fmt.Printf("  %s on %s (PID %d), group %d: diverges at frame %d, %s instead of %s\n",
+				o.Rank, o.Node, o.Pid, o.Group+1, o.DivergeLevel, orNone(o.Frame), orNone(o.MajorityFrame))
+		}
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Println("--------------------------------------------------")
+		fmt.Printf("Ranks without stacks: %s\n", formatRanks(report.Skipped))
+	}
+}
+
+func formatRanks(ranks []rules.RankRef) string {
+	names := make([]string, 0, maxListedRanks+1)
+	for i, ref := range ranks {
+		if i == maxListedRanks {
+			names = append(names, fmt.Sprintf("... %d more", len(ranks)-maxListedRanks))
+			break
+		}
+		names = append(names, fmt.Sprintf("%s@%s", ref.Rank, ref.Node))
+	}
+	return strings.Join(names, ", ")
+}
+
+func orNone(frame string) string {
+	if frame == "" {
+		return "<end of stack>"
+	}
+	return frame
+}
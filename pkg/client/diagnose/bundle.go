@@ -0,0 +1,153 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package diagnose
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	Job           string            `json:"job"`
+	CreatedAt     time.Time         `json:"created_at"`
+	ClientVersion string            `json:"client_version"`
+	Nodes         []string          `json:"nodes"`
+	WorkDir       string            `json:"work_dir,omitempty"`
+	Verdict       string            `json:"verdict,omitempty"`
+	Files         []ManifestFile    `json:"files"`
+	Errors        []CollectionError `json:"errors,omitempty"`
+}
+
+// ManifestFile is a file of the bundle, paths are relative to the bundle directory
+type ManifestFile struct {
+	Path    string `json:"path"`
+	Node    string `json:"node,omitempty"`
+	Section string `json:"section"`
+	Size    int64  `json:"size"`
+}
+
+// Write a response as indented protojson, the format "deeptracex stacks" saves
+func writeProtoJSON(fileName string, msg proto.Message) error {
+	data, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeFile(fileName, data)
+}
+
+func writeJSON(fileName string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(fileName, data)
+}
+
+func writeFile(fileName string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
+
+// List the files under the bundle directory, nodes/<node>/<section>.json belong to a node,
+// other files to the section of their top-level name
+func listFiles(dir string) ([]ManifestFile, error) {
+	var files []ManifestFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		file := ManifestFile{Path: rel, Size: info.Size()}
+		parts := strings.Split(rel, "/")
+		if len(parts) == 3 && parts[0] == "nodes" {
+			file.Node = parts[1]
+			file.Section = strings.TrimSuffix(parts[2], filepath.Ext(parts[2]))
+		} else {
+			file.Section = strings.TrimSuffix(parts[0], filepath.Ext(parts[0]))
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+// Write the files under dir to a tar.gz, under a top-level directory named prefix
+func writeTarGz(dir, fileName, prefix string) (err error) {
+	out, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(fileName)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,96 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package diagnose
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeBundleDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"summary.txt":                "summary",
+		"signatures.json":            "[]",
+		"nodes/node1/version.json":   "{}",
+		"nodes/node1/stacks.json":    "{}",
+		"check_hang/verdict.json":    "{}",
+		"check_hang/checkLogs/a.log": "log",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, name), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestListFiles(t *testing.T) {
+	files, err := listFiles(writeBundleDir(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][2]string)
+	for _, f := range files {
+		got[f.Path] = [2]string{f.Node, f.Section}
+	}
+	want := map[string][2]string{
+		"summary.txt":                {"", "summary"},
+		"signatures.json":            {"", "signatures"},
+		"nodes/node1/version.json":   {"node1", "version"},
+		"nodes/node1/stacks.json":    {"node1", "stacks"},
+		"check_hang/verdict.json":    {"", "check_hang"},
+		"check_hang/checkLogs/a.log": {"", "check_hang"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteTarGz(t *testing.T) {
+	dir := writeBundleDir(t)
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := writeTarGz(dir, bundle, "deeptrace_job"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	got := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[header.Name] = string(data)
+	}
+	if got["deeptrace_job/nodes/node1/version.json"] != "{}\n" || got["deeptrace_job/summary.txt"] != "summary\n" {
+		t.Errorf("unexpected bundle content %v", got)
+	}
+	if len(got) != 6 {
+		t.Errorf("bundle has %d files, want 6", len(got))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"deeptrace/pkg/client/checkhang"
+	"deeptrace/pkg/client/utils"
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Sections of the bundle
+const (
+	sectionVersion    = "version"
+	sectionLogs       = "logs"
+	sectionLogErrors  = "log_errors"
+	sectionStacks     = "stacks"
+	sectionAlerts     = "alerts"
+	sectionCheckHang  = "check_hang"
+	sectionSignatures = "signatures"
+)
+
+// Options of a collection
+type collectOptions struct {
+	workDir  string
+	maxLines int32
+	backend  string
+	// Seconds allowed to dump the stacks of one process
+	timeout     int32
+	alertsSince time.Time
+	// Options of check-hang, its signatures are matched in the logs and stacks too
+	hang checkhang.Options
+}
+
+// Everything collected from one node, nil sections failed
+type nodeData struct {
+	node      string
+	version   *pb.VersionResponse
+	logs      *pb.LogResponse
+	logErrors *pb.LogErrorsResponse
+	stacks    *pb.ProcessStacksResponse
+	alerts    *pb.GetAlertsResponse
+}
+
+// A section that could not be collected
+type CollectionError struct {
+	Node    string `json:"node"`
+	Section string `json:"section"`
+	Error   string `json:"error"`
+}
+
+// Collect the sections of every node but the stacks, nodes and sections in parallel
+func collectNodes(addressList []string, port string, opts collectOptions) ([]*nodeData, []CollectionError) {
+	nodes := make([]*nodeData, len(addressList))
+	for i, node := range addressList {
+		nodes[i] = &nodeData{node: node}
+	}
+	errs := forEachNode(nodes, port, func(conn *grpc.ClientConn, data *nodeData, fail func(node, section string, err error)) {
+		collectNode(conn, data, opts, fail)
+	})
+	return nodes, errs
+}
+
+// Dump the stacks of every node in parallel. check-hang attaches to the same processes, so
+// this runs once it is done.
+func collectStacks(nodes []*nodeData, port string, opts collectOptions) []CollectionError {
+	return forEachNode(nodes, port, func(conn *grpc.ClientConn, data *nodeData, fail func(node, section string, err error)) {
+		client := pb.NewDeepTraceServiceClient(conn)
+		// Processes are dumped in parallel, leave the agent time beyond the per-process limit
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.timeout)*time.Second+10*time.Second)
+		defer cancel()
+		var err error
+		data.stacks, err = client.GetProcessStacks(ctx, &pb.GetProcessStacksRequest{
+			ProcessType:    pb.ProcessType_PROCESS_UNSPECIFIED,
+			Backend:        opts.backend,
+			TimeoutSeconds: opts.timeout,
+		})
+		if err != nil {
+			fail(data.node, sectionStacks, err)
+		}
+	})
+}
+
+// Run collect on a connection to every node in parallel, returning the sections that failed
+func forEachNode(nodes []*nodeData, port string, collect func(conn *grpc.ClientConn, data *nodeData, fail func(node, section string, err error))) []CollectionError {
+	var mu sync.Mutex
+	var errs []CollectionError
+	fail := func(node, section string, err error) {
+		fmt.Printf("Failed to collect %s from node %s: %v\n", section, node, err)
+		mu.Lock()
+		errs = append(errs, CollectionError{Node: node, Section: section, Error: err.Error()})
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(data *nodeData) {
+			defer wg.Done()
+			conn, err := grpc.Dial(
+				data.node+":"+port,
+				grpc.WithInsecure(),
+				grpc.WithTimeout(5*time.Second),
+			)
+			if err != nil {
+				fail(data.node, "connection", err)
+				return
+			}
+			defer conn.Close()
+			collect(conn, data, fail)
+		}(node)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Run the requests of every section of a node in parallel on one connection
+func collectNode(conn *grpc.ClientConn, data *nodeData, opts collectOptions, fail func(node, section string, err error)) {
+	client := pb.NewDeepTraceServiceClient(conn)
+	alertClient := pb.NewAlertServiceClient(conn)
+	call := func(timeout time.Duration, fn func(ctx context.Context) error, section string) func() {
+		return func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := fn(ctx); err != nil {
+				fail(data.node, section, err)
+			}
+		}
+	}
+
+	sections := []func(){
+		call(10*time.Second, func(ctx context.Context) (err error) {
+			data.version, err = client.GetVersion(ctx, &emptypb.Empty{})
+			return err
+		}, sectionVersion),
+		call(10*time.Second, func(ctx context.Context) (err error) {
+			data.logs, err = client.GetRecentLogs(ctx, &pb.GetRecentLogsRequest{MaxLines: opts.maxLines, WorkDir: opts.workDir})
+			if err == nil {
+				for _, rankLog := range data.logs.Ranklogs {
+					for _, entry := range rankLog.Entries {
+						entry.Message = utils.CleanUTF8(entry.Message)
+					}
+				}
+			}
+			return err
+		}, sectionLogs),
+		call(10*time.Second, func(ctx context.Context) (err error) {
+			data.logErrors, err = client.GetLogErrors(ctx, &pb.GetLogErrorsRequest{WorkDir: opts.workDir})
+			if err == nil {
+				for _, rankLog := range data.logErrors.Ranklogs {
+					for _, e := range rankLog.Errors {
+						e.Summary = utils.CleanUTF8(e.Summary)
+						for i, line := range e.Lines {
+							e.Lines[i] = utils.CleanUTF8(line)
+						}
+					}
+				}
+			}
+			return err
+		}, sectionLogErrors),
+		// Peeked, so "deeptracex alerts" still reports the events
+		call(10*time.Second, func(ctx context.Context) (err error) {
+			data.alerts, err = alertClient.GetAlerts(ctx, &pb.GetAlertsRequest{
+				StartTime: timestamppb.New(opts.alertsSince),
+				Peek:      true,
+			})
+			return err
+		}, sectionAlerts),
+	}
+
+	var wg sync.WaitGroup
+	for _, section := range sections {
+		wg.Add(1)
+		go func(section func()) {
+			defer wg.Done()
+			section()
+		}(section)
+	}
+	wg.Wait()
+}
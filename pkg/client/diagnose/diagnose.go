@@ -0,0 +1,287 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package diagnose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"deeptrace/pkg/client/checkhang"
+	"deeptrace/pkg/client/utils"
+	"deeptrace/pkg/rules"
+	v "deeptrace/pkg/version"
+	pb "deeptrace/v1"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
+)
+
+// Log lines collected per rank when --max-line is not given
+const defaultMaxLines = 200
+
+// NewCmdDiagnose creates a cobra command collecting the diagnostics of all nodes into one
+// bundle
+func NewCmdDiagnose() *cobra.Command {
+	var workDir string
+	var maxLines int32
+
+	cmd := &cobra.Command{
+		Use:   "diagnose",
+		Short: "Collect the diagnostics of all nodes into one bundle",
+		Long: `Collect, in parallel from every node, the agent version, the recent rank logs, the
+tracebacks and fatal errors of the rank logs and the recent alerts while check-hang
+concludes the state of the job, then the stacks of all processes once check-hang is done
+sampling them. The logs and stacks are matched against known failure signatures, which
+are extended or overridden by name with "signature-files" in the configuration file or
+--signature-file.
+
+Everything is written to a single tar.gz, deeptrace_<job>_<time>.tar.gz in --output-dir:
+  manifest.json                  job, nodes, files and the sections that could not be collected
+  summary.txt                    verdict, versions, fatal errors, signatures, stacks and alerts
+  signatures.json                known failure signatures matched per rank
+  nodes/<node>/<section>.json    version, logs, log_errors, stacks and alerts of each node
+  check_hang/                    verdict.json and the checkLogs and checkStacks of check-hang
+Alerts are read without marking them processed, "deeptracex alerts" still reports them.
+Usage:
+  deeptracex diagnose --job-id <job name> -w clusterx [--work-dir <working directory>] [--max-line <lines per rank>] [--alerts-since <time>] [--output-dir <directory>] [--signature-file <file>] [--backend <backend>] [--timeout <seconds>] [--port <service port>]
+
+Example:
+  deeptracex diagnose --job-id my_job -w clusterx --alerts-since 2h --output-dir incidents`,
+		Run: func(cmd *cobra.Command, args []string) {
+			jobName, _ := cmd.Flags().GetString("job-id")
+			if jobName == "" {
+				jobName = viper.GetString("job-id")
+			}
+			if jobName != "" {
+				fmt.Printf("Using job name: %s\n", jobName)
+			} else {
+				fmt.Println("Note: Job name not specified")
+			}
+
+			// Get worker source
+			workSource, _ := cmd.Flags().GetString("worker-source")
+			if workSource == "" {
+				workSource = viper.GetString("worker-source")
+			}
+			if workSource == "" {
+				fmt.Println("Error: worker source must be specified")
+				os.Exit(1)
+			}
+			// Read address list
+			addressList, err := utils.GetWorkerList(workSource, jobName)
+			if err != nil {
+				fmt.Printf("Failed to read address list file: %v\n", err)
+				os.Exit(1)
+			}
+			if len(addressList) == 0 {
+				fmt.Println("Error: no nodes found")
+				os.Exit(1)
+			}
+			fmt.Printf("Obtained addresses: %v\n", addressList)
+
+			port, _ := cmd.Flags().GetString("port")
+			if port == "" {
+				port = viper.GetString("port")
+				if port == "" {
+					fmt.Println("Port number not specified, using default value 50051")
+					port = "50051"
+				}
+			}
+			if workDir == "" {
+				workDir = viper.GetString("work-dir")
+			}
+			if maxLines == 0 {
+				maxLines = defaultMaxLines
+			}
+			opts := collectOptions{workDir: workDir, maxLines: maxLines}
+			opts.backend, _ = cmd.Flags().GetString("backend")
+			if opts.backend == "" {
+				opts.backend = viper.GetString("stack-backend")
+			}
+			opts.timeout, _ = cmd.Flags().GetInt32("timeout")
+			alertsSince, _ := cmd.Flags().GetString("alerts-since")
+			if opts.alertsSince, err = utils.ParseTimeFlag(alertsSince, time.Now()); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			outputDir, _ := cmd.Flags().GetString("output-dir")
+
+			// check-hang options resolved like check-hang does, its log lines and threshold
+			// come from the configuration file since --max-line is the bundle's
+			if opts.hang, err = checkhang.ResolveOptions(cmd); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			opts.hang.WorkDir = workDir
+			opts.hang.MaxLines = int32(viper.GetInt("max-line"))
+			opts.hang.Threshold = int32(viper.GetInt("threshold"))
+			// Same defaults as check-hang
+			if opts.hang.MaxLines == 0 {
+				opts.hang.MaxLines = 30
+			}
+			if opts.hang.Threshold == 0 {
+				opts.hang.Threshold = 120
+			}
+
+			bundle, summary, err := createBundle(jobName, addressList, port, outputDir, opts)
+			if err != nil {
+				fmt.Printf("Failed to create diagnosis bundle: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(summary)
+			fmt.Printf("\nDiagnosis bundle saved to %s\n", bundle)
+		},
+	}
+
+	cmd.Flags().StringVar(&workDir, "work-dir", "", "Specify working directory")
+	cmd.Flags().Int32Var(&maxLines, "max-line", 0, fmt.Sprintf("Log lines collected per rank, %d if not specified", defaultMaxLines))
+	cmd.Flags().String("alerts-since", "24h", "Collect alerts since this time (RFC3339, YYYY-MM-DDTHH:MM:SS or a duration such as 2h)")
+	cmd.Flags().String("output-dir", ".", "Directory the bundle is written to")
+	cmd.Flags().StringSlice("signature-file", nil, "YAML file of failure signatures added to the built-in ones, can be repeated")
+	cmd.Flags().String("backend", "", "Stack backend (auto, pystack, py-spy, gdb, proc), if not specified, the agent's default is used")
+	cmd.Flags().Int32("timeout", 20, "Seconds allowed to dump the stacks of one process")
+
+	return cmd
+}
+
+// Build the bundle of the job, it returns the path of the tar.gz and the summary report
+func createBundle(job string, addressList []string, port, outputDir string, opts collectOptions) (string, string, error) {
+	createdAt := time.Now()
+	name := "deeptrace_" + createdAt.Format("2006-01-02_15-04-05")
+	if job != "" {
+		name = fmt.Sprintf("deeptrace_%s_%s", job, createdAt.Format("2006-01-02_15-04-05"))
+	}
+	staging, err := os.MkdirTemp("", name)
+	if err != nil {
+		return "", "", err
+	}
+	defer os.RemoveAll(staging)
+
+	hang := opts.hang
+	hang.OutputDir = filepath.Join(staging, sectionCheckHang)
+	checker, err := checkhang.NewChecker(hang)
+	if err != nil {
+		return "", "", err
+	}
+
+	// check-hang samples the stacks of suspicious nodes while the other sections are collected,
+	// the stacks follow so that two dumps don't attach to the same process
+	var nodes []*nodeData
+	var errs []CollectionError
+	var verdict *checkhang.Verdict
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		nodes, errs = collectNodes(addressList, port, opts)
+	}()
+	go func() {
+		defer wg.Done()
+		verdict = checker.CheckLogs(job, addressList, port)
+	}()
+	wg.Wait()
+	errs = append(errs, collectStacks(nodes, port, opts)...)
+
+	for _, node := range nodes {
+		dir := filepath.Join(staging, "nodes", node.node)
+		sections := []struct {
+			name string
+			msg  proto.Message
+		}{
+			{sectionVersion, node.version},
+			{sectionLogs, node.logs},
+			{sectionLogErrors, node.logErrors},
+			{sectionStacks, node.stacks},
+			{sectionAlerts, node.alerts},
+		}
+		for _, s := range sections {
+			// Typed nil pointers of the sections that failed
+			if !s.msg.ProtoReflect().IsValid() {
+				continue
+			}
+			if err := writeProtoJSON(filepath.Join(dir, s.name+".json"), s.msg); err != nil {
+				return "", "", err
+			}
+		}
+	}
+	if err := writeJSON(filepath.Join(staging, sectionCheckHang, "verdict.json"), verdict); err != nil {
+		return "", "", err
+	}
+	signatures := matchSignatures(opts.hang.Signatures, nodes)
+	if err := writeJSON(filepath.Join(staging, sectionSignatures+".json"), signatures); err != nil {
+		return "", "", err
+	}
+
+	manifest := &Manifest{
+		Job:           job,
+		CreatedAt:     createdAt,
+		ClientVersion: v.GetClientVersionInfo(),
+		Nodes:         addressList,
+		WorkDir:       opts.workDir,
+		Verdict:       string(verdict.Kind),
+		Errors:        errs,
+	}
+	summary := renderSummary(manifest, verdict, nodes, signatures)
+	if err := writeFile(filepath.Join(staging, "summary.txt"), []byte(summary)); err != nil {
+		return "", "", err
+	}
+	if manifest.Files, err = listFiles(staging); err != nil {
+		return "", "", err
+	}
+	if err := writeJSON(filepath.Join(staging, "manifest.json"), manifest); err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", "", err
+	}
+	bundle := filepath.Join(outputDir, name+".tar.gz")
+	if err := writeTarGz(staging, bundle, name); err != nil {
+		return "", "", err
+	}
+	return bundle, summary, nil
+}
+
+// Match the recent log lines, the lines of the log errors and the trainer stacks of every
+// rank against the signature library
+func matchSignatures(library *rules.SignatureLibrary, nodes []*nodeData) []rules.RankSignatures {
+	report := rules.NewSignatureReport()
+	var stacks []rules.NodeProcesses
+	for _, node := range nodes {
+		lines := make(map[string][]string)
+		if node.logs != nil {
+			for _, rankLog := range node.logs.Ranklogs {
+				for _, entry := range rankLog.Entries {
+					lines[rankLog.Rank] = append(lines[rankLog.Rank], entry.Message)
+				}
+			}
+		}
+		// Tracebacks may be older than the recent lines, their exception line names the failure
+		if node.logErrors != nil {
+			for _, rankLog := range node.logErrors.Ranklogs {
+				for _, e := range rankLog.Errors {
+					lines[rankLog.Rank] = append(lines[rankLog.Rank], e.Lines...)
+				}
+			}
+		}
+		for rank, rankLines := range lines {
+			report.Add(node.node, rank, library.MatchLog(rankLines))
+		}
+
+		if node.stacks != nil {
+			trainers := rules.NodeProcesses{Node: node.node}
+			for _, proc := range node.stacks.Processes {
+				if proc.Type == pb.ProcessType_PROCESS_TRAINER {
+					trainers.Processes = append(trainers.Processes, proc)
+				}
+			}
+			stacks = append(stacks, trainers)
+		}
+	}
+	report.AddStacks(library, stacks)
+	return report.Ranks()
+}
@@ -0,0 +1,142 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package diagnose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"deeptrace/pkg/client/checkhang"
+	"deeptrace/pkg/client/logs"
+	"deeptrace/pkg/rules"
+	pb "deeptrace/v1"
+)
+
+// Render the summary report of a bundle, the first file to read when opening it
+func renderSummary(m *Manifest, verdict *checkhang.Verdict, nodes []*nodeData, signatures []rules.RankSignatures) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DeepTrace diagnosis of job %s\n", m.Job)
+	fmt.Fprintf(&b, "Created at: %s\n", m.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Client: %s\n", m.ClientVersion)
+	fmt.Fprintf(&b, "Nodes (%d): %s\n", len(m.Nodes), strings.Join(m.Nodes, ", "))
+
+	section(&b, "Verdict")
+	fmt.Fprintf(&b, "%s (confidence %.2f, exit code %d)\n", verdict.Kind, verdict.Confidence, verdict.ExitCode)
+	for _, e := range verdict.Evidence {
+		where := e.Node
+		if e.Rank != "" {
+			where += " " + e.Rank
+		}
+		fmt.Fprintf(&b, "  [%s] %s: %s\n", e.Kind, where, e.Detail)
+	}
+
+	section(&b, "Agent versions")
+	for _, node := range nodes {
+		if node.version == nil {
+			fmt.Fprintf(&b, "%s: unknown\n", node.node)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s (commit %s, built %s)\n", node.node, node.version.Version, node.version.Commit, node.version.BuildTime)
+	}
+
+	section(&b, "Rank logs")
+	for _, node := range nodes {
+		if node.logs == nil {
+			fmt.Fprintf(&b, "%s: not collected\n", node.node)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d ranks\n", node.node, len(node.logs.Ranklogs))
+		for _, rankLog := range node.logs.Ranklogs {
+			if rankLog.Status != pb.RankLogStatus_RANK_LOG_OK {
+				fmt.Fprintf(&b, "  %s: %s (%s)\n", rankLog.Rank, rankLog.Status, rankLog.StatusMessage)
+			} else if rankLog.SuspendSeconds > 0 {
+				fmt.Fprintf(&b, "  %s: last line %ds ago\n", rankLog.Rank, rankLog.SuspendSeconds)
+			}
+		}
+	}
+
+	section(&b, "Fatal log errors")
+	nodeErrors := make(map[string][]*pb.RankLogErrors)
+	for _, node := range nodes {
+		if node.logErrors != nil {
+			nodeErrors[node.node] = node.logErrors.Ranklogs
+		}
+	}
+	groups := logs.GroupLogErrors(m.Nodes, nodeErrors)
+	if len(groups) == 0 {
+		b.WriteString("None found\n")
+	}
+	for _, group := range groups {
+		fmt.Fprintf(&b, "[%s] %s\n", group.Kind, group.Summary)
+		fmt.Fprintf(&b, "  Ranks (%d): %v, occurrences: %d\n", len(group.Ranks), group.Ranks, group.Count)
+		if location := group.Context["location"]; location != "" {
+			fmt.Fprintf(&b, "  Raised at: %s\n", location)
+		}
+	}
+
+	section(&b, "Known failure signatures")
+	if len(signatures) == 0 {
+		b.WriteString("No known failure signature matched\n")
+	}
+	for _, rank := range signatures {
+		b.WriteString(rank.Text())
+	}
+
+	section(&b, "Stacks")
+	for _, node := range nodes {
+		if node.stacks == nil {
+			fmt.Fprintf(&b, "%s: not collected\n", node.node)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d of %d processes sampled\n", node.node, node.stacks.SampledProcesses, node.stacks.TotalProcesses)
+		for _, proc := range node.stacks.Processes {
+			if proc.Status != pb.StackStatus_STACK_OK && proc.Status != pb.StackStatus_STACK_UNSPECIFIED {
+				fmt.Fprintf(&b, "  pid %d (%s %s): %s %s\n", proc.Pid, proc.Type, proc.Rank, proc.Status, proc.Error)
+			}
+		}
+	}
+
+	section(&b, "Alerts")
+	for _, node := range nodes {
+		if node.alerts == nil {
+			fmt.Fprintf(&b, "%s: not collected\n", node.node)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", node.node, countAlerts(node.alerts.Alerts))
+	}
+
+	if len(m.Errors) > 0 {
+		section(&b, "Collection errors")
+		for _, e := range m.Errors {
+			fmt.Fprintf(&b, "%s %s: %s\n", e.Node, e.Section, e.Error)
+		}
+	}
+	return b.String()
+}
+
+func section(b *strings.Builder, title string) {
+	fmt.Fprintf(b, "\n== %s ==\n", title)
+}
+
+// Number of alerts by type, e.g. "3 alert, 1 process_exit"
+func countAlerts(alerts []*pb.AlertRecord) string {
+	if len(alerts) == 0 {
+		return "no alerts"
+	}
+	counts := make(map[string]int)
+	for _, alert := range alerts {
+		counts[alert.Type]++
+	}
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[t], t))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,32 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package diagnose
+
+import (
+	"testing"
+
+	pb "deeptrace/v1"
+)
+
+func TestCountAlerts(t *testing.T) {
+	tests := []struct {
+		name   string
+		alerts []*pb.AlertRecord
+		want   string
+	}{
+		{"no alerts", nil, "no alerts"},
+		{"one type", []*pb.AlertRecord{{Type: "hang"}, {Type: "hang"}}, "2 hang"},
+		{
+			"sorted by type",
+			[]*pb.AlertRecord{{Type: "process_exit"}, {Type: "alert"}, {Type: "process_exit"}},
+			"1 alert, 2 process_exit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countAlerts(tt.alerts); got != tt.want {
+				t.Errorf("countAlerts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"deeptrace/pkg/client/utils"
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc"
+)
+
+// ErrorGroup is one fatal error deduplicated across ranks
+type ErrorGroup struct {
+	Kind    string            `json:"kind"`
+	Summary string            `json:"summary"`
+	Ranks   []string          `json:"ranks"`
+	Count   int32             `json:"count"`
+	Context map[string]string `json:"context,omitempty"`
+	// Lines of the first occurrence
+	Lines []string `json:"lines"`
+}
+
+type logErrorsResult struct {
+	node string
+	resp *pb.LogErrorsResponse
+	err  error
+}
+
+// Numbers differ between ranks reporting the same error, e.g. "[Rank 3]" or "SeqNum=1201"
+var errorNumberReg = regexp.MustCompile(`\d+`)
+
+// FetchLogErrors prints the tracebacks and fatal errors of all ranks, the same error on
+// several ranks is printed once
+func FetchLogErrors(jobName string, addressList []string, workDir string, maxLines int32, port string) {
+	results := make(chan logErrorsResult, len(addressList))
+	for _, addr := range addressList {
+		go func(node string) {
+			conn, err := grpc.Dial(
+				node+":"+port,
+				grpc.WithInsecure(),
+				grpc.WithTimeout(5*time.Second),
+			)
+			if err != nil {
+				results <- logErrorsResult{node, nil, err}
+				return
+			}
+			defer conn.Close()
+
+			client := pb.NewDeepTraceServiceClient(conn)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			resp, err := client.GetLogErrors(ctx, &pb.GetLogErrorsRequest{WorkDir: workDir, MaxLines: maxLines})
+			results <- logErrorsResult{node, resp, err}
+		}(addr)
+	}
+
+	// Group in address list order, so the first rank of a group is stable
+	byNode := make(map[string]logErrorsResult, len(addressList))
+	for i := 0; i < len(addressList); i++ {
+		res := <-results
+		byNode[res.node] = res
+	}
+	close(results)
+	nodeErrors := make(map[string][]*pb.RankLogErrors)
+	for _, node := range addressList {
+		res := byNode[node]
+		if res.err != nil {
+			fmt.Printf("Failed to get log errors from node %s: %v\n", node, res.err)
+			continue
+		}
+		for _, rankLog := range res.resp.Ranklogs {
+			if rankLog.Status != pb.RankLogStatus_RANK_LOG_OK {
+				fmt.Printf("Node %s %s: %s (%s)\n", node, rankLog.Rank, rankLog.Status, rankLog.StatusMessage)
+			}
+			for _, e := range rankLog.Errors {
+				e.Summary = utils.CleanUTF8(e.Summary)
+				for i, line := range e.Lines {
+					e.Lines[i] = utils.CleanUTF8(line)
+				}
+			}
+		}
+		nodeErrors[node] = res.resp.Ranklogs
+	}
+
+	groups := GroupLogErrors(addressList, nodeErrors)
+	if len(groups) == 0 {
+		fmt.Println("No tracebacks or fatal errors found in the rank logs")
+		return
+	}
+	printErrorGroups(groups)
+
+	jsonData, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		fmt.Printf("Failed to convert to JSON: %v\n", err)
+		return
+	}
+	fileName := fmt.Sprintf("%s_log_errors.json", jobName)
+	if err := utils.AppendWithTimestamp("logs", fileName, jsonData); err != nil {
+		fmt.Println("Error:", err)
+	} else {
+		fmt.Printf("Log errors successfully saved to %s\n", fileName)
+	}
+}
+
+// GroupLogErrors merges the errors of every rank that have the same kind and the same
+// summary apart from numbers, in order of first occurrence
+func GroupLogErrors(addressList []string, nodeErrors map[string][]*pb.RankLogErrors) []*ErrorGroup {
+	var groups []*ErrorGroup
+	byKey := make(map[string]*ErrorGroup)
+	for _, node := range addressList {
+		for _, rankLog := range nodeErrors[node] {
+			for _, e := range rankLog.Errors {
+				key := e.Kind + "\x00" + errorNumberReg.ReplaceAllString(e.Summary, "N")
+				group, ok := byKey[key]
+				if !ok {
+					group = &ErrorGroup{Kind: e.Kind, Summary: e.Summary, Context: e.Context, Lines: e.Lines}
+					byKey[key] = group
+					groups = append(groups, group)
+				}
+				rank := node + "/" + rankLog.Rank
+				if len(group.Ranks) == 0 || group.Ranks[len(group.Ranks)-1] != rank {
+					group.Ranks = append(group.Ranks, rank)
+				}
+				group.Count += e.Count
+			}
+		}
+	}
+	return groups
+}
+
+func printErrorGroups(groups []*ErrorGroup) {
+	for _, group := range groups {
+		fmt.Println("==================================================")
+		fmt.Printf("[%s] %s\n", group.Kind, group.Summary)
+		fmt.Printf("  Ranks (%d): %v\n", len(group.Ranks), group.Ranks)
+		fmt.Printf("  Occurrences: %d\n", group.Count)
+		if location := group.Context["location"]; location != "" {
+			fmt.Printf("  Raised at: %s\n", location)
+		}
+		for _, line := range group.Lines {
+			fmt.Printf("  | %s\n", line)
+		}
+	}
+	fmt.Println("==================================================")
+}
@@ -0,0 +1,45 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	pb "deeptrace/v1"
+)
+
+func TestGroupLogErrors(t *testing.T) {
+	timeout := func(rank int) *pb.LogError {
+		return &pb.LogError{Kind: "nccl_timeout", Summary: fmt.Sprintf("[Rank %d] Watchdog caught collective operation timeout", rank), Count: 1}
+	}
+	oom := &pb.LogError{Kind: "cuda_oom", Summary: "torch.OutOfMemoryError: CUDA out of memory.", Count: 2}
+	nodeErrors := map[string][]*pb.RankLogErrors{
+		"node1": {
+			{Rank: "RANK0", Errors: []*pb.LogError{timeout(0)}},
+			{Rank: "RANK1", Errors: []*pb.LogError{oom}},
+		},
+		"node2": {
+			{Rank: "RANK2", Errors: []*pb.LogError{timeout(2)}},
+			{Rank: "RANK3", Status: pb.RankLogStatus_RANK_LOG_FILE_MISSING},
+		},
+	}
+
+	type group struct {
+		kind  string
+		ranks []string
+		count int32
+	}
+	var got []group
+	for _, g := range GroupLogErrors([]string{"node1", "node2"}, nodeErrors) {
+		got = append(got, group{g.Kind, g.Ranks, g.Count})
+	}
+	want := []group{
+		{"nccl_timeout", []string{"node1/RANK0", "node2/RANK2"}, 2},
+		{"cuda_oom", []string{"node1/RANK1"}, 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupLogErrors() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"deeptrace/pkg/client/utils"
+	pb "deeptrace/v1"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LogFilterOptions are applied by the agent before log lines are returned
+type LogFilterOptions struct {
+	Levels        []pb.LogLevel
+	SearchKeyword string
+	Regex         string
+	Since         *timestamppb.Timestamp
+	Until         *timestamppb.Timestamp
+	LogFormat     string
+}
+
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("level", nil, "Only return entries of these levels (DEBUG, INFO, WARNING, ERROR, CRITICAL), comma separated")
+	cmd.Flags().String("keyword", "", "Only return lines containing the keyword")
+	cmd.Flags().String("regex", "", "Only return lines matching the regular expression")
+	cmd.Flags().String("since", "", "Only return entries after this time (RFC3339, YYYY-MM-DDTHH:MM:SS or a duration such as 30m)")
+	cmd.Flags().String("until", "", "Only return entries before this time (same formats as --since)")
+	cmd.Flags().String("log-format", "", "Log format parser used by the agent (xtuner, python, loguru, glog, jsonl or a configured name), detected when empty")
+}
+
+func getFilterOptions(cmd *cobra.Command) (LogFilterOptions, error) {
+	opts := LogFilterOptions{}
+	levels, _ := cmd.Flags().GetStringSlice("level")
+	for _, name := range levels {
+		level, ok := parseLevelFlag(name)
+		if !ok {
+			return opts, fmt.Errorf("Invalid log level %q, available levels: DEBUG, INFO, WARNING, ERROR, CRITICAL", name)
+		}
+		opts.Levels = append(opts.Levels, level)
+	}
+	opts.SearchKeyword, _ = cmd.Flags().GetString("keyword")
+	opts.Regex, _ = cmd.Flags().GetString("regex")
+	opts.LogFormat, _ = cmd.Flags().GetString("log-format")
+	if opts.LogFormat == "" {
+		opts.LogFormat = viper.GetString("log-format")
+	}
+
+	now := time.Now()
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		t, err := utils.ParseTimeFlag(since, now)
+		if err != nil {
+			return opts, err
+		}
+		opts.Since = timestamppb.New(t)
+	}
+	if until, _ := cmd.Flags().GetString("until"); until != "" {
+		t, err := utils.ParseTimeFlag(until, now)
+		if err != nil {
+			return opts, err
+		}
+		opts.Until = timestamppb.New(t)
+	}
+	return opts, nil
+}
+
+func parseLevelFlag(name string) (pb.LogLevel, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "WARN" {
+		name = "WARNING"
+	}
+	if !strings.HasPrefix(name, "LOG_") {
+		name = "LOG_" + name
+	}
+	level, ok := pb.LogLevel_value[name]
+	if !ok || level == int32(pb.LogLevel_LOG_UNSPECIFIED) {
+		return pb.LogLevel_LOG_UNSPECIFIED, false
+	}
+	return pb.LogLevel(level), true
+}
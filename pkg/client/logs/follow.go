@@ -0,0 +1,115 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"deeptrace/pkg/client/utils"
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc"
+)
+
+// Delay before reconnecting a node whose log stream broke
+const followRetryInterval = 5 * time.Second
+
+type followResult struct {
+	node    string
+	rankLog *pb.RankLog
+}
+
+// FollowRankLogs merges the log streams of all nodes into one live view until interrupted
+func FollowRankLogs(addressList []string, workDir string, tailLines int32, rank string, port string, filter LogFilterOptions) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results := make(chan followResult, 64)
+	var wg sync.WaitGroup
+	for _, addr := range addressList {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			replay := tailLines
+			for {
+				err := followNode(ctx, node, port, workDir, replay, filter, results)
+				if ctx.Err() != nil {
+					return
+				}
+				fmt.Printf("Log stream from node %s interrupted: %v, reconnecting in %v\n", node, err, followRetryInterval)
+				// Only replay the tail once, otherwise lines are printed again after every reconnect
+				replay = 0
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(followRetryInterval):
+				}
+			}
+		}(addr)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	fmt.Println("Following logs, press Ctrl+C to stop")
+	for res := range results {
+		if rank != "" && !strings.EqualFold(res.rankLog.Rank, rank) {
+			continue
+		}
+		for _, entry := range res.rankLog.Entries {
+			fmt.Printf("[%s][%s] %s\n", res.node, res.rankLog.Rank, utils.CleanUTF8(entry.Message))
+		}
+	}
+}
+
+func followNode(ctx context.Context, node, port, workDir string, tailLines int32, filter LogFilterOptions, results chan<- followResult) error {
+	conn, err := grpc.Dial(
+		node+":"+port,
+		grpc.WithInsecure(),
+		grpc.WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := pb.NewDeepTraceServiceClient(conn)
+	stream, err := client.FollowLogs(ctx, &pb.FollowLogsRequest{
+		WorkDir:       workDir,
+		TailLines:     tailLines,
+		Levels:        filter.Levels,
+		SearchKeyword: filter.SearchKeyword,
+		Regex:         filter.Regex,
+		Since:         filter.Since,
+		Until:         filter.Until,
+		LogFormat:     filter.LogFormat,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		rankLog, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("stream closed by agent")
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case results <- followResult{node: node, rankLog: rankLog}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"strconv"
 	"time"
 
 	"deeptrace/pkg/client/utils"
@@ -19,10 +21,41 @@ import (
 
 // Create a customizable structure to store the converted results
 type CustomLogEntry struct {
-	Timestamp string      `json:"timestamp"`
-	Level     pb.LogLevel `json:"level,omitempty"`
-	Epoch     int32       `json:"epoch,omitempty"`
-	Message   string
+	Timestamp    string      `json:"timestamp"`
+	Level        pb.LogLevel `json:"level,omitempty"`
+	Epoch        int32       `json:"epoch,omitempty"`
+	Step         *int64      `json:"step,omitempty"`
+	Loss         *JSONFloat  `json:"loss,omitempty"`
+	Lr           *JSONFloat  `json:"lr,omitempty"`
+	TokensPerSec *JSONFloat  `json:"tokens_per_sec,omitempty"`
+	Tflops       *JSONFloat  `json:"tflops,omitempty"`
+	Message      string
+}
+
+// JSONFloat writes NaN and Inf as strings, encoding/json rejects them as numbers
+type JSONFloat float64
+
+func (f JSONFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return json.Marshal(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return json.Marshal(v)
+}
+
+// Convert a log entry to its JSON output form
+func NewCustomLogEntry(entry *pb.LogEntry) CustomLogEntry {
+	return CustomLogEntry{
+		Timestamp:    utils.FormatTimestamp(entry.Timestamp),
+		Level:        entry.Level,
+		Epoch:        entry.Epoch,
+		Step:         entry.Step,
+		Loss:         (*JSONFloat)(entry.Loss),
+		Lr:           (*JSONFloat)(entry.Lr),
+		TokensPerSec: (*JSONFloat)(entry.TokensPerSec),
+		Tflops:       (*JSONFloat)(entry.Tflops),
+		Message:      entry.Message,
+	}
 }
 
 type CustomRankLog struct {
@@ -30,6 +63,24 @@ type CustomRankLog struct {
 	Entries        []CustomLogEntry `json:"entries"`
 	SuspendSeconds int32            `json:"suspend_seconds"`
 	TailTime       string           `json:"tail_time"`
+	Status         string           `json:"status"`
+	StatusMessage  string           `json:"status_message,omitempty"`
+}
+
+// Convert a rank log to its JSON output form
+func NewCustomRankLog(rankLog *pb.RankLog) CustomRankLog {
+	var customEntries []CustomLogEntry
+	for _, entry := range rankLog.Entries {
+		customEntries = append(customEntries, NewCustomLogEntry(entry))
+	}
+	return CustomRankLog{
+		Rank:           rankLog.Rank,
+		Entries:        customEntries,
+		SuspendSeconds: rankLog.SuspendSeconds,
+		TailTime:       utils.FormatTimestamp(rankLog.TailTime),
+		Status:         rankLog.Status.String(),
+		StatusMessage:  rankLog.StatusMessage,
+	}
 }
 
 type CustomLogResponse struct {
@@ -46,16 +97,23 @@ type Result struct {
 func NewCmdLogs() *cobra.Command {
 	var workDir string
 	var maxLines int32
+	var follow bool
+	var dryRun bool
+	var errorsView bool
 
 	cmd := &cobra.Command{
 		Use:   "logs",
 		Short: "Get log information",
 		Long: `Get log information for the specified job.
 Usage:
-  client logs --job-id <job name> -w clusterx [--work-dir <working directory>] [--max-line <maximum lines>] [--port <server port>]
+  client logs --job-id <job name> -w clusterx [--work-dir <working directory>] [--max-line <maximum lines>] [--follow] [--dry-run] [--errors] [--level <levels>] [--keyword <keyword>] [--regex <regex>] [--since <time>] [--until <time>] [--port <server port>]
 
 Examples:
-  client logs --job-id my_job -w clusterx --work-dir /mnt/shared-storage --max-line 30 --port 50052`,
+  client logs --job-id my_job -w clusterx --work-dir /mnt/shared-storage --max-line 30 --port 50052
+  client logs --job-id my_job -w clusterx --follow --max-line 10  # Follow new log lines of all nodes
+  client logs --job-id my_job -w clusterx --dry-run  # Show which log file each rank resolves to
+  client logs --job-id my_job -w clusterx --errors  # Tracebacks and fatal errors, deduplicated across ranks
+  client logs --job-id my_job -w clusterx --level ERROR,CRITICAL --since 30m  # Errors of the last 30 minutes`,
 		Run: func(cmd *cobra.Command, args []string) {
 			jobName, _ := cmd.Flags().GetString("job-id")
 			if jobName == "" {
@@ -109,6 +167,15 @@ Examples:
 			} else {
 				fmt.Printf("Using working directory specified on command line: %s\n", workDir)
 			}
+			if errorsView {
+				// The agent scans 2000 lines per rank unless --max-line is given
+				var scanLines int32
+				if cmd.Flags().Changed("max-line") {
+					scanLines = maxLines
+				}
+				FetchLogErrors(jobName, addressList, workDir, scanLines, port)
+				return
+			}
 			if maxLines == 0 {
 				maxLines = int32(viper.GetInt("max-line"))
 				if maxLines != 0 {
@@ -121,20 +188,39 @@ Examples:
 				fmt.Printf("Using maximum log lines specified on command line: %d\n", maxLines)
 			}
 
-			FetchRankLogs(jobName, addressList, workDir, maxLines, rank, port)
+			if dryRun {
+				ResolveRankLogFiles(addressList, workDir, port)
+				return
+			}
+
+			filter, err := getFilterOptions(cmd)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if follow {
+				FollowRankLogs(addressList, workDir, maxLines, rank, port, filter)
+				return
+			}
+			FetchRankLogs(jobName, addressList, workDir, maxLines, rank, port, filter)
 		},
 	}
 
 	// Add --work-dir and --max-line flags
 	cmd.Flags().StringVar(&workDir, "work-dir", "", "Specify working directory")
 	cmd.Flags().Int32Var(&maxLines, "max-line", 0, "Specify maximum log lines")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep streaming new log lines from all nodes, --max-line sets the lines replayed per rank first")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show which log file each rank resolves to on every node")
+	cmd.Flags().BoolVar(&errorsView, "errors", false, "Show the Python tracebacks and fatal errors (NCCL timeout, CUDA OOM, segfault) found at the end of the rank logs, deduplicated across ranks")
 	cmd.Flags().String("rank", "", "rank number, if not specified, return all ranks")
 	_ = cmd.Flags().MarkHidden("rank")
+	addFilterFlags(cmd)
 
 	return cmd
 }
 
-func FetchRankLogs(jobName string, addressList []string, workDir string, maxLines int32, rank string, port string) {
+func FetchRankLogs(jobName string, addressList []string, workDir string, maxLines int32, rank string, port string, filter LogFilterOptions) {
 	results := make(chan Result, len(addressList))
 
 	// Start goroutines to process each node in parallel
@@ -156,8 +242,14 @@ func FetchRankLogs(jobName string, addressList []string, workDir string, maxLine
 			defer cancel()
 
 			req := &pb.GetRecentLogsRequest{
-				MaxLines: maxLines,
-				WorkDir:  workDir,
+				MaxLines:      maxLines,
+				WorkDir:       workDir,
+				Levels:        filter.Levels,
+				SearchKeyword: filter.SearchKeyword,
+				Regex:         filter.Regex,
+				Since:         filter.Since,
+				Until:         filter.Until,
+				LogFormat:     filter.LogFormat,
 			}
 
 			resp, err := client.GetRecentLogs(ctx, req)
@@ -215,21 +307,7 @@ func FetchRankLogs(jobName string, addressList []string, workDir string, maxLine
 
 	customResponse := CustomLogResponse{}
 	for _, rankLog := range finalResponse.Ranklogs {
-		var customEntries []CustomLogEntry
-		for _, entry := range rankLog.Entries {
-			customEntries = append(customEntries, CustomLogEntry{
-				Timestamp: utils.FormatTimestamp(entry.Timestamp),
-				Level:     entry.Level,
-				Epoch:     entry.Epoch,
-				Message:   entry.Message,
-			})
-		}
-		customResponse.Ranklogs = append(customResponse.Ranklogs, CustomRankLog{
-			Rank:           rankLog.Rank,
-			Entries:        customEntries,
-			SuspendSeconds: rankLog.SuspendSeconds,
-			TailTime:       utils.FormatTimestamp(rankLog.TailTime),
-		})
+		customResponse.Ranklogs = append(customResponse.Ranklogs, NewCustomRankLog(rankLog))
 	}
 	jsonData, err := json.MarshalIndent(customResponse, "", "  ")
 	if customResponse.Ranklogs != nil {
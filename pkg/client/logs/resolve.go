@@ -0,0 +1,73 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"deeptrace/pkg/client/utils"
+	pb "deeptrace/v1"
+
+	"google.golang.org/grpc"
+)
+
+type resolveResult struct {
+	node string
+	resp *pb.ResolveLogFilesResponse
+	err  error
+}
+
+// ResolveRankLogFiles prints which log file each node uses for every rank, without reading logs
+func ResolveRankLogFiles(addressList []string, workDir string, port string) {
+	results := make(chan resolveResult, len(addressList))
+	for _, addr := range addressList {
+		go func(node string) {
+			conn, err := grpc.Dial(
+				node+":"+port,
+				grpc.WithInsecure(),
+				grpc.WithTimeout(5*time.Second),
+			)
+			if err != nil {
+				results <- resolveResult{node, nil, err}
+				return
+			}
+			defer conn.Close()
+
+			client := pb.NewDeepTraceServiceClient(conn)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			resp, err := client.ResolveLogFiles(ctx, &pb.ResolveLogFilesRequest{WorkDir: workDir})
+			results <- resolveResult{node, resp, err}
+		}(addr)
+	}
+
+	// Print in address list order
+	byNode := make(map[string]resolveResult, len(addressList))
+	for i := 0; i < len(addressList); i++ {
+		res := <-results
+		byNode[res.node] = res
+	}
+	close(results)
+
+	for _, node := range addressList {
+		res := byNode[node]
+		if res.err != nil {
+			fmt.Printf("[%s] Failed to resolve log files: %v\n", node, res.err)
+			continue
+		}
+		fmt.Printf("[%s] layout %s, run directory %s\n", node, res.resp.Layout, res.resp.RunDir)
+		for _, file := range res.resp.Files {
+			if !file.Exists {
+				fmt.Printf("  %-8s %s (missing)\n", file.Rank, file.Path)
+				continue
+			}
+			fmt.Printf("  %-8s %s (%d bytes, modified %s)\n", file.Rank, file.Path, file.Size, utils.FormatTimestamp(file.ModTime))
+		}
+		for _, warning := range res.resp.Warnings {
+			fmt.Printf("  Warning: %s\n", warning)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacks
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"deeptrace/pkg/rules"
+	"deeptrace/pkg/stackfmt"
+	pb "deeptrace/v1"
+)
+
+// CollapsedStacks counts identical stacks in Brendan Gregg's collapsed format: frames
+// from the process type down to the innermost frame, joined by ";"
+type CollapsedStacks map[string]int
+
+// CollapseStacks aggregates the threads of processes dumped on any number of ranks and
+// samples. Frames are named without line numbers so the same code path on different
+// ranks and iterations adds up.
+func CollapseStacks(processes []*pb.ProcessInfo) CollapsedStacks {
+	stacks := make(CollapsedStacks)
+	for _, proc := range processes {
+		if proc.Status != pb.StackStatus_STACK_OK && proc.Status != pb.StackStatus_STACK_UNSPECIFIED {
+			continue
+		}
+		for _, thread := range proc.Threads {
+			frames := []string{proc.Type.String()}
+			if len(thread.Frames) == 0 {
+				frames = append(frames, rules.CallPath(thread, nil)...)
+			} else {
+				for _, frame := range thread.Frames {
+					frames = append(frames, stackfmt.FrameName(frame))
+				}
+			}
+			for i, frame := range frames {
+				// ";" separates frames and the count follows the last space
+				frames[i] = strings.ReplaceAll(frame, ";", ":")
+			}
+			stacks[strings.Join(frames, ";")]++
+		}
+	}
+	return stacks
+}
+
+// WriteCollapsed writes one "frame;frame;frame count" line per stack, sorted by stack
+func (c CollapsedStacks) WriteCollapsed(w io.Writer) error {
+	keys := make([]string, 0, len(c))
+	for stack := range c {
+		keys = append(keys, stack)
+	}
+	sort.Strings(keys)
+	for _, stack := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, c[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layout of the flame graph
+const (
+	flameWidth       = 1200
+	flameFrameHeight = 16
+	flamePadding     = 10
+	flameTitleHeight = 30
+	// Approximate width of a character of the 12px font
+	flameCharWidth = 7
+)
+
+type flameNode struct {
+	name     string
+	value    int
+	children map[string]*flameNode
+}
+
+func (n *flameNode) child(name string) *flameNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+	c := &flameNode{name: name, children: make(map[string]*flameNode)}
+	n.children[name] = c
+	return c
+}
+
+func (n *flameNode) depth() int {
+	depth := 0
+	for _, c := range n.children {
+		if d := c.depth(); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+type flameRect struct {
+	X, Y, Width float64
+	Label       string
+	Title       string
+	Color       string
+}
+
+// WriteFlameGraph draws the stacks as a self-contained SVG flame graph, the root at the
+// bottom. With asHTML the SVG is embedded in an HTML page.
+func (c CollapsedStacks) WriteFlameGraph(w io.Writer, title string, asHTML bool) error {
+	root := &flameNode{name: "all", children: make(map[string]*flameNode)}
+	for stack, count := range c {
+		root.value += count
+		node := root
+		for _, frame := range strings.Split(stack, ";") {
+			node = node.child(frame)
+			node.value += count
+		}
+	}
+
+	depth := root.depth()
+	height := flameTitleHeight + depth*flameFrameHeight + 2*flamePadding
+	var rects []flameRect
+	if root.value > 0 {
+		scale := float64(flameWidth-2*flamePadding) / float64(root.value)
+		var layout func(n *flameNode, x float64, level int)
+		layout = func(n *flameNode, x float64, level int) {
+			width := float64(n.value) * scale
+			rects = append(rects, flameRect{
+				X:     x,
+				Y:     float64(height - flamePadding - (level+1)*flameFrameHeight),
+				Width: width,
+				Label: fitLabel(n.name, width),
+				Title: fmt.Sprintf("%s (%d samples, %.2f%%)", n.name, n.value, 100*float64(n.value)/float64(root.value)),
+				Color: frameColor(n.name),
+			})
+			names := make([]string, 0, len(n.children))
+			for name := range n.children {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				child := n.children[name]
+				layout(child, x, level+1)
+				x += float64(child.value) * scale
+			}
+		}
+		layout(root, flamePadding, 0)
+	}
+
+	return flameTemplate.Execute(w, struct {
+		Title  string
+		Width  int
+		Center int
+		Height int
+		Rects  []flameRect
+		HTML   bool
+	}{title, flameWidth, flameWidth / 2, height, rects, asHTML})
+}
+
+// Label drawn in a frame, cut to the frame width
+func fitLabel(name string, width float64) string {
+	chars := int(width-6) / flameCharWidth
+	if chars < 3 {
+		return ""
+	}
+	if len(name) <= chars {
+		return name
+	}
+	return name[:chars-2] + ".."
+}
+
+// Warm color derived from the frame name, so a frame has the same color in every graph
+func frameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, (v>>8)%230, (v>>16)%55)
+}
+
+// IsHTML reports whether a flame graph file should be written as HTML
+func IsHTML(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".html" || ext == ".htm"
+}
+
+var flameTemplate = template.Must(template.New("flamegraph").Parse(`{{if .HTML}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+{{end}}<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" font-family="Verdana, sans-serif" font-size="12">
+<rect x="0" y="0" width="{{.Width}}" height="{{.Height}}" fill="#f8f8f8"/>
+<text x="{{.Center}}" y="20" text-anchor="middle" font-size="16">{{.Title}}</text>
+{{range .Rects}}<g>
+<title>{{.Title}}</title>
+<rect x="{{printf "%.2f" .X}}" y="{{printf "%.2f" .Y}}" width="{{printf "%.2f" .Width}}" height="15" fill="{{.Color}}" rx="2" ry="2"/>
+{{if .Label}}<text x="{{printf "%.2f" .X}}" dx="3" y="{{printf "%.2f" .Y}}" dy="12">{{.Label}}</text>
+{{end}}</g>
+{{end}}</svg>
+{{if .HTML}}</body>
+</html>
+{{end}}`))
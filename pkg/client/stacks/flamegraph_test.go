@@ -0,0 +1,54 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stacks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseStacks(t *testing.T) {
+	step := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step", Module: "train"}
+	allReduce := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "c10d.py", Line: 2050, Function: "all_reduce", Module: "torch.distributed.distributed_c10d"}
+	nccl := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "ncclGroupEnd", Module: "libnccl.so.2"}
+	trainer := func(rank string, line int32) *pb.ProcessInfo {
+		return &pb.ProcessInfo{Type: pb.ProcessType_PROCESS_TRAINER, Rank: rank, Status: pb.StackStatus_STACK_OK, Threads: []*pb.ThreadStack{
+			{ThreadId: 1, Frames: []*pb.StackFrame{
+				{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: line, Function: "<module>", Module: "train"},
+				step, allReduce, nccl,
+			}},
+			{ThreadId: 2, StackFrames: []string{"File \"/usr/lib/python3.12/threading.py\", line 1030, in _bootstrap\nself._bootstrap_inner()"}},
+		}}
+	}
+	processes := []*pb.ProcessInfo{
+		trainer("RANK0", 120),
+		// Another rank in another sample, at a different line
+		trainer("RANK1", 121),
+		{Type: pb.ProcessType_PROCESS_TRAINER, Rank: "RANK2", Status: pb.StackStatus_STACK_TIMEOUT, Threads: []*pb.ThreadStack{{ThreadId: 1}}},
+	}
+
+	stacks := CollapseStacks(processes)
+	var buf bytes.Buffer
+	assert.NoError(t, stacks.WriteCollapsed(&buf))
+	assert.Equal(t,
+		"PROCESS_TRAINER;threading.py:_bootstrap 2\n"+
+			"PROCESS_TRAINER;train:<module>;train:step;torch.distributed.distributed_c10d:all_reduce;libnccl.so.2:ncclGroupEnd 2\n",
+		buf.String())
+
+	buf.Reset()
+	assert.NoError(t, stacks.WriteFlameGraph(&buf, "Stacks of my_job", false))
+	svg := buf.String()
+	assert.True(t, strings.HasPrefix(svg, "<svg"), svg)
+	assert.Contains(t, svg, "train:step (2 samples, 50.00%)")
+	assert.Contains(t, svg, "train:&lt;module&gt;")
+
+	buf.Reset()
+	assert.NoError(t, stacks.WriteFlameGraph(&buf, "Stacks of my_job", true))
+	assert.True(t, strings.HasPrefix(buf.String(), "<!DOCTYPE html>"))
+	assert.Contains(t, buf.String(), "<svg")
+}
@@ -5,6 +5,7 @@ package stacks
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -25,10 +26,20 @@ func NewCmdStacks() *cobra.Command {
 		Short: "Get information through stack",
 		Long: `Get stack information for the specified job.
 Usage:
-  client stacks --job-id <job name> -w clusterx --process-type <process type> --rank <rank> [--port <service port>]
+  client stacks --job-id <job name> -w clusterx --process-type <process type> --rank <rank> [--native | --native-all] [--backend <backend>] [--samples <count> --interval <seconds>] [--collapsed <file>] [--flamegraph <file.svg|file.html>] [--port <service port>]
+
+Native frames (C/C++/CUDA) are needed to see where NCCL or CUDA calls are blocked.
+--native adds them to threads running Python code, --native-all to every thread.
+--backend selects the tool dumping the stacks on the agent: pystack, py-spy, gdb (native
+frames only) or proc (kernel stacks). By default the agent uses the first one installed.
+--collapsed and --flamegraph aggregate the threads of all ranks into collapsed stacks
+(Brendan Gregg's format, e.g. for flamegraph.pl or speedscope) and a self-contained flame
+graph. With --samples the stacks are sampled several times and every sample adds up.
 
 Example:
-  client stacks --job-id my_job -w clusterx --process-type PROCESS_TRAINER --rank 0 --port 50052`,
+  client stacks --job-id my_job -w clusterx --process-type PROCESS_TRAINER --rank 0 --port 50052
+  client stacks --job-id my_job -w clusterx --rank 0 --native
+  client stacks --job-id my_job -w clusterx --process-type PROCESS_TRAINER --samples 10 --interval 2 --flamegraph trainers.svg`,
 		Run: func(cmd *cobra.Command, args []string) {
 			jobName, _ := cmd.Flags().GetString("job-id")
 			if jobName == "" {
@@ -98,17 +109,58 @@ Example:
 				os.Exit(1)
 			}
 
-			FetchStacksFromNodes(jobName, addressList, processType, rank, port)
+			nativeMode := pb.NativeMode_NATIVE_OFF
+			if nativeAll, _ := cmd.Flags().GetBool("native-all"); nativeAll {
+				nativeMode = pb.NativeMode_NATIVE_ALL
+			} else if native, _ := cmd.Flags().GetBool("native"); native {
+				nativeMode = pb.NativeMode_NATIVE
+			}
+
+			backend, _ := cmd.Flags().GetString("backend")
+			if backend == "" {
+				backend = viper.GetString("stack-backend")
+			}
+
+			timeout, _ := cmd.Flags().GetInt32("timeout")
+
+			collapsedFile, _ := cmd.Flags().GetString("collapsed")
+			flameGraphFile, _ := cmd.Flags().GetString("flamegraph")
+			samples, _ := cmd.Flags().GetInt32("samples")
+			interval, _ := cmd.Flags().GetInt32("interval")
+
+			var processes []*pb.ProcessInfo
+			if samples > 1 {
+				if collapsedFile == "" && flameGraphFile == "" {
+					fmt.Println("Error: --samples requires --collapsed or --flamegraph")
+					os.Exit(1)
+				}
+				processes = SampleStacksFromNodes(addressList, processType, rank, port, nativeMode, backend, timeout, samples, interval)
+			} else {
+				processes = FetchStacksFromNodes(jobName, addressList, processType, rank, port, nativeMode, backend, timeout)
+			}
+
+			if collapsedFile != "" || flameGraphFile != "" {
+				writeFlameGraphFiles(CollapseStacks(processes), collapsedFile, flameGraphFile, jobName)
+			}
 		},
 	}
 
 	cmd.Flags().String("process-type", "", "Target process type (PROCESS_TRAINER, PROCESS_DATA_LOADER, PROCESS_LAUNCHER, if not specified, return all types)")
 	cmd.Flags().String("rank", "", "Rank number, if not specified, return all ranks")
+	cmd.Flags().Bool("native", false, "Include native (C/C++/CUDA) frames of threads running Python code")
+	cmd.Flags().Bool("native-all", false, "Include native frames of all threads, including threads without Python frames")
+	cmd.Flags().Int32("timeout", 20, "Seconds allowed to dump the stacks of one process")
+	cmd.Flags().String("backend", "", "Stack backend (auto, pystack, py-spy, gdb, proc), if not specified, the agent's default is used")
+	cmd.Flags().String("collapsed", "", "Write the stacks aggregated over ranks and samples in collapsed-stack format to this file")
+	cmd.Flags().String("flamegraph", "", "Write a flame graph of the stacks aggregated over ranks and samples to this file (.svg or .html)")
+	cmd.Flags().Int32("samples", 1, "Number of stack samples aggregated into --collapsed and --flamegraph")
+	cmd.Flags().Int32("interval", 5, "Seconds between stack samples")
 
 	return cmd
 }
 
-func FetchStacksFromNodes(jobName string, addressList []string, processType pb.ProcessType, rank string, port string) {
+// FetchStacksFromNodes dumps and saves the stacks of the selected processes on every node, returning all processes
+func FetchStacksFromNodes(jobName string, addressList []string, processType pb.ProcessType, rank string, port string, nativeMode pb.NativeMode, backend string, timeout int32) []*pb.ProcessInfo {
 	type Result struct {
 		address string
 		stacks  *pb.ProcessStacksResponse
@@ -132,12 +184,16 @@ func FetchStacksFromNodes(jobName string, addressList []string, processType pb.P
 			defer conn.Close()
 
 			client := pb.NewDeepTraceServiceClient(conn)
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			// Processes are dumped in parallel, leave the agent time beyond the per-process limit
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second+10*time.Second)
 			defer cancel()
 
 			req := &pb.GetProcessStacksRequest{
-				ProcessType: processType,
-				Rank:        rank,
+				ProcessType:    processType,
+				Rank:           rank,
+				NativeMode:     nativeMode,
+				Backend:        backend,
+				TimeoutSeconds: timeout,
 			}
 
 			resp, err := client.GetProcessStacks(ctx, req)
@@ -158,6 +214,14 @@ func FetchStacksFromNodes(jobName string, addressList []string, processType pb.P
 			fmt.Printf("Failed to get stack information from node %s: %v\n", res.address, res.err)
 			continue
 		}
+		if res.stacks.SampledProcesses < res.stacks.TotalProcesses {
+			fmt.Printf("Node %s: dumped %d of %d processes\n", res.address, res.stacks.SampledProcesses, res.stacks.TotalProcesses)
+			for _, proc := range res.stacks.Processes {
+				if proc.Status != pb.StackStatus_STACK_OK {
+					fmt.Printf("  PID %d %s %s: %s: %s\n", proc.Pid, proc.Type, proc.Rank, proc.Status, proc.Error)
+				}
+			}
+		}
 		allProcesses = append(allProcesses, res.stacks.Processes...)
 	}
 	close(results)
@@ -174,7 +238,7 @@ func FetchStacksFromNodes(jobName string, addressList []string, processType pb.P
 	})
 	if err != nil {
 		fmt.Printf("Failed to convert to JSON: %v\n", err)
-		return
+		return allProcesses
 	}
 	if rank == "" {
 		rank = "allrank"
@@ -190,5 +254,99 @@ func FetchStacksFromNodes(jobName string, addressList []string, processType pb.P
 			fmt.Printf("Process data successfully saved to %s\n", fileName)
 		}
 	}
+	return allProcesses
+}
+
+// SampleStacksFromNodes samples the stacks of the selected processes several times on every
+// node, returning the processes of all samples
+func SampleStacksFromNodes(addressList []string, processType pb.ProcessType, rank string, port string, nativeMode pb.NativeMode, backend string, timeout, count, interval int32) []*pb.ProcessInfo {
+	type Result struct {
+		address string
+		samples *pb.SampleProcessStacksResponse
+		err     error
+	}
 
+	results := make(chan Result, len(addressList))
+
+	for _, addr := range addressList {
+		go func(address string) {
+			conn, err := grpc.Dial(
+				address+":"+port,
+				grpc.WithInsecure(),
+				grpc.WithTimeout(5*time.Second),
+			)
+			if err != nil {
+				results <- Result{address, nil, err}
+				return
+			}
+			defer conn.Close()
+
+			client := pb.NewDeepTraceServiceClient(conn)
+			// The agent samples for count*interval plus the time of each dump
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(count*(interval+timeout)+10)*time.Second)
+			defer cancel()
+
+			resp, err := client.SampleProcessStacks(ctx, &pb.SampleProcessStacksRequest{
+				Count:           count,
+				IntervalSeconds: interval,
+				ProcessType:     processType,
+				Rank:            rank,
+				NativeMode:      nativeMode,
+				Backend:         backend,
+				TimeoutSeconds:  timeout,
+			})
+			results <- Result{address, resp, err}
+		}(addr)
+	}
+
+	var allProcesses []*pb.ProcessInfo
+	for i := 0; i < len(addressList); i++ {
+		res := <-results
+		if res.err != nil {
+			fmt.Printf("Failed to sample stack information from node %s: %v\n", res.address, res.err)
+			continue
+		}
+		fmt.Printf("Node %s: %d samples\n", res.address, len(res.samples.Samples))
+		for _, sample := range res.samples.Samples {
+			allProcesses = append(allProcesses, sample.Processes...)
+		}
+	}
+	close(results)
+	return allProcesses
+}
+
+func writeFlameGraphFiles(stacks CollapsedStacks, collapsedFile, flameGraphFile, jobName string) {
+	if collapsedFile != "" {
+		if err := writeFile(collapsedFile, stacks.WriteCollapsed); err != nil {
+			fmt.Println("Error:", err)
+		} else {
+			fmt.Printf("Collapsed stacks saved to %s\n", collapsedFile)
+		}
+	}
+	if flameGraphFile != "" {
+		title := "Stacks"
+		if jobName != "" {
+			title = fmt.Sprintf("Stacks of %s", jobName)
+		}
+		err := writeFile(flameGraphFile, func(w io.Writer) error {
+			return stacks.WriteFlameGraph(w, title, IsHTML(flameGraphFile))
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+		} else {
+			fmt.Printf("Flame graph saved to %s\n", flameGraphFile)
+		}
+	}
+}
+
+func writeFile(fileName string, write func(w io.Writer) error) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("Cannot create file: %v", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to write to file: %v", err)
+	}
+	return file.Close()
 }
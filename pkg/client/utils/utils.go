@@ -114,6 +114,25 @@ func FormatTimestamp(ts *timestamppb.Timestamp) string {
 	return ts.AsTime().Format(time.RFC3339)
 }
 
+// ParseTimeFlag parses a time given on the command line. Besides absolute times
+// (RFC3339 or local "2006-01-02T15:04:05" / "2006-01-02 15:04:05"), a duration
+// such as "30m" means that long before now.
+func ParseTimeFlag(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid time %q, expected RFC3339, YYYY-MM-DDTHH:MM:SS or a duration such as 30m", value)
+}
+
 // Extract numeric part from string (e.g., extract 123 from "rank123")
 func ExtractNumber(s string) (int, error) {
 	re := regexp.MustCompile(`\d+`) // Match numbers in string
@@ -5,6 +5,7 @@ package utils
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 // TestExtractNodes tests various scenarios of the extractNodes function
@@ -160,3 +161,30 @@ func TestCompareRank_ErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2025, 7, 11, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "duration", value: "30m", want: now.Add(-30 * time.Minute)},
+		{name: "rfc3339", value: "2025-07-11T02:32:52Z", want: time.Date(2025, 7, 11, 2, 32, 52, 0, time.UTC)},
+		{name: "local time", value: "2025-07-11T02:32:52", want: time.Date(2025, 7, 11, 2, 32, 52, 0, time.Local)},
+		{name: "local time with space", value: "2025-07-11 02:32:52", want: time.Date(2025, 7, 11, 2, 32, 52, 0, time.Local)},
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeFlag(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeFlag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseTimeFlag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
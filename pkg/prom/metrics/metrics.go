@@ -49,6 +49,25 @@ func MetricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnarySe
 	return resp, err
 }
 
+// gRPC stream interceptor, the duration covers the whole stream lifetime
+func StreamMetricsInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	method := info.FullMethod
+
+	err := handler(srv, ss)
+
+	duration := time.Since(start).Seconds()
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	RequestsTotal.WithLabelValues(method, status).Inc()
+	RequestDuration.WithLabelValues(method).Observe(duration)
+
+	return err
+}
+
 func PushMetricsToGateway(pushgatewayUrl, jobName string, interval time.Duration) {
 	if pushgatewayUrl == "" {
 		logger.Logger.Error("Pushgateway URL not set, skipping metrics push")
@@ -0,0 +1,224 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package rules
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"deeptrace/pkg/stackfmt"
+	pb "deeptrace/v1"
+)
+
+// NodeProcesses are the processes dumped on one node
+type NodeProcesses struct {
+	Node      string
+	Processes []*pb.ProcessInfo
+}
+
+// RankRef identifies the trainer process of a rank
+type RankRef struct {
+	Node string
+	Rank string
+	Pid  int32
+}
+
+// StackGroup is a set of ranks whose main threads share a call path
+type StackGroup struct {
+	// Normalized frames, outermost first
+	CallPath []string
+	Ranks    []RankRef
+}
+
+// Outlier is a rank outside the majority group
+type Outlier struct {
+	RankRef
+	// Index of the outlier's group in ConsensusReport.Groups
+	Group int
+	// First frame level (from 1) where the call path leaves the majority's
+	DivergeLevel int
+	// Frame of the outlier and of the majority at that level, empty past the end of a path
+	Frame         string
+	MajorityFrame string
+}
+
+// ConsensusReport groups the trainer stacks of all ranks
+type ConsensusReport struct {
+	TotalRanks int
+	// Largest group first
+	Groups []StackGroup
+	// Index of the majority group, -1 when the largest groups are tied
+	Majority int
+	Outliers []Outlier
+	// Ranks that could not be compared, e.g. their stacks could not be dumped
+	Skipped []RankRef
+}
+
+// StackConsensus groups trainer ranks across nodes by the call path of their main thread
+// and reports the ranks that sit elsewhere than the majority. In a collective hang most
+// ranks wait in the collective while the outliers show where the job got stuck. Threads
+// ignored by the normalizer are not taken as main thread, and its collapsed and top
+// frames apply to the call paths.
+func StackConsensus(nodes []NodeProcesses, n *Normalizer) *ConsensusReport {
+	report := &ConsensusReport{Majority: -1}
+	groups := make(map[string]int)
+	for _, node := range nodes {
+		for _, proc := range node.Processes {
+			if proc.Type != pb.ProcessType_PROCESS_TRAINER {
+				continue
+			}
+			ref := RankRef{Node: node.Node, Rank: proc.Rank, Pid: proc.Pid}
+			report.TotalRanks++
+			thread := mainThread(proc, n)
+			if thread == nil || !stackDumped(proc) {
+				report.Skipped = append(report.Skipped, ref)
+				continue
+			}
+			callPath := CallPath(thread, n)
+			key := strings.Join(callPath, "\n")
+			idx, ok := groups[key]
+			if !ok {
+				idx = len(report.Groups)
+				groups[key] = idx
+				report.Groups = append(report.Groups, StackGroup{CallPath: callPath})
+			}
+			report.Groups[idx].Ranks = append(report.Groups[idx].Ranks, ref)
+		}
+	}
+
+	for i := range report.Groups {
+		sortRanks(report.Groups[i].Ranks)
+	}
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return len(report.Groups[i].Ranks) > len(report.Groups[j].Ranks)
+	})
+	sortRanks(report.Skipped)
+	if len(report.Groups) == 0 {
+		return report
+	}
+	if len(report.Groups) > 1 && len(report.Groups[0].Ranks) == len(report.Groups[1].Ranks) {
+		return report
+	}
+
+	report.Majority = 0
+	majority := report.Groups[0].CallPath
+	for i, group := range report.Groups[1:] {
+		level := divergeLevel(majority, group.CallPath)
+		for _, ref := range group.Ranks {
+			report.Outliers = append(report.Outliers, Outlier{
+				RankRef:       ref,
+				Group:         i + 1,
+				DivergeLevel:  level,
+				Frame:         frameAt(group.CallPath, level),
+				MajorityFrame: frameAt(majority, level),
+			})
+		}
+	}
+	return report
+}
+
+// Main thread of a process: the thread whose ID is the PID, else the deepest stack
+func mainThread(proc *pb.ProcessInfo, n *Normalizer) *pb.ThreadStack {
+	var deepest *pb.ThreadStack
+	for _, thread := range n.Threads(proc.Threads) {
+		if thread.ThreadId == proc.Pid {
+			return thread
+		}
+		if deepest == nil || len(stackfmt.ThreadFrames(thread)) > len(stackfmt.ThreadFrames(deepest)) {
+			deepest = thread
+		}
+	}
+	return deepest
+}
+
+// CallPath returns the frames of a thread compared across ranks, outermost first, normalized
+// by n unless it is nil. Line numbers are left out so ranks at different iterations of the
+// same loop still match. Native frames are only used when the thread has no Python frames,
+// they differ between ranks waiting in the same collective. Frames are collapsed by their
+// full text, so patterns on file paths apply too.
+func CallPath(thread *pb.ThreadStack, n *Normalizer) []string {
+	names, texts := callPathFrames(thread)
+	if n == nil {
+		return names
+	}
+	return n.reduce(names, texts)
+}
+
+// Frames of the call path of a thread and the full text of each, without line numbers
+func callPathFrames(thread *pb.ThreadStack) (callPath, texts []string) {
+	if len(thread.Frames) == 0 {
+		for _, frame := range thread.StackFrames {
+			// Legacy frames: File "<path>", line <n>, in <function>\n<code>
+			frame, _, _ = strings.Cut(frame, "\n")
+			texts = append(texts, legacyLineRegexp.ReplaceAllString(frame, "$1$2"))
+			if file, rest, ok := strings.Cut(frame, ", line "); ok {
+				if _, function, ok := strings.Cut(rest, ", in "); ok {
+					frame = path.Base(strings.Trim(strings.TrimPrefix(file, "File "), `"`)) + ":" + function
+				}
+			}
+			callPath = append(callPath, frame)
+		}
+		return callPath, texts
+	}
+
+	lang := pb.FrameLanguage_FRAME_NATIVE
+	for _, frame := range thread.Frames {
+		if frame.Language == pb.FrameLanguage_FRAME_PYTHON {
+			lang = pb.FrameLanguage_FRAME_PYTHON
+			break
+		}
+	}
+	for _, frame := range thread.Frames {
+		if frame.Language != lang {
+			continue
+		}
+		callPath = append(callPath, stackfmt.FrameName(frame))
+		texts = append(texts, stackfmt.FormatFrameWithoutLine(frame))
+	}
+	return callPath, texts
+}
+
+// First level (from 1) where the paths differ, or 0 when they are equal
+func divergeLevel(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i >= len(a) || i >= len(b) || a[i] != b[i] {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func frameAt(callPath []string, level int) string {
+	if level < 1 || level > len(callPath) {
+		return ""
+	}
+	return callPath[level-1]
+}
+
+// Order ranks by rank number, then node
+func sortRanks(ranks []RankRef) {
+	sort.Slice(ranks, func(i, j int) bool {
+		if ni, nj := rankNumber(ranks[i].Rank), rankNumber(ranks[j].Rank); ni != nj {
+			return ni < nj
+		}
+		return ranks[i].Node < ranks[j].Node
+	})
+}
+
+func rankNumber(rank string) int {
+	var n int
+	if _, err := fmt.Sscanf(strings.ToUpper(rank), "RANK%d", &n); err != nil {
+		return -1
+	}
+	return n
+}
+
+// Innermost frame of a call path
+func (g StackGroup) Top() string {
+	if len(g.CallPath) == 0 {
+		return ""
+	}
+	return g.CallPath[len(g.CallPath)-1]
+}
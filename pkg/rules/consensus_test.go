@@ -0,0 +1,150 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package rules
+
+import (
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func trainer(rank string, pid int32, frames ...*pb.StackFrame) *pb.ProcessInfo {
+	return &pb.ProcessInfo{
+		Pid:    pid,
+		Type:   pb.ProcessType_PROCESS_TRAINER,
+		Rank:   rank,
+		Status: pb.StackStatus_STACK_OK,
+		Threads: []*pb.ThreadStack{
+			{ThreadId: pid + 1, ThreadName: "pt_autograd_0"},
+			{ThreadId: pid, ThreadName: "python", Frames: frames},
+		},
+	}
+}
+
+func TestStackConsensus(t *testing.T) {
+	main := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 120, Function: "<module>", Module: "train"}
+	step := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step", Module: "train"}
+	allReduce := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "c10d.py", Line: 2050, Function: "all_reduce", Module: "torch.distributed.distributed_c10d"}
+	nccl := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "ncclGroupEnd", Module: "libnccl.so.2"}
+	yield := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "sched_yield", Module: "libc.so.6"}
+	next := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "dataloader.py", Line: 630, Function: "__next__", Module: "torch.utils.data.dataloader"}
+
+	nodes := []NodeProcesses{
+		{Node: "node1", Processes: []*pb.ProcessInfo{
+			trainer("RANK0", 100, main, step, allReduce, nccl),
+			// A different line and native frame is still the same call path
+			trainer("RANK1", 200, main, &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 90, Function: "step", Module: "train"}, allReduce, yield),
+			{Pid: 300, Type: pb.ProcessType_PROCESS_DATA_LOADER, Rank: "RANK0", Status: pb.StackStatus_STACK_OK},
+		}},
+		{Node: "node2", Processes: []*pb.ProcessInfo{
+			trainer("RANK3", 100, main, step, allReduce, nccl),
+			trainer("RANK2", 200, main, step, next),
+			{Pid: 300, Type: pb.ProcessType_PROCESS_TRAINER, Rank: "RANK4", Status: pb.StackStatus_STACK_TIMEOUT},
+		}},
+	}
+
+	report := StackConsensus(nodes, nil)
+	assert.Equal(t, 5, report.TotalRanks)
+	assert.Equal(t, 0, report.Majority)
+	assert.Equal(t, []StackGroup{
+		{
+			CallPath: []string{"train:<module>", "train:step", "torch.distributed.distributed_c10d:all_reduce"},
+			Ranks: []RankRef{
+				{Node: "node1", Rank: "RANK0", Pid: 100},
+				{Node: "node1", Rank: "RANK1", Pid: 200},
+				{Node: "node2", Rank: "RANK3", Pid: 100},
+			},
+		},
+		{
+			CallPath: []string{"train:<module>", "train:step", "torch.utils.data.dataloader:__next__"},
+			Ranks:    []RankRef{{Node: "node2", Rank: "RANK2", Pid: 200}},
+		},
+	}, report.Groups)
+	assert.Equal(t, []Outlier{
+		{
+			RankRef:       RankRef{Node: "node2", Rank: "RANK2", Pid: 200},
+			Group:         1,
+			DivergeLevel:  3,
+			Frame:         "torch.utils.data.dataloader:__next__",
+			MajorityFrame: "torch.distributed.distributed_c10d:all_reduce",
+		},
+	}, report.Outliers)
+	assert.Equal(t, []RankRef{{Node: "node2", Rank: "RANK4", Pid: 300}}, report.Skipped)
+}
+
+func TestStackConsensus_tie(t *testing.T) {
+	a := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "a.py", Function: "a"}
+	b := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "b.py", Function: "b"}
+	report := StackConsensus([]NodeProcesses{{Node: "node1", Processes: []*pb.ProcessInfo{
+		trainer("RANK0", 100, a),
+		trainer("RANK1", 200, b),
+	}}}, nil)
+	assert.Equal(t, -1, report.Majority)
+	assert.Len(t, report.Groups, 2)
+	assert.Empty(t, report.Outliers)
+}
+
+func TestStackConsensus_normalized(t *testing.T) {
+	main := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Function: "<module>", Module: "train"}
+	hook := func(function string) *pb.StackFrame {
+		return &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/usr/lib/python3/site-packages/torch/nn/modules/module.py", Function: function, Module: "torch.nn.modules.module"}
+	}
+	allReduce := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "c10d.py", Function: "all_reduce", Module: "torch.distributed.distributed_c10d"}
+
+	// The main thread of RANK1 is ignored, its deepest remaining thread is compared
+	rank1 := trainer("RANK1", 200, main, hook("_call_impl"), allReduce)
+	rank1.Threads[0].Frames = []*pb.StackFrame{main, hook("_wrapped_call_impl"), hook("_call_impl"), allReduce}
+	rank1.Threads[1].ThreadName = "TMonitor"
+	rank1.Threads[1].Frames = []*pb.StackFrame{main}
+	nodes := []NodeProcesses{{Node: "node1", Processes: []*pb.ProcessInfo{
+		trainer("RANK0", 100, main, hook("_wrapped_call_impl"), hook("_call_impl"), allReduce),
+		rank1,
+	}}}
+
+	n, err := NewNormalizer(NormalizeConfig{
+		CollapseFrames: []string{`torch/nn/modules/module\.py`},
+		IgnoreThreads:  []string{`^TMonitor$`},
+	})
+	assert.NoError(t, err)
+	report := StackConsensus(nodes, n)
+	assert.Equal(t, 0, report.Majority)
+	assert.Equal(t, []StackGroup{{
+		CallPath: []string{"train:<module>", `<frames matching torch/nn/modules/module\.py>`, "torch.distributed.distributed_c10d:all_reduce"},
+		Ranks: []RankRef{
+			{Node: "node1", Rank: "RANK0", Pid: 100},
+			{Node: "node1", Rank: "RANK1", Pid: 200},
+		},
+	}}, report.Groups)
+	assert.Len(t, StackConsensus(nodes, nil).Groups, 2)
+}
+
+func TestCallPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		thread *pb.ThreadStack
+		want   []string
+	}{
+		{
+			name: "native only",
+			thread: &pb.ThreadStack{Frames: []*pb.StackFrame{
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "start_thread", File: "./nptl/pthread_create.c", Line: 442},
+				{Language: pb.FrameLanguage_FRAME_NATIVE, Function: "epoll_wait", Module: "libc.so.6"},
+			}},
+			want: []string{"pthread_create.c:start_thread", "libc.so.6:epoll_wait"},
+		},
+		{
+			name: "legacy string frames",
+			thread: &pb.ThreadStack{StackFrames: []string{
+				"File \"/usr/lib/python3.12/threading.py\", line 1030, in _bootstrap\nself._bootstrap_inner()",
+			}},
+			want: []string{"threading.py:_bootstrap"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CallPath(tt.thread, nil))
+		})
+	}
+}
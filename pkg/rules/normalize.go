@@ -0,0 +1,144 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package rules
+
+import (
+	"fmt"
+	"regexp"
+
+	"deeptrace/pkg/stackfmt"
+	pb "deeptrace/v1"
+)
+
+// NormalizeConfig controls which stack differences are ignored when comparing stacks over
+// time, loaded from the client config
+type NormalizeConfig struct {
+	// Compare frames without their line numbers, e.g. for polling loops
+	IgnoreLineNumbers bool `mapstructure:"ignore-line-numbers"`
+	// Consecutive frames matching one of the patterns are collapsed into a single frame
+	CollapseFrames []string `mapstructure:"collapse-frames"`
+	// Threads whose name matches one of the patterns are not compared
+	IgnoreThreads []string `mapstructure:"ignore-threads"`
+	// Compare only the innermost frames, 0 compares all
+	TopFrames int `mapstructure:"top-frames"`
+}
+
+// Normalizer turns thread stacks into the frames that are compared, a nil Normalizer
+// compares stacks as they are
+type Normalizer struct {
+	ignoreLineNumbers bool
+	collapseFrames    []*regexp.Regexp
+	ignoreThreads     []*regexp.Regexp
+	topFrames         int
+}
+
+// NewNormalizer compiles the patterns of the config
+func NewNormalizer(cfg NormalizeConfig) (*Normalizer, error) {
+	if cfg.TopFrames < 0 {
+		return nil, fmt.Errorf("top-frames must not be negative")
+	}
+	n := &Normalizer{ignoreLineNumbers: cfg.IgnoreLineNumbers, topFrames: cfg.TopFrames}
+	for _, pattern := range cfg.CollapseFrames {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collapse-frames pattern %q: %v", pattern, err)
+		}
+		n.collapseFrames = append(n.collapseFrames, reg)
+	}
+	for _, pattern := range cfg.IgnoreThreads {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore-threads pattern %q: %v", pattern, err)
+		}
+		n.ignoreThreads = append(n.ignoreThreads, reg)
+	}
+	return n, nil
+}
+
+// IgnoreThread reports whether the thread is left out of comparisons
+func (n *Normalizer) IgnoreThread(thread *pb.ThreadStack) bool {
+	if n == nil {
+		return false
+	}
+	for _, reg := range n.ignoreThreads {
+		if reg.MatchString(thread.ThreadName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Threads that are compared
+func (n *Normalizer) Threads(threads []*pb.ThreadStack) []*pb.ThreadStack {
+	if n == nil || len(n.ignoreThreads) == 0 {
+		return threads
+	}
+	kept := make([]*pb.ThreadStack, 0, len(threads))
+	for _, thread := range threads {
+		if !n.IgnoreThread(thread) {
+			kept = append(kept, thread)
+		}
+	}
+	return kept
+}
+
+// Frames returns the frames of a thread to compare, outermost first
+func (n *Normalizer) Frames(thread *pb.ThreadStack) []string {
+	frames := stackfmt.ThreadFrames(thread)
+	if n == nil {
+		return frames
+	}
+	if n.ignoreLineNumbers {
+		if len(thread.Frames) > 0 {
+			for i, frame := range thread.Frames {
+				frames[i] = stackfmt.FormatFrameWithoutLine(frame)
+			}
+		} else {
+			withoutLines := make([]string, 0, len(frames))
+			for _, frame := range frames {
+				withoutLines = append(withoutLines, legacyLineRegexp.ReplaceAllString(frame, "$1$2"))
+			}
+			frames = withoutLines
+		}
+	}
+	return n.reduce(frames, frames)
+}
+
+// Collapse consecutive frames whose text matches the same pattern, then keep the
+// innermost frames
+func (n *Normalizer) reduce(frames, texts []string) []string {
+	if len(n.collapseFrames) > 0 {
+		collapsed := make([]string, 0, len(frames))
+		var last *regexp.Regexp
+		for i, frame := range frames {
+			reg := n.collapsePattern(texts[i])
+			if reg != nil && reg == last {
+				continue
+			}
+			last = reg
+			if reg != nil {
+				frame = fmt.Sprintf("<frames matching %s>", reg)
+			}
+			collapsed = append(collapsed, frame)
+		}
+		frames = collapsed
+	}
+
+	if n.topFrames > 0 && len(frames) > n.topFrames {
+		frames = frames[len(frames)-n.topFrames:]
+	}
+	return frames
+}
+
+// Line number of a legacy frame: File "<path>", line <n>, in <function>\n<code>. The
+// source line goes too as it changes with the line.
+var legacyLineRegexp = regexp.MustCompile(`^(File ".*"), line \d+(, in [^\n]*)(?s:\n.*)?$`)
+
+func (n *Normalizer) collapsePattern(frame string) *regexp.Regexp {
+	for _, reg := range n.collapseFrames {
+		if reg.MatchString(frame) {
+			return reg
+		}
+	}
+	return nil
+}
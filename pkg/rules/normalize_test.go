@@ -0,0 +1,100 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package rules
+
+import (
+	"context"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizer_Frames(t *testing.T) {
+	thread := &pb.ThreadStack{
+		Frames: []*pb.StackFrame{
+			{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 120, Function: "<module>"},
+			{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/torch/nn/modules/module.py", Line: 1736, Function: "_wrapped_call_impl"},
+			{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/torch/nn/modules/module.py", Line: 1747, Function: "_call_impl"},
+			{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/model.py", Line: 42, Function: "forward"},
+			{Language: pb.FrameLanguage_FRAME_NATIVE, File: "???", Function: "ncclGroupEnd", Module: "libnccl.so.2"},
+		},
+	}
+	tests := []struct {
+		name   string
+		cfg    NormalizeConfig
+		thread *pb.ThreadStack
+		want   []string
+	}{
+		{
+			name:   "no normalization",
+			thread: thread,
+			want: []string{
+				`File "/workspace/train.py", line 120, in <module>`,
+				`File "/torch/nn/modules/module.py", line 1736, in _wrapped_call_impl`,
+				`File "/torch/nn/modules/module.py", line 1747, in _call_impl`,
+				`File "/workspace/model.py", line 42, in forward`,
+				`(C) File "???", line 0, in ncclGroupEnd (libnccl.so.2)`,
+			},
+		},
+		{
+			name:   "ignore line numbers, collapse and top frames",
+			cfg:    NormalizeConfig{IgnoreLineNumbers: true, CollapseFrames: []string{`torch/nn/modules/`}, TopFrames: 3},
+			thread: thread,
+			want: []string{
+				`<frames matching torch/nn/modules/>`,
+				`File "/workspace/model.py", in forward`,
+				`(C) File "???", in ncclGroupEnd (libnccl.so.2)`,
+			},
+		},
+		{
+			name: "legacy frames without line numbers",
+			cfg:  NormalizeConfig{IgnoreLineNumbers: true},
+			thread: &pb.ThreadStack{StackFrames: []string{
+				"File \"/usr/lib/python3.12/threading.py\", line 355, in wait\nwaiter.acquire()",
+			}},
+			want: []string{`File "/usr/lib/python3.12/threading.py", in wait`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNormalizer(tt.cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, n.Frames(tt.thread))
+		})
+	}
+}
+
+func TestNewNormalizer_invalid(t *testing.T) {
+	_, err := NewNormalizer(NormalizeConfig{IgnoreThreads: []string{"("}})
+	assert.Error(t, err)
+	_, err = NewNormalizer(NormalizeConfig{TopFrames: -1})
+	assert.Error(t, err)
+}
+
+func TestThreadsStacksEqual_normalized(t *testing.T) {
+	n, err := NewNormalizer(NormalizeConfig{
+		IgnoreLineNumbers: true,
+		IgnoreThreads:     []string{`_pin_memory_loop`, `^TMonitor`},
+	})
+	assert.NoError(t, err)
+
+	poll := func(line int32) *pb.ThreadStack {
+		return &pb.ThreadStack{ThreadId: 10, ThreadName: "python", Frames: []*pb.StackFrame{
+			{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: line, Function: "poll"},
+		}}
+	}
+	a := &pb.ProcessInfo{Pid: 10, Rank: "RANK0", Threads: []*pb.ThreadStack{
+		poll(30),
+		{ThreadId: 11, ThreadName: "Thread-3 (_pin_memory_loop)"},
+	}}
+	b := &pb.ProcessInfo{Pid: 10, Rank: "RANK0", Threads: []*pb.ThreadStack{
+		poll(32),
+		{ThreadId: 12, ThreadName: "TMonitor"},
+	}}
+
+	equal, diff, err := ThreadsStacksEqual(context.TODO(), n, "time1", "time2", a, b)
+	assert.NoError(t, err)
+	assert.True(t, equal, diff)
+}
@@ -0,0 +1,325 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package rules
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	pb "deeptrace/v1"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in signatures, shipped with the client
+//
+//go:embed signatures.yaml
+var builtinSignatures []byte
+
+// Signature is a known failure recognized from log lines or stack frames
+type Signature struct {
+	Name        string `yaml:"name" json:"name"`
+	Category    string `yaml:"category" json:"category"`
+	Severity    string `yaml:"severity" json:"severity"`
+	Description string `yaml:"description" json:"description"`
+	// Any log line matching one of the patterns
+	LogPatterns []string `yaml:"log_patterns" json:"-"`
+	// A thread with a frame matching each of the patterns
+	StackPatterns []string `yaml:"stack_patterns" json:"-"`
+	Remediation   string   `yaml:"remediation" json:"remediation"`
+	// Removes a signature of the same name loaded before
+	Disabled bool `yaml:"disabled" json:"-"`
+}
+
+type signatureFile struct {
+	Signatures []Signature `yaml:"signatures"`
+}
+
+// Sources of a signature match
+const (
+	MatchSourceLog   = "log"
+	MatchSourceStack = "stack"
+)
+
+// SignatureMatch is a signature found in the log or the stacks of a rank
+type SignatureMatch struct {
+	Signature *Signature `json:"signature"`
+	Source    string     `json:"source"`
+	// Matching log line, or the matching frames of the thread
+	Evidence string `json:"evidence"`
+}
+
+type compiledSignature struct {
+	*Signature
+	logPatterns   []*regexp.Regexp
+	stackPatterns []*regexp.Regexp
+}
+
+// SignatureLibrary matches log lines and stacks against known failure signatures
+type SignatureLibrary struct {
+	signatures []compiledSignature
+}
+
+// ParseSignatures reads signatures from YAML
+func ParseSignatures(data []byte) ([]Signature, error) {
+	var file signatureFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Signatures, nil
+}
+
+// NewSignatureLibrary compiles the signatures. A later signature replaces an earlier one of
+// the same name.
+func NewSignatureLibrary(sigs []Signature) (*SignatureLibrary, error) {
+	var names []string
+	byName := make(map[string]*Signature)
+	for i := range sigs {
+		if sigs[i].Name == "" {
+			return nil, fmt.Errorf("signature %d has no name", i+1)
+		}
+		if _, ok := byName[sigs[i].Name]; !ok {
+			names = append(names, sigs[i].Name)
+		}
+		byName[sigs[i].Name] = &sigs[i]
+	}
+
+	l := &SignatureLibrary{}
+	for _, name := range names {
+		sig := byName[name]
+		if sig.Disabled {
+			continue
+		}
+		compiled, err := compileSignature(sig)
+		if err != nil {
+			return nil, err
+		}
+		l.signatures = append(l.signatures, compiled)
+	}
+	return l, nil
+}
+
+func compileSignature(sig *Signature) (compiledSignature, error) {
+	sig.Severity = strings.ToUpper(sig.Severity)
+	if sig.Severity == "" {
+		sig.Severity = pb.Severity_WARNING.String()
+	}
+	if _, ok := pb.Severity_value[sig.Severity]; !ok {
+		return compiledSignature{}, fmt.Errorf("signature %s: invalid severity %q", sig.Name, sig.Severity)
+	}
+	if len(sig.LogPatterns) == 0 && len(sig.StackPatterns) == 0 {
+		return compiledSignature{}, fmt.Errorf("signature %s has no log_patterns or stack_patterns", sig.Name)
+	}
+	c := compiledSignature{Signature: sig}
+	for _, pattern := range sig.LogPatterns {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledSignature{}, fmt.Errorf("signature %s: invalid log pattern %q: %v", sig.Name, pattern, err)
+		}
+		c.logPatterns = append(c.logPatterns, reg)
+	}
+	for _, pattern := range sig.StackPatterns {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledSignature{}, fmt.Errorf("signature %s: invalid stack pattern %q: %v", sig.Name, pattern, err)
+		}
+		c.stackPatterns = append(c.stackPatterns, reg)
+	}
+	return c, nil
+}
+
+// LoadSignatures builds a library of the built-in signatures extended by the files
+func LoadSignatures(files []string) (*SignatureLibrary, error) {
+	sigs, err := ParseSignatures(builtinSignatures)
+	if err != nil {
+		return nil, fmt.Errorf("built-in signatures: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		extra, err := ParseSignatures(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		sigs = append(sigs, extra...)
+	}
+	return NewSignatureLibrary(sigs)
+}
+
+// MatchLog returns the signatures found in the log lines of a rank, each once with the
+// first matching line
+func (l *SignatureLibrary) MatchLog(lines []string) []SignatureMatch {
+	var matches []SignatureMatch
+	for _, sig := range l.signatures {
+	lines:
+		for _, line := range lines {
+			for _, reg := range sig.logPatterns {
+				if reg.MatchString(line) {
+					matches = append(matches, SignatureMatch{Signature: sig.Signature, Source: MatchSourceLog, Evidence: strings.TrimSpace(line)})
+					break lines
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// MatchStacks returns the signatures found in the threads of the processes of a rank, each
+// once with the frames of the first matching thread
+func (l *SignatureLibrary) MatchStacks(processes []*pb.ProcessInfo) []SignatureMatch {
+	var matches []SignatureMatch
+	for _, sig := range l.signatures {
+		if len(sig.stackPatterns) == 0 {
+			continue
+		}
+	threads:
+		for _, proc := range processes {
+			for _, thread := range proc.Threads {
+				if frames, ok := matchThread(sig.stackPatterns, thread); ok {
+					matches = append(matches, SignatureMatch{Signature: sig.Signature, Source: MatchSourceStack, Evidence: strings.Join(frames, ", ")})
+					break threads
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// Innermost frame of the thread matching each pattern, in pattern order
+func matchThread(patterns []*regexp.Regexp, thread *pb.ThreadStack) ([]string, bool) {
+	frames := frameTexts(thread)
+	matched := make([]string, 0, len(patterns))
+	for _, reg := range patterns {
+		found := false
+		for i := len(frames) - 1; i >= 0; i-- {
+			if reg.MatchString(frames[i]) {
+				matched = append(matched, frames[i])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return matched, true
+}
+
+// Frames of a thread as "<file>:<function>", outermost first
+func frameTexts(thread *pb.ThreadStack) []string {
+	if len(thread.Frames) == 0 {
+		texts := make([]string, 0, len(thread.StackFrames))
+		for _, frame := range thread.StackFrames {
+			// Legacy frames: File "<path>", line <n>, in <function>\n<code>
+			frame, _, _ = strings.Cut(frame, "\n")
+			if file, rest, ok := strings.Cut(frame, ", line "); ok {
+				if _, function, ok := strings.Cut(rest, ", in "); ok {
+					frame = strings.Trim(strings.TrimPrefix(file, "File "), `"`) + ":" + function
+				}
+			}
+			texts = append(texts, frame)
+		}
+		return texts
+	}
+	texts := make([]string, 0, len(thread.Frames))
+	for _, frame := range thread.Frames {
+		file := frame.File
+		if file == "" || file == "???" {
+			file = frame.Module
+		}
+		texts = append(texts, file+":"+frame.Function)
+	}
+	return texts
+}
+
+// RankSignatures are the signatures matched on one rank
+type RankSignatures struct {
+	Node    string           `json:"node"`
+	Rank    string           `json:"rank"`
+	Matches []SignatureMatch `json:"matches"`
+}
+
+// Text renders the matches of the rank with their remediation
+func (r RankSignatures) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s on %s:\n", r.Rank, r.Node)
+	for _, m := range r.Matches {
+		fmt.Fprintf(&b, "  [%s] %s (%s, from %s): %s\n", m.Signature.Severity, m.Signature.Name, m.Signature.Category, m.Source, m.Signature.Description)
+		fmt.Fprintf(&b, "    Evidence: %s\n", m.Evidence)
+		if m.Signature.Remediation != "" {
+			fmt.Fprintf(&b, "    Remediation: %s\n", m.Signature.Remediation)
+		}
+	}
+	return b.String()
+}
+
+// SignatureReport collects the signatures matched on the ranks of a job, safe for
+// concurrent use
+type SignatureReport struct {
+	mu    sync.Mutex
+	ranks map[RankRef]*RankSignatures
+}
+
+// NewSignatureReport creates an empty report
+func NewSignatureReport() *SignatureReport {
+	return &SignatureReport{ranks: make(map[RankRef]*RankSignatures)}
+}
+
+// Add records the matches of a rank, a signature is kept once per rank
+func (r *SignatureReport) Add(node, rank string, matches []SignatureMatch) {
+	if len(matches) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ref := RankRef{Node: node, Rank: rank}
+	rs, ok := r.ranks[ref]
+	if !ok {
+		rs = &RankSignatures{Node: node, Rank: rank}
+		r.ranks[ref] = rs
+	}
+	for _, m := range matches {
+		known := false
+		for _, prev := range rs.Matches {
+			known = known || prev.Signature.Name == m.Signature.Name
+		}
+		if !known {
+			rs.Matches = append(rs.Matches, m)
+		}
+	}
+}
+
+// Ranks returns the ranks with matches, ordered by rank number then node
+func (r *SignatureReport) Ranks() []RankSignatures {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	refs := make([]RankRef, 0, len(r.ranks))
+	for ref := range r.ranks {
+		refs = append(refs, ref)
+	}
+	sortRanks(refs)
+	ranks := make([]RankSignatures, 0, len(refs))
+	for _, ref := range refs {
+		ranks = append(ranks, *r.ranks[ref])
+	}
+	return ranks
+}
+
+// AddStacks matches the stacks of every rank of the nodes and records the matches
+func (r *SignatureReport) AddStacks(l *SignatureLibrary, nodes []NodeProcesses) {
+	for _, node := range nodes {
+		byRank := make(map[string][]*pb.ProcessInfo)
+		for _, proc := range node.Processes {
+			byRank[proc.Rank] = append(byRank[proc.Rank], proc)
+		}
+		for rank, processes := range byRank {
+			r.Add(node.Node, rank, l.MatchStacks(processes))
+		}
+	}
+}
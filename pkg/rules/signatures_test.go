@@ -0,0 +1,168 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func matchNames(matches []SignatureMatch) []string {
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Signature.Name)
+	}
+	return names
+}
+
+func loadBuiltinSignatures(t *testing.T) *SignatureLibrary {
+	l, err := LoadSignatures(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSignatureLibrary_MatchLog(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name:  "healthy",
+			lines: []string{"[XTuner][RANK 0][2025-07-11 02:32:52][INFO] [Step 10/1000] loss: 2.3"},
+		},
+		{
+			name: "NCCL timeout",
+			lines: []string{
+				"[E ProcessGroupNCCL.cpp:563] [Rank 1] Watchdog caught collective operation timeout: WorkNCCL(SeqNum=1201, OpType=ALLREDUCE) ran for 600000 milliseconds before timing out.",
+				"[E ProcessGroupNCCL.cpp:577] [Rank 1] Some NCCL operations have failed or timed out.",
+			},
+			want: []string{"nccl_timeout"},
+		},
+		{
+			name: "dataloader worker killed by shared memory",
+			lines: []string{
+				"ERROR: Unexpected bus error encountered in worker. This might be caused by insufficient shared memory (shm).",
+				"RuntimeError: DataLoader worker (pid 4211) is killed by signal: Bus error.",
+			},
+			want: []string{"dataloader_worker_killed", "shm_exhausted"},
+		},
+		{
+			name:  "CUDA OOM",
+			lines: []string{"torch.OutOfMemoryError: CUDA out of memory. Tried to allocate 2.00 GiB."},
+			want:  []string{"cuda_oom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchNames(loadBuiltinSignatures(t).MatchLog(tt.lines)))
+		})
+	}
+}
+
+func TestSignatureLibrary_MatchStacks(t *testing.T) {
+	thread := func(frames ...*pb.StackFrame) *pb.ProcessInfo {
+		return &pb.ProcessInfo{Pid: 100, Type: pb.ProcessType_PROCESS_TRAINER, Threads: []*pb.ThreadStack{{ThreadId: 100, Frames: frames}}}
+	}
+	train := &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Function: "<module>"}
+	tests := []struct {
+		name      string
+		processes []*pb.ProcessInfo
+		want      []string
+	}{
+		{
+			name:      "training",
+			processes: []*pb.ProcessInfo{thread(train, &pb.StackFrame{File: "/workspace/model.py", Function: "forward"})},
+		},
+		{
+			name: "blocked in torch.save",
+			processes: []*pb.ProcessInfo{thread(train,
+				&pb.StackFrame{File: "/usr/lib/python3/site-packages/torch/serialization.py", Function: "save"},
+				&pb.StackFrame{File: "/usr/lib/python3/site-packages/torch/serialization.py", Function: "_save"},
+			)},
+			want: []string{"checkpoint_fs_stall"},
+		},
+		{
+			name: "legacy frames waiting in all_reduce",
+			processes: []*pb.ProcessInfo{{Pid: 100, Threads: []*pb.ThreadStack{{StackFrames: []string{
+				"File \"/workspace/train.py\", line 10, in <module>\nmain()",
+				"File \"/torch/distributed/distributed_c10d.py\", line 2501, in all_reduce\nwork.wait()",
+			}}}}},
+			want: []string{"collective_wait"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchNames(loadBuiltinSignatures(t).MatchStacks(tt.processes)))
+		})
+	}
+}
+
+func TestLoadSignatures(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "signatures.yaml")
+	os.WriteFile(file, []byte(`signatures:
+  - name: cuda_oom
+    disabled: true
+  - name: nccl_timeout
+    category: communication
+    severity: warning
+    log_patterns: ['Watchdog caught collective operation timeout']
+    remediation: Ask the network team
+  - name: lustre_stall
+    category: storage
+    log_patterns: ['LustreError']
+`), 0644)
+	l, err := LoadSignatures([]string{file})
+	assert.NoError(t, err)
+
+	matches := l.MatchLog([]string{
+		"torch.OutOfMemoryError: CUDA out of memory.",
+		"Watchdog caught collective operation timeout",
+		"LustreError: 11-0: fs-OST0004: operation ost_write failed",
+	})
+	assert.Equal(t, []string{"nccl_timeout", "lustre_stall"}, matchNames(matches))
+	assert.Equal(t, "WARNING", matches[0].Signature.Severity)
+	assert.Equal(t, "Ask the network team", matches[0].Signature.Remediation)
+	assert.Equal(t, "WARNING", matches[1].Signature.Severity)
+
+	invalid := []string{
+		"signatures:\n  - name: bad\n    severity: FATAL\n    log_patterns: ['x']\n",
+		"signatures:\n  - name: bad\n    log_patterns: ['(']\n",
+		"signatures:\n  - name: bad\n",
+		"signatures:\n  - category: memory\n    log_patterns: ['x']\n",
+	}
+	for _, content := range invalid {
+		os.WriteFile(file, []byte(content), 0644)
+		_, err := LoadSignatures([]string{file})
+		assert.Error(t, err, content)
+	}
+	_, err = LoadSignatures([]string{filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestSignatureReport(t *testing.T) {
+	report := NewSignatureReport()
+	logMatches := loadBuiltinSignatures(t).MatchLog([]string{"Watchdog caught collective operation timeout"})
+	report.Add("node2", "RANK10", logMatches)
+	report.Add("node1", "RANK2", logMatches)
+	report.Add("node1", "RANK2", logMatches)
+	report.Add("node1", "RANK3", nil)
+	report.AddStacks(loadBuiltinSignatures(t), []NodeProcesses{{Node: "node1", Processes: []*pb.ProcessInfo{
+		{Pid: 100, Rank: "RANK2", Threads: []*pb.ThreadStack{{Frames: []*pb.StackFrame{{File: "/torch/distributed/distributed_c10d.py", Function: "barrier"}}}}},
+	}}})
+
+	ranks := report.Ranks()
+	assert.Len(t, ranks, 2)
+	assert.Equal(t, RankRef{Node: "node1", Rank: "RANK2"}, RankRef{Node: ranks[0].Node, Rank: ranks[0].Rank})
+	assert.Equal(t, []string{"nccl_timeout", "collective_wait"}, matchNames(ranks[0].Matches))
+	assert.Equal(t, "RANK10", ranks[1].Rank)
+	assert.Contains(t, ranks[0].Text(), "[CRITICAL] nccl_timeout (communication, from log)")
+	assert.Contains(t, ranks[0].Text(), "Evidence: /torch/distributed/distributed_c10d.py:barrier")
+}
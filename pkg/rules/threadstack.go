@@ -6,96 +6,185 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"deeptrace/logger"
 	pb "deeptrace/v1"
 )
 
-// Comparison of process stack information for all training processes on a node at different times
-func PstreeEqual(ctx context.Context, time1, time2 string, psa, psb []*pb.ProcessInfo) (bool, []ProccessInfoDiff, error) {
-	if len(psa) != len(psb) {
-		return false, nil, fmt.Errorf("process count mismatch: psa: %d, psb: %d", len(psa), len(psb))
+// Comparison of process stack information for all training processes on a node at different
+// times, after normalization by n. Processes are matched by PID and only those dumped in both
+// samples are compared, the PIDs of the others are returned as undumpable: a hung process
+// often times out in one of the dumps.
+func PstreeEqual(ctx context.Context, n *Normalizer, time1, time2 string, psa, psb []*pb.ProcessInfo) (equal bool, diff []ProccessInfoDiff, undumpable []int32, err error) {
+	byPid := make(map[int32]*pb.ProcessInfo, len(psb))
+	for _, proc := range psb {
+		byPid[proc.Pid] = proc
 	}
-	sort.Slice(psa, func(i, j int) bool { return psa[i].Pid < psa[j].Pid })
-	sort.Slice(psb, func(i, j int) bool { return psb[i].Pid < psb[j].Pid })
-	equal := true
-	diff := []ProccessInfoDiff{}
-
-	for i := 0; i < len(psa); i++ {
+	seen := make(map[int32]bool, len(psa))
+	var pairs [][2]*pb.ProcessInfo
+	for _, proc := range psa {
+		seen[proc.Pid] = true
+		other, ok := byPid[proc.Pid]
+		if !ok || !stackDumped(proc) || !stackDumped(other) {
+			undumpable = append(undumpable, proc.Pid)
+			continue
+		}
+		pairs = append(pairs, [2]*pb.ProcessInfo{proc, other})
+	}
+	for _, proc := range psb {
+		if !seen[proc.Pid] {
+			undumpable = append(undumpable, proc.Pid)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0].Pid < pairs[j][0].Pid })
+	sort.Slice(undumpable, func(i, j int) bool { return undumpable[i] < undumpable[j] })
 
-		eq, dif, err := ThreadsStacksEqual(ctx, time1, time2, psa[i], psb[i])
+	equal = true
+	diff = []ProccessInfoDiff{}
+	for _, pair := range pairs {
+		diffs, err := DiffThreadsStacks(ctx, n, pair[0], pair[1])
 		if err != nil {
-			return false, nil, err
+			return false, nil, nil, err
 		}
-		if !eq {
+		if len(diffs) > 0 {
 			equal = false
-			diff = append(diff, ProccessInfoDiff{
-				Rank:  psa[i].Rank,
-				Diff:  dif,
-				PType: psa[i].Type,
-			})
+			d := ProccessInfoDiff{
+				Rank:  pair[0].Rank,
+				PType: pair[0].Type,
+				Pid:   pair[0].Pid,
+				Time1: time1,
+				Time2: time2,
+				Diffs: diffs,
+			}
+			d.Diff = d.Text()
+			diff = append(diff, d)
 		}
 	}
 
-	return equal, diff, nil
+	return equal, diff, undumpable, nil
+}
+
+// Whether the stacks of a process were dumped, agents before stack statuses set none
+func stackDumped(proc *pb.ProcessInfo) bool {
+	return proc.Status == pb.StackStatus_STACK_OK || proc.Status == pb.StackStatus_STACK_UNSPECIFIED
+}
+
+// ThreadsStacksEqual compares the stacks of a process at two times, diff describes every difference
+func ThreadsStacksEqual(ctx context.Context, n *Normalizer, time1, time2 string, proccessa, proccessb *pb.ProcessInfo) (equal bool, diff string, err error) {
+	diffs, err := DiffThreadsStacks(ctx, n, proccessa, proccessb)
+	if err != nil {
+		return false, "", err
+	}
+	if len(diffs) == 0 {
+		return true, "", nil
+	}
+	d := ProccessInfoDiff{
+		Rank:  proccessa.Rank,
+		PType: proccessa.Type,
+		Pid:   proccessa.Pid,
+		Time1: time1,
+		Time2: time2,
+		Diffs: diffs,
+	}
+	return false, d.Text(), nil
 }
 
-func ThreadsStacksEqual(ctx context.Context, time1, time2 string, proccessa, proccessb *pb.ProcessInfo) (equal bool, diff string, err error) {
+// DiffThreadsStacks returns every difference between the stacks of a process at two
+// times, after normalization by n. Threads are matched by ID.
+func DiffThreadsStacks(ctx context.Context, n *Normalizer, proccessa, proccessb *pb.ProcessInfo) ([]StackDiff, error) {
 	if proccessa == nil || proccessb == nil {
 		logger.Logger.Error("process info is nil")
-		return false, "", fmt.Errorf("process info is empty")
+		return nil, fmt.Errorf("process info is empty")
 	}
 
 	if !strings.EqualFold(proccessa.Rank, proccessb.Rank) {
-		return false, "", fmt.Errorf("process ranks are different: %s and %s", proccessa.Rank, proccessb.Rank)
+		return nil, fmt.Errorf("process ranks are different: %s and %s", proccessa.Rank, proccessb.Rank)
 	}
 
 	if proccessa.Pid != proccessb.Pid {
-		return false, "", fmt.Errorf("internal error, comparing two different processes. pid: %d and %d", proccessa.Pid, proccessb.Pid)
+		return nil, fmt.Errorf("internal error, comparing two different processes. pid: %d and %d", proccessa.Pid, proccessb.Pid)
 	}
 
-	if len(proccessa.Threads) != len(proccessb.Threads) {
-		header := fmt.Sprintf("Detected different number of threads in process stack. %s, Process type: %s, Process ID:%d\n", proccessa.Rank, proccessa.Type.String(), proccessa.Pid)
-		before := fmt.Sprintf("[%s]Thread count: %d\n", time1, len(proccessa.Threads))
-		after := fmt.Sprintf("[%s]Thread count: %d\n", time2, len(proccessb.Threads))
-		return false, header + before + after, nil
+	// Threads ignored by the normalization are left out
+	threadsa, threadsb := n.Threads(proccessa.Threads), n.Threads(proccessb.Threads)
+
+	diffs := []StackDiff{}
+	if len(threadsa) != len(threadsb) {
+		diffs = append(diffs, StackDiff{
+			Kind:   DiffThreadCount,
+			Before: strconv.Itoa(len(threadsa)),
+			After:  strconv.Itoa(len(threadsb)),
+		})
 	}
 
-	// threads sort
-		sort.Slice(proccessa.Threads, func(i, j int) bool {
-		return proccessa.Threads[i].ThreadId < proccessa.Threads[j].ThreadId
-	})
-	sort.Slice(proccessb.Threads, func(i, j int) bool {
-		return proccessb.Threads[i].ThreadId < proccessb.Threads[j].ThreadId
-	})
-
-	for i := 0; i < len(proccessa.Threads); i++ {
-		ta, tb := proccessa.Threads[i], proccessb.Threads[i]
-		if ta.ThreadId != tb.ThreadId {
-			header := fmt.Sprintf("Detected different thread IDs. %s, Process type: %s, Process ID:%d\n", proccessa.Rank, proccessa.Type.String(), proccessa.Pid)
-			before := fmt.Sprintf("[%s]Thread ID: %d\n", time1, ta.ThreadId)
-			after := fmt.Sprintf("[%s]Thread ID: %d\n", time2, tb.ThreadId)
-			return false, header + before + after, nil
+	byIDb := make(map[int32]*pb.ThreadStack, len(threadsb))
+	for _, t := range threadsb {
+		byIDb[t.ThreadId] = t
+	}
+	byIDa := make(map[int32]*pb.ThreadStack, len(threadsa))
+	ids := make([]int32, 0, len(threadsa)+len(threadsb))
+	for _, t := range threadsa {
+		byIDa[t.ThreadId] = t
+		ids = append(ids, t.ThreadId)
+	}
+	for _, t := range threadsb {
+		if _, ok := byIDa[t.ThreadId]; !ok {
+			ids = append(ids, t.ThreadId)
 		}
-		if len(ta.StackFrames) != len(tb.StackFrames) {
-			header := fmt.Sprintf("Detected different number of thread stack frames. %s, Process type: %s, Process ID:%d, Thread ID: %d\n", proccessa.Rank, proccessa.Type.String(), proccessa.Pid, ta.ThreadId)
-			before := fmt.Sprintf("[%s]Frame count: %d\n", time1, len(ta.StackFrames))
-			after := fmt.Sprintf("[%s]Frame count: %d\n", time2, len(tb.StackFrames))
-			return false, header + before + after, nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		ta, tb := byIDa[id], byIDb[id]
+		if ta == nil || tb == nil {
+			// The thread started or exited between the two times
+			d := StackDiff{Kind: DiffThreadSet, ThreadID: id}
+			if ta != nil {
+				d.ThreadName, d.Before = ta.ThreadName, threadLabel(ta)
+			} else {
+				d.ThreadName, d.After = tb.ThreadName, threadLabel(tb)
+			}
+			diffs = append(diffs, d)
+			continue
 		}
 
-		var flevel int32
-		for flevel = 0; flevel < int32(len(ta.StackFrames)); flevel++ {
-			if !strings.EqualFold(ta.StackFrames[flevel], tb.StackFrames[flevel]) {
-				header := fmt.Sprintf("Detected different process stacks. %s, Process type: %s, Process ID:%d, Thread ID: %d, Stack frame level: %d\n", proccessa.Rank, proccessa.Type.String(), proccessa.Pid, ta.ThreadId, flevel+1)
-				before := fmt.Sprintf("[%s]Stack frame content: %s\n", time1, ta.StackFrames[flevel])
-				after := fmt.Sprintf("[%s]Stack frame content: %s\n", time2, tb.StackFrames[flevel])
-
-				return false, header + before + after, nil
+		framesa, framesb := n.Frames(ta), n.Frames(tb)
+		if len(framesa) != len(framesb) {
+			diffs = append(diffs, StackDiff{
+				Kind:       DiffFrameCount,
+				ThreadID:   id,
+				ThreadName: ta.ThreadName,
+				Before:     strconv.Itoa(len(framesa)),
+				After:      strconv.Itoa(len(framesb)),
+			})
+		}
+		for level := 0; level < len(framesa) || level < len(framesb); level++ {
+			var before, after string
+			if level < len(framesa) {
+				before = framesa[level]
+			}
+			if level < len(framesb) {
+				after = framesb[level]
 			}
+			// Frames past the end of the shorter stack are covered by the frame count
+			if before == "" || after == "" || strings.EqualFold(before, after) {
+				continue
+			}
+			diffs = append(diffs, StackDiff{
+				Kind:       DiffFrameContent,
+				ThreadID:   id,
+				ThreadName: ta.ThreadName,
+				FrameIndex: level + 1,
+				Before:     before,
+				After:      after,
+			})
 		}
 	}
+	return diffs, nil
+}
 
-	return true, "", nil
+func threadLabel(t *pb.ThreadStack) string {
+	return fmt.Sprintf("%d (%s)", t.ThreadId, t.ThreadName)
 }
@@ -74,10 +74,50 @@ func TestThreadsStacksEqual(t *testing.T) {
 			},
 			wantEqual: true,
 		},
+		{
+			name: "structured frames",
+			args: args{
+				ctx:   context.TODO(),
+				time1: "time1",
+				time2: "time2",
+				processa: &pb.ProcessInfo{
+					Pid:  112,
+					Rank: "RANK0",
+					Threads: []*pb.ThreadStack{
+						{
+							ThreadId:    123,
+							StackFrames: []string{"File \"/workspace/train.py\", line 88, in step\nloss.backward()"},
+							Frames: []*pb.StackFrame{
+								{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step", Code: "loss.backward()"},
+								{Language: pb.FrameLanguage_FRAME_NATIVE, File: "???", Function: "ncclGroupEnd", Module: "libnccl.so.2"},
+							},
+						},
+					},
+				},
+				processb: &pb.ProcessInfo{
+					Pid:  112,
+					Rank: "RANK0",
+					Threads: []*pb.ThreadStack{
+						{
+							ThreadId:    123,
+							StackFrames: []string{"File \"/workspace/train.py\", line 88, in step\nloss.backward()"},
+							Frames: []*pb.StackFrame{
+								{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/workspace/train.py", Line: 88, Function: "step", Code: "loss.backward()"},
+								{Language: pb.FrameLanguage_FRAME_NATIVE, File: "???", Function: "sched_yield", Module: "libc.so.6"},
+							},
+						},
+					},
+				},
+			},
+			wantEqual: false,
+			wantDiff: "Detected different process stacks. RANK0, Process type: PROCESS_UNSPECIFIED, Process ID:112, Thread ID: 123, Stack frame level: 2\n" +
+				"[time1]Stack frame content: (C) File \"???\", line 0, in ncclGroupEnd (libnccl.so.2)\n" +
+				"[time2]Stack frame content: (C) File \"???\", line 0, in sched_yield (libc.so.6)\n",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotEqual, gotDiff, err := ThreadsStacksEqual(tt.args.ctx, tt.args.time1, tt.args.time2, tt.args.processa, tt.args.processb)
+			gotEqual, gotDiff, err := ThreadsStacksEqual(tt.args.ctx, nil, tt.args.time1, tt.args.time2, tt.args.processa, tt.args.processb)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ThreadsStacksEqual() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -130,13 +170,26 @@ func TestPstreeEqual(t *testing.T) {
 					Rank:  "RANK7",
 					Diff:  "Detected different process stacks. RANK7, Process type: PROCESS_DATA_LOADER, Process ID:40826, Thread ID: 40826, Stack frame level: 9\n[time1]Stack frame content: File \"/usr/local/lib/python3.12/dist-packages/xxx/tools/sft.py\", line 553, in __getitem__\ntime.sleep(2)\n[time2]Stack frame content: File \"/usr/local/lib/python3.12/dist-packages/xxx/tools/sft.py\", line 563, in __getitem__\ntime.sleep(2)\n",
 					PType: pb.ProcessType_PROCESS_DATA_LOADER,
+					Pid:   40826,
+					Time1: "time1",
+					Time2: "time2",
+					Diffs: []StackDiff{
+						{
+							Kind:       DiffFrameContent,
+							ThreadID:   40826,
+							ThreadName: "pt_data_worker",
+							FrameIndex: 9,
+							Before:     "File \"/usr/local/lib/python3.12/dist-packages/xxx/tools/sft.py\", line 553, in __getitem__\ntime.sleep(2)",
+							After:      "File \"/usr/local/lib/python3.12/dist-packages/xxx/tools/sft.py\", line 563, in __getitem__\ntime.sleep(2)",
+						},
+					},
 				},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, got1, err := PstreeEqual(tt.args.ctx, tt.args.time1, tt.args.time2, tt.args.psa, tt.args.psb)
+			got, got1, _, err := PstreeEqual(tt.args.ctx, nil, tt.args.time1, tt.args.time2, tt.args.psa, tt.args.psb)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("PstreeEqual() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -150,3 +203,123 @@ func TestPstreeEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestPstreeEqual_byPid(t *testing.T) {
+	proc := func(pid int32, status pb.StackStatus, frame string) *pb.ProcessInfo {
+		return &pb.ProcessInfo{Pid: pid, Rank: fmt.Sprintf("RANK%d", pid), Status: status, Threads: []*pb.ThreadStack{
+			{ThreadId: pid, ThreadName: "python", StackFrames: []string{frame}},
+		}}
+	}
+	ok, timeout := pb.StackStatus_STACK_OK, pb.StackStatus_STACK_TIMEOUT
+
+	tests := []struct {
+		name           string
+		psa, psb       []*pb.ProcessInfo
+		wantEqual      bool
+		wantDiffPids   []int32
+		wantUndumpable []int32
+	}{
+		{
+			name:           "dump timeout in one sample",
+			psa:            []*pb.ProcessInfo{proc(1, ok, "wait"), proc(2, timeout, "")},
+			psb:            []*pb.ProcessInfo{proc(2, ok, "wait"), proc(1, ok, "wait")},
+			wantEqual:      true,
+			wantUndumpable: []int32{2},
+		},
+		{
+			name:           "same count, different processes",
+			psa:            []*pb.ProcessInfo{proc(1, ok, "wait"), proc(3, ok, "step")},
+			psb:            []*pb.ProcessInfo{proc(1, ok, "wait"), proc(4, ok, "load")},
+			wantEqual:      true,
+			wantUndumpable: []int32{3, 4},
+		},
+		{
+			name:         "changed stack",
+			psa:          []*pb.ProcessInfo{proc(1, ok, "wait"), proc(2, ok, "step")},
+			psb:          []*pb.ProcessInfo{proc(1, ok, "wait"), proc(2, ok, "load")},
+			wantEqual:    false,
+			wantDiffPids: []int32{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal, diffs, undumpable, err := PstreeEqual(context.TODO(), nil, "time1", "time2", tt.psa, tt.psb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var diffPids []int32
+			for _, d := range diffs {
+				diffPids = append(diffPids, d.Pid)
+			}
+			if equal != tt.wantEqual || !reflect.DeepEqual(diffPids, tt.wantDiffPids) || !reflect.DeepEqual(undumpable, tt.wantUndumpable) {
+				t.Errorf("PstreeEqual() = %v, %v, %v, want %v, %v, %v", equal, diffPids, undumpable, tt.wantEqual, tt.wantDiffPids, tt.wantUndumpable)
+			}
+		})
+	}
+}
+
+func TestDiffThreadsStacks(t *testing.T) {
+	thread := func(id int32, name string, frames ...string) *pb.ThreadStack {
+		return &pb.ThreadStack{ThreadId: id, ThreadName: name, StackFrames: frames}
+	}
+	tests := []struct {
+		name     string
+		processa *pb.ProcessInfo
+		processb *pb.ProcessInfo
+		want     []StackDiff
+		wantErr  bool
+	}{
+		{
+			name:     "equal",
+			processa: &pb.ProcessInfo{Pid: 1, Rank: "RANK0", Threads: []*pb.ThreadStack{thread(1, "main", "a", "b")}},
+			processb: &pb.ProcessInfo{Pid: 1, Rank: "RANK0", Threads: []*pb.ThreadStack{thread(1, "main", "a", "b")}},
+			want:     []StackDiff{},
+		},
+		{
+			name: "every difference",
+			processa: &pb.ProcessInfo{Pid: 1, Rank: "RANK0", Threads: []*pb.ThreadStack{
+				thread(1, "main", "a", "b", "c"),
+				thread(2, "worker", "x", "y"),
+				thread(3, "loader", "l"),
+			}},
+			processb: &pb.ProcessInfo{Pid: 1, Rank: "RANK0", Threads: []*pb.ThreadStack{
+				thread(1, "main", "a", "q"),
+				thread(2, "worker", "x", "z"),
+			}},
+			want: []StackDiff{
+				{Kind: DiffThreadCount, Before: "3", After: "2"},
+				{Kind: DiffFrameCount, ThreadID: 1, ThreadName: "main", Before: "3", After: "2"},
+				{Kind: DiffFrameContent, ThreadID: 1, ThreadName: "main", FrameIndex: 2, Before: "b", After: "q"},
+				{Kind: DiffFrameContent, ThreadID: 2, ThreadName: "worker", FrameIndex: 2, Before: "y", After: "z"},
+				{Kind: DiffThreadSet, ThreadID: 3, ThreadName: "loader", Before: "3 (loader)"},
+			},
+		},
+		{
+			name:     "thread replaced",
+			processa: &pb.ProcessInfo{Pid: 1, Rank: "RANK0", Threads: []*pb.ThreadStack{thread(2, "old")}},
+			processb: &pb.ProcessInfo{Pid: 1, Rank: "RANK0", Threads: []*pb.ThreadStack{thread(3, "new")}},
+			want: []StackDiff{
+				{Kind: DiffThreadSet, ThreadID: 2, ThreadName: "old", Before: "2 (old)"},
+				{Kind: DiffThreadSet, ThreadID: 3, ThreadName: "new", After: "3 (new)"},
+			},
+		},
+		{
+			name:     "different processes",
+			processa: &pb.ProcessInfo{Pid: 1, Rank: "RANK0"},
+			processb: &pb.ProcessInfo{Pid: 2, Rank: "RANK0"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffThreadsStacks(context.TODO(), nil, tt.processa, tt.processb)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DiffThreadsStacks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffThreadsStacks() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,11 +3,74 @@
 package rules
 
 import (
+	"fmt"
+	"strings"
+
 	pb "deeptrace/v1"
 )
 
+// DiffKind is the kind of difference between two stacks of a process
+type DiffKind string
+
+const (
+	// The number of compared threads changed
+	DiffThreadCount DiffKind = "thread_count"
+	// A thread exists at only one of the two times
+	DiffThreadSet DiffKind = "thread_set"
+	// The number of frames of a thread changed
+	DiffFrameCount DiffKind = "frame_count"
+	// A frame of a thread changed
+	DiffFrameContent DiffKind = "frame_content"
+)
+
+// StackDiff is one difference between two stacks of a process. Before and After hold the
+// thread count, thread, frame count or frame at the two times, empty when absent.
+type StackDiff struct {
+	Kind       DiffKind `json:"kind"`
+	ThreadID   int32    `json:"thread_id,omitempty"`
+	ThreadName string   `json:"thread_name,omitempty"`
+	// Frame level from 1, outermost first
+	FrameIndex int    `json:"frame_index,omitempty"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+}
+
 type ProccessInfoDiff struct {
-	Rank  string
-	Diff  string
-	PType pb.ProcessType
+	Rank  string         `json:"rank"`
+	Diff  string         `json:"-"`
+	PType pb.ProcessType `json:"type"`
+	Pid   int32          `json:"pid"`
+	Time1 string         `json:"time1"`
+	Time2 string         `json:"time2"`
+	Diffs []StackDiff    `json:"diffs"`
+}
+
+// Text renders the differences as readable text, one block per difference
+func (d ProccessInfoDiff) Text() string {
+	var b strings.Builder
+	for _, diff := range d.Diffs {
+		proc := fmt.Sprintf("%s, Process type: %s, Process ID:%d", d.Rank, d.PType.String(), d.Pid)
+		switch diff.Kind {
+		case DiffThreadCount:
+			fmt.Fprintf(&b, "Detected different number of threads in process stack. %s\n", proc)
+			fmt.Fprintf(&b, "[%s]Thread count: %s\n[%s]Thread count: %s\n", d.Time1, diff.Before, d.Time2, diff.After)
+		case DiffThreadSet:
+			fmt.Fprintf(&b, "Detected different thread IDs. %s\n", proc)
+			fmt.Fprintf(&b, "[%s]Thread: %s\n[%s]Thread: %s\n", d.Time1, orAbsent(diff.Before), d.Time2, orAbsent(diff.After))
+		case DiffFrameCount:
+			fmt.Fprintf(&b, "Detected different number of thread stack frames. %s, Thread ID: %d\n", proc, diff.ThreadID)
+			fmt.Fprintf(&b, "[%s]Frame count: %s\n[%s]Frame count: %s\n", d.Time1, diff.Before, d.Time2, diff.After)
+		case DiffFrameContent:
+			fmt.Fprintf(&b, "Detected different process stacks. %s, Thread ID: %d, Stack frame level: %d\n", proc, diff.ThreadID, diff.FrameIndex)
+			fmt.Fprintf(&b, "[%s]Stack frame content: %s\n[%s]Stack frame content: %s\n", d.Time1, diff.Before, d.Time2, diff.After)
+		}
+	}
+	return b.String()
+}
+
+func orAbsent(s string) string {
+	if s == "" {
+		return "<absent>"
+	}
+	return s
 }
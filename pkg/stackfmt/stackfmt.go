@@ -0,0 +1,59 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+// Package stackfmt formats stack frames, shared by the agent and the client rules
+package stackfmt
+
+import (
+	"fmt"
+	"path"
+
+	pb "deeptrace/v1"
+)
+
+// ThreadFrames returns the frames of a thread to compare. Structured frames are used
+// when the agent sent them, the source line is left out as it follows from the file and line.
+func ThreadFrames(thread *pb.ThreadStack) []string {
+	if len(thread.Frames) == 0 {
+		return thread.StackFrames
+	}
+	frames := make([]string, 0, len(thread.Frames))
+	for _, frame := range thread.Frames {
+		frames = append(frames, FormatFrame(frame))
+	}
+	return frames
+}
+
+// FormatFrame formats a frame the way pystack prints it
+func FormatFrame(frame *pb.StackFrame) string {
+	return formatFrame(frame, true)
+}
+
+// FormatFrameWithoutLine formats a frame like FormatFrame, without the line number
+func FormatFrameWithoutLine(frame *pb.StackFrame) string {
+	return formatFrame(frame, false)
+}
+
+func formatFrame(frame *pb.StackFrame, withLine bool) string {
+	s := fmt.Sprintf("File %q", frame.File)
+	if withLine {
+		s += fmt.Sprintf(", line %d", frame.Line)
+	}
+	s += ", in " + frame.Function
+	if frame.Language == pb.FrameLanguage_FRAME_NATIVE {
+		s = "(C) " + s
+		if frame.Module != "" {
+			s += " (" + frame.Module + ")"
+		}
+	}
+	return s
+}
+
+// FrameName returns a frame without its line number, e.g.
+// "torch.distributed.distributed_c10d:all_reduce"
+func FrameName(frame *pb.StackFrame) string {
+	module := frame.Module
+	if module == "" {
+		module = path.Base(frame.File)
+	}
+	return module + ":" + frame.Function
+}
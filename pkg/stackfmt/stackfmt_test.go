@@ -0,0 +1,61 @@
+// Copyright (c) OpenMMLab. All rights reserved.
+
+package stackfmt
+
+import (
+	"testing"
+
+	pb "deeptrace/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFrame(t *testing.T) {
+	tests := []struct {
+		name       string
+		frame      *pb.StackFrame
+		want       string
+		wantNoLine string
+		wantName   string
+	}{
+		{
+			name:       "python",
+			frame:      &pb.StackFrame{Language: pb.FrameLanguage_FRAME_PYTHON, File: "/torch/distributed/distributed_c10d.py", Line: 2501, Function: "all_reduce", Module: "torch.distributed.distributed_c10d"},
+			want:       `File "/torch/distributed/distributed_c10d.py", line 2501, in all_reduce`,
+			wantNoLine: `File "/torch/distributed/distributed_c10d.py", in all_reduce`,
+			wantName:   "torch.distributed.distributed_c10d:all_reduce",
+		},
+		{
+			name:       "native with module",
+			frame:      &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, File: "./nptl/pthread_create.c", Line: 442, Function: "start_thread", Module: "libc.so.6"},
+			want:       `(C) File "./nptl/pthread_create.c", line 442, in start_thread (libc.so.6)`,
+			wantNoLine: `(C) File "./nptl/pthread_create.c", in start_thread (libc.so.6)`,
+			wantName:   "libc.so.6:start_thread",
+		},
+		{
+			name:       "native without module",
+			frame:      &pb.StackFrame{Language: pb.FrameLanguage_FRAME_NATIVE, File: "./nptl/pthread_create.c", Line: 442, Function: "start_thread"},
+			want:       `(C) File "./nptl/pthread_create.c", line 442, in start_thread`,
+			wantNoLine: `(C) File "./nptl/pthread_create.c", in start_thread`,
+			wantName:   "pthread_create.c:start_thread",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatFrame(tt.frame))
+			assert.Equal(t, tt.wantNoLine, FormatFrameWithoutLine(tt.frame))
+			assert.Equal(t, tt.wantName, FrameName(tt.frame))
+		})
+	}
+}
+
+func TestThreadFrames(t *testing.T) {
+	legacy := &pb.ThreadStack{StackFrames: []string{`File "train.py", line 7, in step`}}
+	assert.Equal(t, legacy.StackFrames, ThreadFrames(legacy))
+
+	structured := &pb.ThreadStack{
+		Frames:      []*pb.StackFrame{{Language: pb.FrameLanguage_FRAME_PYTHON, File: "train.py", Line: 7, Function: "step"}},
+		StackFrames: []string{"ignored"},
+	}
+	assert.Equal(t, []string{`File "train.py", line 7, in step`}, ThreadFrames(structured))
+}
@@ -82,6 +82,65 @@ func (LogLevel) EnumDescriptor() ([]byte, []int) {
 	return file_v1_deeptrace_proto_rawDescGZIP(), []int{0}
 }
 
+// Whether the log of a rank could be collected
+type RankLogStatus int32
+
+const (
+	RankLogStatus_RANK_LOG_UNSPECIFIED  RankLogStatus = 0
+	RankLogStatus_RANK_LOG_OK           RankLogStatus = 1
+	RankLogStatus_RANK_LOG_FILE_MISSING RankLogStatus = 2 // No log file found for the rank
+	RankLogStatus_RANK_LOG_READ_ERROR   RankLogStatus = 3 // The log file exists but could not be read
+	RankLogStatus_RANK_LOG_PARSE_ERROR  RankLogStatus = 4 // The log has lines but none is in the log format
+	RankLogStatus_RANK_LOG_TRUNCATED    RankLogStatus = 5 // The filter hit the scan limit, older matching lines are left out
+)
+
+// Enum value maps for RankLogStatus.
+var (
+	RankLogStatus_name = map[int32]string{
+		0: "RANK_LOG_UNSPECIFIED",
+		1: "RANK_LOG_OK",
+		2: "RANK_LOG_FILE_MISSING",
+		3: "RANK_LOG_READ_ERROR",
+		4: "RANK_LOG_PARSE_ERROR",
+		5: "RANK_LOG_TRUNCATED",
+	}
+	RankLogStatus_value = map[string]int32{
+		"RANK_LOG_UNSPECIFIED":  0,
+		"RANK_LOG_OK":           1,
+		"RANK_LOG_FILE_MISSING": 2,
+		"RANK_LOG_READ_ERROR":   3,
+		"RANK_LOG_PARSE_ERROR":  4,
+		"RANK_LOG_TRUNCATED":    5,
+	}
+)
+
+func (x RankLogStatus) Enum() *RankLogStatus {
+	p := new(RankLogStatus)
+	*p = x
+	return p
+}
+
+func (x RankLogStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RankLogStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_deeptrace_proto_enumTypes[1].Descriptor()
+}
+
+func (RankLogStatus) Type() protoreflect.EnumType {
+	return &file_v1_deeptrace_proto_enumTypes[1]
+}
+
+func (x RankLogStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RankLogStatus.Descriptor instead.
+func (RankLogStatus) EnumDescriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{1}
+}
+
 // Process type enumeration
 type ProcessType int32
 
@@ -119,11 +178,11 @@ func (x ProcessType) String() string {
 }
 
 func (ProcessType) Descriptor() protoreflect.EnumDescriptor {
-	return file_v1_deeptrace_proto_enumTypes[1].Descriptor()
+	return file_v1_deeptrace_proto_enumTypes[2].Descriptor()
 }
 
 func (ProcessType) Type() protoreflect.EnumType {
-	return &file_v1_deeptrace_proto_enumTypes[1]
+	return &file_v1_deeptrace_proto_enumTypes[2]
 }
 
 func (x ProcessType) Number() protoreflect.EnumNumber {
@@ -132,7 +191,163 @@ func (x ProcessType) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ProcessType.Descriptor instead.
 func (ProcessType) EnumDescriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{1}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{2}
+}
+
+// Language of a stack frame
+type FrameLanguage int32
+
+const (
+	FrameLanguage_FRAME_LANGUAGE_UNSPECIFIED FrameLanguage = 0
+	FrameLanguage_FRAME_PYTHON               FrameLanguage = 1
+	FrameLanguage_FRAME_NATIVE               FrameLanguage = 2 // C/C++/CUDA frame
+)
+
+// Enum value maps for FrameLanguage.
+var (
+	FrameLanguage_name = map[int32]string{
+		0: "FRAME_LANGUAGE_UNSPECIFIED",
+		1: "FRAME_PYTHON",
+		2: "FRAME_NATIVE",
+	}
+	FrameLanguage_value = map[string]int32{
+		"FRAME_LANGUAGE_UNSPECIFIED": 0,
+		"FRAME_PYTHON":               1,
+		"FRAME_NATIVE":               2,
+	}
+)
+
+func (x FrameLanguage) Enum() *FrameLanguage {
+	p := new(FrameLanguage)
+	*p = x
+	return p
+}
+
+func (x FrameLanguage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FrameLanguage) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_deeptrace_proto_enumTypes[3].Descriptor()
+}
+
+func (FrameLanguage) Type() protoreflect.EnumType {
+	return &file_v1_deeptrace_proto_enumTypes[3]
+}
+
+func (x FrameLanguage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FrameLanguage.Descriptor instead.
+func (FrameLanguage) EnumDescriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{3}
+}
+
+// Native frames collected with the Python stack
+type NativeMode int32
+
+const (
+	NativeMode_NATIVE_OFF NativeMode = 0 // Python frames only
+	NativeMode_NATIVE     NativeMode = 1 // Native frames of threads holding Python frames (pystack --native)
+	NativeMode_NATIVE_ALL NativeMode = 2 // Native frames of all threads (pystack --native-all)
+)
+
+// Enum value maps for NativeMode.
+var (
+	NativeMode_name = map[int32]string{
+		0: "NATIVE_OFF",
+		1: "NATIVE",
+		2: "NATIVE_ALL",
+	}
+	NativeMode_value = map[string]int32{
+		"NATIVE_OFF": 0,
+		"NATIVE":     1,
+		"NATIVE_ALL": 2,
+	}
+)
+
+func (x NativeMode) Enum() *NativeMode {
+	p := new(NativeMode)
+	*p = x
+	return p
+}
+
+func (x NativeMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (NativeMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_deeptrace_proto_enumTypes[4].Descriptor()
+}
+
+func (NativeMode) Type() protoreflect.EnumType {
+	return &file_v1_deeptrace_proto_enumTypes[4]
+}
+
+func (x NativeMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use NativeMode.Descriptor instead.
+func (NativeMode) EnumDescriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{4}
+}
+
+// Result of dumping the stacks of a process
+type StackStatus int32
+
+const (
+	StackStatus_STACK_UNSPECIFIED StackStatus = 0
+	StackStatus_STACK_OK          StackStatus = 1
+	StackStatus_STACK_FAILED      StackStatus = 2 // The backend failed, see error
+	StackStatus_STACK_TIMEOUT     StackStatus = 3 // The backend did not finish within the per-process timeout
+	StackStatus_STACK_CANCELED    StackStatus = 4 // The request was canceled or its deadline passed
+)
+
+// Enum value maps for StackStatus.
+var (
+	StackStatus_name = map[int32]string{
+		0: "STACK_UNSPECIFIED",
+		1: "STACK_OK",
+		2: "STACK_FAILED",
+		3: "STACK_TIMEOUT",
+		4: "STACK_CANCELED",
+	}
+	StackStatus_value = map[string]int32{
+		"STACK_UNSPECIFIED": 0,
+		"STACK_OK":          1,
+		"STACK_FAILED":      2,
+		"STACK_TIMEOUT":     3,
+		"STACK_CANCELED":    4,
+	}
+)
+
+func (x StackStatus) Enum() *StackStatus {
+	p := new(StackStatus)
+	*p = x
+	return p
+}
+
+func (x StackStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StackStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_deeptrace_proto_enumTypes[5].Descriptor()
+}
+
+func (StackStatus) Type() protoreflect.EnumType {
+	return &file_v1_deeptrace_proto_enumTypes[5]
+}
+
+func (x StackStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StackStatus.Descriptor instead.
+func (StackStatus) EnumDescriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{5}
 }
 
 // Error status codes
@@ -178,11 +393,11 @@ func (x ErrorCode) String() string {
 }
 
 func (ErrorCode) Descriptor() protoreflect.EnumDescriptor {
-	return file_v1_deeptrace_proto_enumTypes[2].Descriptor()
+	return file_v1_deeptrace_proto_enumTypes[6].Descriptor()
 }
 
 func (ErrorCode) Type() protoreflect.EnumType {
-	return &file_v1_deeptrace_proto_enumTypes[2]
+	return &file_v1_deeptrace_proto_enumTypes[6]
 }
 
 func (x ErrorCode) Number() protoreflect.EnumNumber {
@@ -191,7 +406,7 @@ func (x ErrorCode) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ErrorCode.Descriptor instead.
 func (ErrorCode) EnumDescriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{2}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{6}
 }
 
 type Severity int32
@@ -230,11 +445,11 @@ func (x Severity) String() string {
 }
 
 func (Severity) Descriptor() protoreflect.EnumDescriptor {
-	return file_v1_deeptrace_proto_enumTypes[3].Descriptor()
+	return file_v1_deeptrace_proto_enumTypes[7].Descriptor()
 }
 
 func (Severity) Type() protoreflect.EnumType {
-	return &file_v1_deeptrace_proto_enumTypes[3]
+	return &file_v1_deeptrace_proto_enumTypes[7]
 }
 
 func (x Severity) Number() protoreflect.EnumNumber {
@@ -243,16 +458,22 @@ func (x Severity) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Severity.Descriptor instead.
 func (Severity) EnumDescriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{3}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{7}
 }
 
 // Single log entry
 type LogEntry struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`           // Log timestamp
-	Level         LogLevel               `protobuf:"varint,2,opt,name=level,proto3,enum=v1.LogLevel" json:"level,omitempty"` // Log level
-	Epoch         int32                  `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
-	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"` // Log content
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`           // Log timestamp
+	Level     LogLevel               `protobuf:"varint,2,opt,name=level,proto3,enum=v1.LogLevel" json:"level,omitempty"` // Log level
+	Epoch     int32                  `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Message   string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"` // Log content
+	// Training progress extracted from the message, unset when the line has none
+	Step          *int64   `protobuf:"varint,5,opt,name=step,proto3,oneof" json:"step,omitempty"` // Training step or iteration
+	Loss          *float64 `protobuf:"fixed64,6,opt,name=loss,proto3,oneof" json:"loss,omitempty"`
+	Lr            *float64 `protobuf:"fixed64,7,opt,name=lr,proto3,oneof" json:"lr,omitempty"`                                           // Learning rate
+	TokensPerSec  *float64 `protobuf:"fixed64,8,opt,name=tokens_per_sec,json=tokensPerSec,proto3,oneof" json:"tokens_per_sec,omitempty"` // Throughput in tokens per second
+	Tflops        *float64 `protobuf:"fixed64,9,opt,name=tflops,proto3,oneof" json:"tflops,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -315,12 +536,49 @@ func (x *LogEntry) GetMessage() string {
 	return ""
 }
 
+func (x *LogEntry) GetStep() int64 {
+	if x != nil && x.Step != nil {
+		return *x.Step
+	}
+	return 0
+}
+
+func (x *LogEntry) GetLoss() float64 {
+	if x != nil && x.Loss != nil {
+		return *x.Loss
+	}
+	return 0
+}
+
+func (x *LogEntry) GetLr() float64 {
+	if x != nil && x.Lr != nil {
+		return *x.Lr
+	}
+	return 0
+}
+
+func (x *LogEntry) GetTokensPerSec() float64 {
+	if x != nil && x.TokensPerSec != nil {
+		return *x.TokensPerSec
+	}
+	return 0
+}
+
+func (x *LogEntry) GetTflops() float64 {
+	if x != nil && x.Tflops != nil {
+		return *x.Tflops
+	}
+	return 0
+}
+
 type RankLog struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Rank           string                 `protobuf:"bytes,1,opt,name=rank,proto3" json:"rank,omitempty"`                                            // Rank number
 	Entries        []*LogEntry            `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`                                      // Log entries
 	SuspendSeconds int32                  `protobuf:"varint,3,opt,name=suspend_seconds,json=suspendSeconds,proto3" json:"suspend_seconds,omitempty"` // Interval (in seconds) between the last line of log for this rank and the collection time
 	TailTime       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=tail_time,json=tailTime,proto3" json:"tail_time,omitempty"`                    // Log timestamp
+	Status         RankLogStatus          `protobuf:"varint,5,opt,name=status,proto3,enum=v1.RankLogStatus" json:"status,omitempty"`
+	StatusMessage  string                 `protobuf:"bytes,6,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"` // Error details when status is not RANK_LOG_OK
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -383,11 +641,36 @@ func (x *RankLog) GetTailTime() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *RankLog) GetStatus() RankLogStatus {
+	if x != nil {
+		return x.Status
+	}
+	return RankLogStatus_RANK_LOG_UNSPECIFIED
+}
+
+func (x *RankLog) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
 // Request to get recent logs
 type GetRecentLogsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	MaxLines      int32                  `protobuf:"varint,1,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"`
-	WorkDir       string                 `protobuf:"bytes,2,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	MaxLines int32                  `protobuf:"varint,1,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"`
+	WorkDir  string                 `protobuf:"bytes,2,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	// Log level filter (optional)
+	Levels []LogLevel `protobuf:"varint,3,rep,packed,name=levels,proto3,enum=v1.LogLevel" json:"levels,omitempty"`
+	// Search keyword (optional)
+	SearchKeyword string `protobuf:"bytes,4,opt,name=search_keyword,json=searchKeyword,proto3" json:"search_keyword,omitempty"`
+	// Regular expression the log line must match (optional)
+	Regex string `protobuf:"bytes,5,opt,name=regex,proto3" json:"regex,omitempty"`
+	// Time window (optional), entries without a timestamp are dropped when set
+	Since *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=since,proto3" json:"since,omitempty"`
+	Until *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=until,proto3" json:"until,omitempty"`
+	// Name of the log format parser (optional), detected from the first lines when empty
+	LogFormat     string `protobuf:"bytes,8,opt,name=log_format,json=logFormat,proto3" json:"log_format,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -436,6 +719,48 @@ func (x *GetRecentLogsRequest) GetWorkDir() string {
 	return ""
 }
 
+func (x *GetRecentLogsRequest) GetLevels() []LogLevel {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+func (x *GetRecentLogsRequest) GetSearchKeyword() string {
+	if x != nil {
+		return x.SearchKeyword
+	}
+	return ""
+}
+
+func (x *GetRecentLogsRequest) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+func (x *GetRecentLogsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *GetRecentLogsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *GetRecentLogsRequest) GetLogFormat() string {
+	if x != nil {
+		return x.LogFormat
+	}
+	return ""
+}
+
 // Log response
 type LogResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -481,30 +806,36 @@ func (x *LogResponse) GetRanklogs() []*RankLog {
 	return nil
 }
 
-// Single thread stack information
-type ThreadStack struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ThreadId      int32                  `protobuf:"varint,1,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`         // Thread ID
-	ThreadName    string                 `protobuf:"bytes,2,opt,name=thread_name,json=threadName,proto3" json:"thread_name,omitempty"`    // Thread name
-	StackFrames   []string               `protobuf:"bytes,3,rep,name=stack_frames,json=stackFrames,proto3" json:"stack_frames,omitempty"` // Stack frame list (most recent first)
+// Request to follow logs
+type FollowLogsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	WorkDir   string                 `protobuf:"bytes,1,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	TailLines int32                  `protobuf:"varint,2,opt,name=tail_lines,json=tailLines,proto3" json:"tail_lines,omitempty"` // Number of existing lines sent per rank before following
+	// Same filters as GetRecentLogsRequest
+	Levels        []LogLevel             `protobuf:"varint,3,rep,packed,name=levels,proto3,enum=v1.LogLevel" json:"levels,omitempty"`
+	SearchKeyword string                 `protobuf:"bytes,4,opt,name=search_keyword,json=searchKeyword,proto3" json:"search_keyword,omitempty"`
+	Regex         string                 `protobuf:"bytes,5,opt,name=regex,proto3" json:"regex,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=since,proto3" json:"since,omitempty"`
+	Until         *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=until,proto3" json:"until,omitempty"`
+	LogFormat     string                 `protobuf:"bytes,8,opt,name=log_format,json=logFormat,proto3" json:"log_format,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ThreadStack) Reset() {
-	*x = ThreadStack{}
+func (x *FollowLogsRequest) Reset() {
+	*x = FollowLogsRequest{}
 	mi := &file_v1_deeptrace_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ThreadStack) String() string {
+func (x *FollowLogsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ThreadStack) ProtoMessage() {}
+func (*FollowLogsRequest) ProtoMessage() {}
 
-func (x *ThreadStack) ProtoReflect() protoreflect.Message {
+func (x *FollowLogsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_v1_deeptrace_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -516,60 +847,1021 @@ func (x *ThreadStack) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ThreadStack.ProtoReflect.Descriptor instead.
-func (*ThreadStack) Descriptor() ([]byte, []int) {
+// Deprecated: Use FollowLogsRequest.ProtoReflect.Descriptor instead.
+func (*FollowLogsRequest) Descriptor() ([]byte, []int) {
 	return file_v1_deeptrace_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *ThreadStack) GetThreadId() int32 {
+func (x *FollowLogsRequest) GetWorkDir() string {
 	if x != nil {
-		return x.ThreadId
+		return x.WorkDir
+	}
+	return ""
+}
+
+func (x *FollowLogsRequest) GetTailLines() int32 {
+	if x != nil {
+		return x.TailLines
+	}
+	return 0
+}
+
+func (x *FollowLogsRequest) GetLevels() []LogLevel {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+func (x *FollowLogsRequest) GetSearchKeyword() string {
+	if x != nil {
+		return x.SearchKeyword
+	}
+	return ""
+}
+
+func (x *FollowLogsRequest) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+func (x *FollowLogsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *FollowLogsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *FollowLogsRequest) GetLogFormat() string {
+	if x != nil {
+		return x.LogFormat
+	}
+	return ""
+}
+
+// Request to resolve rank log files
+type ResolveLogFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkDir       string                 `protobuf:"bytes,1,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLogFilesRequest) Reset() {
+	*x = ResolveLogFilesRequest{}
+	mi := &file_v1_deeptrace_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLogFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLogFilesRequest) ProtoMessage() {}
+
+func (x *ResolveLogFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLogFilesRequest.ProtoReflect.Descriptor instead.
+func (*ResolveLogFilesRequest) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResolveLogFilesRequest) GetWorkDir() string {
+	if x != nil {
+		return x.WorkDir
+	}
+	return ""
+}
+
+// Log file resolved for a rank
+type ResolvedLogFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rank          string                 `protobuf:"bytes,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Exists        bool                   `protobuf:"varint,3,opt,name=exists,proto3" json:"exists,omitempty"`
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	ModTime       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolvedLogFile) Reset() {
+	*x = ResolvedLogFile{}
+	mi := &file_v1_deeptrace_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolvedLogFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolvedLogFile) ProtoMessage() {}
+
+func (x *ResolvedLogFile) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolvedLogFile.ProtoReflect.Descriptor instead.
+func (*ResolvedLogFile) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResolvedLogFile) GetRank() string {
+	if x != nil {
+		return x.Rank
+	}
+	return ""
+}
+
+func (x *ResolvedLogFile) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ResolvedLogFile) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *ResolvedLogFile) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ResolvedLogFile) GetModTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModTime
+	}
+	return nil
+}
+
+// Rank log files and the layout rule that located them
+type ResolveLogFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkDir       string                 `protobuf:"bytes,1,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	Layout        string                 `protobuf:"bytes,2,opt,name=layout,proto3" json:"layout,omitempty"`               // Name of the matching layout
+	RunDir        string                 `protobuf:"bytes,3,opt,name=run_dir,json=runDir,proto3" json:"run_dir,omitempty"` // Run directory selected by the layout
+	Files         []*ResolvedLogFile     `protobuf:"bytes,4,rep,name=files,proto3" json:"files,omitempty"`
+	Warnings      []string               `protobuf:"bytes,5,rep,name=warnings,proto3" json:"warnings,omitempty"` // Problems that did not prevent resolution
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLogFilesResponse) Reset() {
+	*x = ResolveLogFilesResponse{}
+	mi := &file_v1_deeptrace_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLogFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLogFilesResponse) ProtoMessage() {}
+
+func (x *ResolveLogFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLogFilesResponse.ProtoReflect.Descriptor instead.
+func (*ResolveLogFilesResponse) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ResolveLogFilesResponse) GetWorkDir() string {
+	if x != nil {
+		return x.WorkDir
+	}
+	return ""
+}
+
+func (x *ResolveLogFilesResponse) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *ResolveLogFilesResponse) GetRunDir() string {
+	if x != nil {
+		return x.RunDir
+	}
+	return ""
+}
+
+func (x *ResolveLogFilesResponse) GetFiles() []*ResolvedLogFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ResolveLogFilesResponse) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+// Request to extract fatal errors from the rank logs
+type GetLogErrorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkDir       string                 `protobuf:"bytes,1,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	MaxLines      int32                  `protobuf:"varint,2,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"` // Lines scanned at the end of each rank log, 2000 when unset
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLogErrorsRequest) Reset() {
+	*x = GetLogErrorsRequest{}
+	mi := &file_v1_deeptrace_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLogErrorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLogErrorsRequest) ProtoMessage() {}
+
+func (x *GetLogErrorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLogErrorsRequest.ProtoReflect.Descriptor instead.
+func (*GetLogErrorsRequest) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetLogErrorsRequest) GetWorkDir() string {
+	if x != nil {
+		return x.WorkDir
+	}
+	return ""
+}
+
+func (x *GetLogErrorsRequest) GetMaxLines() int32 {
+	if x != nil {
+		return x.MaxLines
+	}
+	return 0
+}
+
+// Fatal error found in a rank log: a Python traceback or a line matching a known signature
+type LogError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`                                                                                 // "traceback", "nccl_timeout", "cuda_oom", "cuda_error", "segfault" or "dist_backend_error"
+	Summary       string                 `protobuf:"bytes,2,opt,name=summary,proto3" json:"summary,omitempty"`                                                                           // Exception line of the traceback, or the matching line
+	Lines         []string               `protobuf:"bytes,3,rep,name=lines,proto3" json:"lines,omitempty"`                                                                               // Lines of the traceback, or the matching line
+	Count         int32                  `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`                                                                              // Occurrences in the scanned lines
+	Context       map[string]string      `protobuf:"bytes,5,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Error context, e.g. "exception" and "location"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogError) Reset() {
+	*x = LogError{}
+	mi := &file_v1_deeptrace_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogError) ProtoMessage() {}
+
+func (x *LogError) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogError.ProtoReflect.Descriptor instead.
+func (*LogError) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *LogError) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *LogError) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *LogError) GetLines() []string {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *LogError) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *LogError) GetContext() map[string]string {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+// Fatal errors of a rank, oldest first
+type RankLogErrors struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rank          string                 `protobuf:"bytes,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	Errors        []*LogError            `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+	Status        RankLogStatus          `protobuf:"varint,3,opt,name=status,proto3,enum=v1.RankLogStatus" json:"status,omitempty"`
+	StatusMessage string                 `protobuf:"bytes,4,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"` // Error details when status is not RANK_LOG_OK
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RankLogErrors) Reset() {
+	*x = RankLogErrors{}
+	mi := &file_v1_deeptrace_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RankLogErrors) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankLogErrors) ProtoMessage() {}
+
+func (x *RankLogErrors) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankLogErrors.ProtoReflect.Descriptor instead.
+func (*RankLogErrors) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RankLogErrors) GetRank() string {
+	if x != nil {
+		return x.Rank
+	}
+	return ""
+}
+
+func (x *RankLogErrors) GetErrors() []*LogError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *RankLogErrors) GetStatus() RankLogStatus {
+	if x != nil {
+		return x.Status
+	}
+	return RankLogStatus_RANK_LOG_UNSPECIFIED
+}
+
+func (x *RankLogErrors) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
+type LogErrorsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ranklogs      []*RankLogErrors       `protobuf:"bytes,1,rep,name=ranklogs,proto3" json:"ranklogs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogErrorsResponse) Reset() {
+	*x = LogErrorsResponse{}
+	mi := &file_v1_deeptrace_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogErrorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogErrorsResponse) ProtoMessage() {}
+
+func (x *LogErrorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogErrorsResponse.ProtoReflect.Descriptor instead.
+func (*LogErrorsResponse) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *LogErrorsResponse) GetRanklogs() []*RankLogErrors {
+	if x != nil {
+		return x.Ranklogs
+	}
+	return nil
+}
+
+// Single stack frame
+type StackFrame struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Language      FrameLanguage          `protobuf:"varint,1,opt,name=language,proto3,enum=v1.FrameLanguage" json:"language,omitempty"`
+	Function      string                 `protobuf:"bytes,2,opt,name=function,proto3" json:"function,omitempty"`
+	File          string                 `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	Line          int32                  `protobuf:"varint,4,opt,name=line,proto3" json:"line,omitempty"`
+	Code          string                 `protobuf:"bytes,5,opt,name=code,proto3" json:"code,omitempty"`     // Source line, Python frames only
+	Module        string                 `protobuf:"bytes,6,opt,name=module,proto3" json:"module,omitempty"` // Python module, or the shared object of a native frame
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StackFrame) Reset() {
+	*x = StackFrame{}
+	mi := &file_v1_deeptrace_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StackFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StackFrame) ProtoMessage() {}
+
+func (x *StackFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StackFrame.ProtoReflect.Descriptor instead.
+func (*StackFrame) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StackFrame) GetLanguage() FrameLanguage {
+	if x != nil {
+		return x.Language
+	}
+	return FrameLanguage_FRAME_LANGUAGE_UNSPECIFIED
+}
+
+func (x *StackFrame) GetFunction() string {
+	if x != nil {
+		return x.Function
+	}
+	return ""
+}
+
+func (x *StackFrame) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *StackFrame) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *StackFrame) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *StackFrame) GetModule() string {
+	if x != nil {
+		return x.Module
+	}
+	return ""
+}
+
+// Single thread stack information
+type ThreadStack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ThreadId      int32                  `protobuf:"varint,1,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`         // Thread ID
+	ThreadName    string                 `protobuf:"bytes,2,opt,name=thread_name,json=threadName,proto3" json:"thread_name,omitempty"`    // Thread name
+	StackFrames   []string               `protobuf:"bytes,3,rep,name=stack_frames,json=stackFrames,proto3" json:"stack_frames,omitempty"` // Python stack frame list (most recent first), kept for compatibility
+	Frames        []*StackFrame          `protobuf:"bytes,4,rep,name=frames,proto3" json:"frames,omitempty"`                              // Python and native frames, in the same order
+	HoldsGil      bool                   `protobuf:"varint,5,opt,name=holds_gil,json=holdsGil,proto3" json:"holds_gil,omitempty"`         // The thread holds the GIL
+	Idle          bool                   `protobuf:"varint,6,opt,name=idle,proto3" json:"idle,omitempty"`                                 // The thread is blocked waiting rather than running
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ThreadStack) Reset() {
+	*x = ThreadStack{}
+	mi := &file_v1_deeptrace_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ThreadStack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThreadStack) ProtoMessage() {}
+
+func (x *ThreadStack) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThreadStack.ProtoReflect.Descriptor instead.
+func (*ThreadStack) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ThreadStack) GetThreadId() int32 {
+	if x != nil {
+		return x.ThreadId
+	}
+	return 0
+}
+
+func (x *ThreadStack) GetThreadName() string {
+	if x != nil {
+		return x.ThreadName
+	}
+	return ""
+}
+
+func (x *ThreadStack) GetStackFrames() []string {
+	if x != nil {
+		return x.StackFrames
+	}
+	return nil
+}
+
+func (x *ThreadStack) GetFrames() []*StackFrame {
+	if x != nil {
+		return x.Frames
+	}
+	return nil
+}
+
+func (x *ThreadStack) GetHoldsGil() bool {
+	if x != nil {
+		return x.HoldsGil
+	}
+	return false
+}
+
+func (x *ThreadStack) GetIdle() bool {
+	if x != nil {
+		return x.Idle
+	}
+	return false
+}
+
+// Single process information
+type ProcessInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`                       // Process ID
+	Ppid          int32                  `protobuf:"varint,2,opt,name=ppid,proto3" json:"ppid,omitempty"`                     // Parent process ID
+	Type          ProcessType            `protobuf:"varint,3,opt,name=type,proto3,enum=v1.ProcessType" json:"type,omitempty"` // Process type
+	Threads       []*ThreadStack         `protobuf:"bytes,4,rep,name=threads,proto3" json:"threads,omitempty"`                // Thread stack information
+	Rank          string                 `protobuf:"bytes,5,opt,name=rank,proto3" json:"rank,omitempty"`
+	LocalRank     string                 `protobuf:"bytes,6,opt,name=local_rank,json=localRank,proto3" json:"local_rank,omitempty"`
+	Backend       string                 `protobuf:"bytes,7,opt,name=backend,proto3" json:"backend,omitempty"` // Tool the stacks were dumped with
+	Status        StackStatus            `protobuf:"varint,8,opt,name=status,proto3,enum=v1.StackStatus" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"` // Why the stacks are missing, unless the status is STACK_OK
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessInfo) Reset() {
+	*x = ProcessInfo{}
+	mi := &file_v1_deeptrace_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessInfo) ProtoMessage() {}
+
+func (x *ProcessInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessInfo.ProtoReflect.Descriptor instead.
+func (*ProcessInfo) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ProcessInfo) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *ProcessInfo) GetPpid() int32 {
+	if x != nil {
+		return x.Ppid
+	}
+	return 0
+}
+
+func (x *ProcessInfo) GetType() ProcessType {
+	if x != nil {
+		return x.Type
+	}
+	return ProcessType_PROCESS_UNSPECIFIED
+}
+
+func (x *ProcessInfo) GetThreads() []*ThreadStack {
+	if x != nil {
+		return x.Threads
+	}
+	return nil
+}
+
+func (x *ProcessInfo) GetRank() string {
+	if x != nil {
+		return x.Rank
+	}
+	return ""
+}
+
+func (x *ProcessInfo) GetLocalRank() string {
+	if x != nil {
+		return x.LocalRank
+	}
+	return ""
+}
+
+func (x *ProcessInfo) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *ProcessInfo) GetStatus() StackStatus {
+	if x != nil {
+		return x.Status
+	}
+	return StackStatus_STACK_UNSPECIFIED
+}
+
+func (x *ProcessInfo) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ProcessInfoList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Processes     []*ProcessInfo         `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
+	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessInfoList) Reset() {
+	*x = ProcessInfoList{}
+	mi := &file_v1_deeptrace_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessInfoList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessInfoList) ProtoMessage() {}
+
+func (x *ProcessInfoList) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessInfoList.ProtoReflect.Descriptor instead.
+func (*ProcessInfoList) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ProcessInfoList) GetProcesses() []*ProcessInfo {
+	if x != nil {
+		return x.Processes
+	}
+	return nil
+}
+
+func (x *ProcessInfoList) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+// Request to get process stacks
+type GetProcessStacksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Target process type (required)
+	ProcessType ProcessType `protobuf:"varint,1,opt,name=process_type,json=processType,proto3,enum=v1.ProcessType" json:"process_type,omitempty"`
+	// Rank (optional)
+	Rank string `protobuf:"bytes,2,opt,name=rank,proto3" json:"rank,omitempty"`
+	// Native frames to collect (optional)
+	NativeMode NativeMode `protobuf:"varint,3,opt,name=native_mode,json=nativeMode,proto3,enum=v1.NativeMode" json:"native_mode,omitempty"`
+	// Stack backend: pystack, py-spy, gdb or proc (optional, defaults to the agent's choice)
+	Backend string `protobuf:"bytes,4,opt,name=backend,proto3" json:"backend,omitempty"`
+	// Time allowed to dump one process in seconds (optional, defaults to 20)
+	TimeoutSeconds int32 `protobuf:"varint,5,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetProcessStacksRequest) Reset() {
+	*x = GetProcessStacksRequest{}
+	mi := &file_v1_deeptrace_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProcessStacksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProcessStacksRequest) ProtoMessage() {}
+
+func (x *GetProcessStacksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProcessStacksRequest.ProtoReflect.Descriptor instead.
+func (*GetProcessStacksRequest) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetProcessStacksRequest) GetProcessType() ProcessType {
+	if x != nil {
+		return x.ProcessType
+	}
+	return ProcessType_PROCESS_UNSPECIFIED
+}
+
+func (x *GetProcessStacksRequest) GetRank() string {
+	if x != nil {
+		return x.Rank
+	}
+	return ""
+}
+
+func (x *GetProcessStacksRequest) GetNativeMode() NativeMode {
+	if x != nil {
+		return x.NativeMode
+	}
+	return NativeMode_NATIVE_OFF
+}
+
+func (x *GetProcessStacksRequest) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *GetProcessStacksRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+// Process stack response
+type ProcessStacksResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Processes        []*ProcessInfo         `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`                                        // Process information list, including failed processes
+	TotalProcesses   int32                  `protobuf:"varint,2,opt,name=total_processes,json=totalProcesses,proto3" json:"total_processes,omitempty"`       // Total number of processes of this type
+	SampledProcesses int32                  `protobuf:"varint,3,opt,name=sampled_processes,json=sampledProcesses,proto3" json:"sampled_processes,omitempty"` // Number of processes whose stacks were dumped
+	SnapshotTime     string                 `protobuf:"bytes,4,opt,name=snapshot_time,json=snapshotTime,proto3" json:"snapshot_time,omitempty"`              // Snapshot acquisition time
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ProcessStacksResponse) Reset() {
+	*x = ProcessStacksResponse{}
+	mi := &file_v1_deeptrace_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessStacksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessStacksResponse) ProtoMessage() {}
+
+func (x *ProcessStacksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessStacksResponse.ProtoReflect.Descriptor instead.
+func (*ProcessStacksResponse) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ProcessStacksResponse) GetProcesses() []*ProcessInfo {
+	if x != nil {
+		return x.Processes
+	}
+	return nil
+}
+
+func (x *ProcessStacksResponse) GetTotalProcesses() int32 {
+	if x != nil {
+		return x.TotalProcesses
 	}
 	return 0
 }
 
-func (x *ThreadStack) GetThreadName() string {
+func (x *ProcessStacksResponse) GetSampledProcesses() int32 {
 	if x != nil {
-		return x.ThreadName
+		return x.SampledProcesses
 	}
-	return ""
+	return 0
 }
 
-func (x *ThreadStack) GetStackFrames() []string {
+func (x *ProcessStacksResponse) GetSnapshotTime() string {
 	if x != nil {
-		return x.StackFrames
+		return x.SnapshotTime
 	}
-	return nil
+	return ""
 }
 
-// Single process information
-type ProcessInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`                       // Process ID
-	Ppid          int32                  `protobuf:"varint,2,opt,name=ppid,proto3" json:"ppid,omitempty"`                     // Parent process ID
-	Type          ProcessType            `protobuf:"varint,3,opt,name=type,proto3,enum=v1.ProcessType" json:"type,omitempty"` // Process type
-	Threads       []*ThreadStack         `protobuf:"bytes,4,rep,name=threads,proto3" json:"threads,omitempty"`                // Thread stack information
-	Rank          string                 `protobuf:"bytes,5,opt,name=rank,proto3" json:"rank,omitempty"`
-	LocalRank     string                 `protobuf:"bytes,6,opt,name=local_rank,json=localRank,proto3" json:"local_rank,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// Request to sample process stacks several times
+type SampleProcessStacksRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Count           int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`                                            // Number of snapshots (optional, defaults to 5)
+	IntervalSeconds int32                  `protobuf:"varint,2,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"` // Time between snapshots (optional, defaults to 5)
+	// Same process selection as GetProcessStacksRequest
+	ProcessType    ProcessType `protobuf:"varint,3,opt,name=process_type,json=processType,proto3,enum=v1.ProcessType" json:"process_type,omitempty"`
+	Rank           string      `protobuf:"bytes,4,opt,name=rank,proto3" json:"rank,omitempty"`
+	NativeMode     NativeMode  `protobuf:"varint,5,opt,name=native_mode,json=nativeMode,proto3,enum=v1.NativeMode" json:"native_mode,omitempty"`
+	Backend        string      `protobuf:"bytes,6,opt,name=backend,proto3" json:"backend,omitempty"`
+	TimeoutSeconds int32       `protobuf:"varint,7,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *ProcessInfo) Reset() {
-	*x = ProcessInfo{}
-	mi := &file_v1_deeptrace_proto_msgTypes[5]
+func (x *SampleProcessStacksRequest) Reset() {
+	*x = SampleProcessStacksRequest{}
+	mi := &file_v1_deeptrace_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessInfo) String() string {
+func (x *SampleProcessStacksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessInfo) ProtoMessage() {}
+func (*SampleProcessStacksRequest) ProtoMessage() {}
 
-func (x *ProcessInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[5]
+func (x *SampleProcessStacksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -580,76 +1872,84 @@ func (x *ProcessInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessInfo.ProtoReflect.Descriptor instead.
-func (*ProcessInfo) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use SampleProcessStacksRequest.ProtoReflect.Descriptor instead.
+func (*SampleProcessStacksRequest) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *ProcessInfo) GetPid() int32 {
+func (x *SampleProcessStacksRequest) GetCount() int32 {
 	if x != nil {
-		return x.Pid
+		return x.Count
 	}
 	return 0
 }
 
-func (x *ProcessInfo) GetPpid() int32 {
+func (x *SampleProcessStacksRequest) GetIntervalSeconds() int32 {
 	if x != nil {
-		return x.Ppid
+		return x.IntervalSeconds
 	}
 	return 0
 }
 
-func (x *ProcessInfo) GetType() ProcessType {
+func (x *SampleProcessStacksRequest) GetProcessType() ProcessType {
 	if x != nil {
-		return x.Type
+		return x.ProcessType
 	}
 	return ProcessType_PROCESS_UNSPECIFIED
 }
 
-func (x *ProcessInfo) GetThreads() []*ThreadStack {
+func (x *SampleProcessStacksRequest) GetRank() string {
 	if x != nil {
-		return x.Threads
+		return x.Rank
 	}
-	return nil
+	return ""
 }
 
-func (x *ProcessInfo) GetRank() string {
+func (x *SampleProcessStacksRequest) GetNativeMode() NativeMode {
 	if x != nil {
-		return x.Rank
+		return x.NativeMode
 	}
-	return ""
+	return NativeMode_NATIVE_OFF
 }
 
-func (x *ProcessInfo) GetLocalRank() string {
+func (x *SampleProcessStacksRequest) GetBackend() string {
 	if x != nil {
-		return x.LocalRank
+		return x.Backend
 	}
 	return ""
 }
 
-type ProcessInfoList struct {
+func (x *SampleProcessStacksRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+// One stack snapshot
+type StackSample struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Processes     []*ProcessInfo         `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
-	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Processes     []*ProcessInfo         `protobuf:"bytes,2,rep,name=processes,proto3" json:"processes,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProcessInfoList) Reset() {
-	*x = ProcessInfoList{}
-	mi := &file_v1_deeptrace_proto_msgTypes[6]
+func (x *StackSample) Reset() {
+	*x = StackSample{}
+	mi := &file_v1_deeptrace_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessInfoList) String() string {
+func (x *StackSample) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessInfoList) ProtoMessage() {}
+func (*StackSample) ProtoMessage() {}
 
-func (x *ProcessInfoList) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[6]
+func (x *StackSample) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -660,51 +1960,49 @@ func (x *ProcessInfoList) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessInfoList.ProtoReflect.Descriptor instead.
-func (*ProcessInfoList) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use StackSample.ProtoReflect.Descriptor instead.
+func (*StackSample) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{19}
 }
 
-func (x *ProcessInfoList) GetProcesses() []*ProcessInfo {
+func (x *StackSample) GetTime() *timestamppb.Timestamp {
 	if x != nil {
-		return x.Processes
+		return x.Time
 	}
 	return nil
 }
 
-func (x *ProcessInfoList) GetTotalCount() int32 {
+func (x *StackSample) GetProcesses() []*ProcessInfo {
 	if x != nil {
-		return x.TotalCount
+		return x.Processes
 	}
-	return 0
+	return nil
 }
 
-// Request to get process stacks
-type GetProcessStacksRequest struct {
-	state protoimpl.MessageState `protogen:"open.v1"`
-	// Target process type (required)
-	ProcessType ProcessType `protobuf:"varint,1,opt,name=process_type,json=processType,proto3,enum=v1.ProcessType" json:"process_type,omitempty"`
-	// Rank (optional)
-	Rank          string `protobuf:"bytes,2,opt,name=rank,proto3" json:"rank,omitempty"`
+// How often a frame was on the stack of a thread
+type FrameCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Frame         *StackFrame            `protobuf:"bytes,1,opt,name=frame,proto3" json:"frame,omitempty"`
+	Samples       int32                  `protobuf:"varint,2,opt,name=samples,proto3" json:"samples,omitempty"` // Number of samples with the frame on the stack
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetProcessStacksRequest) Reset() {
-	*x = GetProcessStacksRequest{}
-	mi := &file_v1_deeptrace_proto_msgTypes[7]
+func (x *FrameCount) Reset() {
+	*x = FrameCount{}
+	mi := &file_v1_deeptrace_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetProcessStacksRequest) String() string {
+func (x *FrameCount) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetProcessStacksRequest) ProtoMessage() {}
+func (*FrameCount) ProtoMessage() {}
 
-func (x *GetProcessStacksRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[7]
+func (x *FrameCount) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -715,51 +2013,55 @@ func (x *GetProcessStacksRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetProcessStacksRequest.ProtoReflect.Descriptor instead.
-func (*GetProcessStacksRequest) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use FrameCount.ProtoReflect.Descriptor instead.
+func (*FrameCount) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *GetProcessStacksRequest) GetProcessType() ProcessType {
+func (x *FrameCount) GetFrame() *StackFrame {
 	if x != nil {
-		return x.ProcessType
+		return x.Frame
 	}
-	return ProcessType_PROCESS_UNSPECIFIED
+	return nil
 }
 
-func (x *GetProcessStacksRequest) GetRank() string {
+func (x *FrameCount) GetSamples() int32 {
 	if x != nil {
-		return x.Rank
+		return x.Samples
 	}
-	return ""
+	return 0
 }
 
-// Process stack response
-type ProcessStacksResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	Processes        []*ProcessInfo         `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`                                        // Process information list
-	TotalProcesses   int32                  `protobuf:"varint,2,opt,name=total_processes,json=totalProcesses,proto3" json:"total_processes,omitempty"`       // Total number of processes of this type
-	SampledProcesses int32                  `protobuf:"varint,3,opt,name=sampled_processes,json=sampledProcesses,proto3" json:"sampled_processes,omitempty"` // Number of actually sampled processes
-	SnapshotTime     string                 `protobuf:"bytes,4,opt,name=snapshot_time,json=snapshotTime,proto3" json:"snapshot_time,omitempty"`              // Snapshot acquisition time
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+// A thread across all samples
+type ThreadSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Type          ProcessType            `protobuf:"varint,2,opt,name=type,proto3,enum=v1.ProcessType" json:"type,omitempty"`
+	Rank          string                 `protobuf:"bytes,3,opt,name=rank,proto3" json:"rank,omitempty"`
+	ThreadId      int32                  `protobuf:"varint,4,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	ThreadName    string                 `protobuf:"bytes,5,opt,name=thread_name,json=threadName,proto3" json:"thread_name,omitempty"`
+	Samples       int32                  `protobuf:"varint,6,opt,name=samples,proto3" json:"samples,omitempty"`     // Number of samples the thread was dumped in
+	Unchanged     bool                   `protobuf:"varint,7,opt,name=unchanged,proto3" json:"unchanged,omitempty"` // Identical stack in every sample it was dumped in, at least two
+	Frames        []*FrameCount          `protobuf:"bytes,8,rep,name=frames,proto3" json:"frames,omitempty"`        // Most frequent first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProcessStacksResponse) Reset() {
-	*x = ProcessStacksResponse{}
-	mi := &file_v1_deeptrace_proto_msgTypes[8]
+func (x *ThreadSummary) Reset() {
+	*x = ThreadSummary{}
+	mi := &file_v1_deeptrace_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessStacksResponse) String() string {
+func (x *ThreadSummary) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessStacksResponse) ProtoMessage() {}
+func (*ThreadSummary) ProtoMessage() {}
 
-func (x *ProcessStacksResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[8]
+func (x *ThreadSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -770,39 +2072,120 @@ func (x *ProcessStacksResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessStacksResponse.ProtoReflect.Descriptor instead.
-func (*ProcessStacksResponse) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use ThreadSummary.ProtoReflect.Descriptor instead.
+func (*ThreadSummary) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{21}
 }
 
-func (x *ProcessStacksResponse) GetProcesses() []*ProcessInfo {
+func (x *ThreadSummary) GetPid() int32 {
 	if x != nil {
-		return x.Processes
+		return x.Pid
 	}
-	return nil
+	return 0
 }
 
-func (x *ProcessStacksResponse) GetTotalProcesses() int32 {
+func (x *ThreadSummary) GetType() ProcessType {
 	if x != nil {
-		return x.TotalProcesses
+		return x.Type
 	}
-	return 0
+	return ProcessType_PROCESS_UNSPECIFIED
 }
 
-func (x *ProcessStacksResponse) GetSampledProcesses() int32 {
+func (x *ThreadSummary) GetRank() string {
 	if x != nil {
-		return x.SampledProcesses
+		return x.Rank
+	}
+	return ""
+}
+
+func (x *ThreadSummary) GetThreadId() int32 {
+	if x != nil {
+		return x.ThreadId
 	}
 	return 0
 }
 
-func (x *ProcessStacksResponse) GetSnapshotTime() string {
+func (x *ThreadSummary) GetThreadName() string {
 	if x != nil {
-		return x.SnapshotTime
+		return x.ThreadName
 	}
 	return ""
 }
 
+func (x *ThreadSummary) GetSamples() int32 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *ThreadSummary) GetUnchanged() bool {
+	if x != nil {
+		return x.Unchanged
+	}
+	return false
+}
+
+func (x *ThreadSummary) GetFrames() []*FrameCount {
+	if x != nil {
+		return x.Frames
+	}
+	return nil
+}
+
+// Stack snapshots and their summary
+type SampleProcessStacksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Samples       []*StackSample         `protobuf:"bytes,1,rep,name=samples,proto3" json:"samples,omitempty"`
+	Threads       []*ThreadSummary       `protobuf:"bytes,2,rep,name=threads,proto3" json:"threads,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SampleProcessStacksResponse) Reset() {
+	*x = SampleProcessStacksResponse{}
+	mi := &file_v1_deeptrace_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SampleProcessStacksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SampleProcessStacksResponse) ProtoMessage() {}
+
+func (x *SampleProcessStacksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_deeptrace_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SampleProcessStacksResponse.ProtoReflect.Descriptor instead.
+func (*SampleProcessStacksResponse) Descriptor() ([]byte, []int) {
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SampleProcessStacksResponse) GetSamples() []*StackSample {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+func (x *SampleProcessStacksResponse) GetThreads() []*ThreadSummary {
+	if x != nil {
+		return x.Threads
+	}
+	return nil
+}
+
 // Error details
 type ErrorDetail struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -815,7 +2198,7 @@ type ErrorDetail struct {
 
 func (x *ErrorDetail) Reset() {
 	*x = ErrorDetail{}
-	mi := &file_v1_deeptrace_proto_msgTypes[9]
+	mi := &file_v1_deeptrace_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -827,7 +2210,7 @@ func (x *ErrorDetail) String() string {
 func (*ErrorDetail) ProtoMessage() {}
 
 func (x *ErrorDetail) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[9]
+	mi := &file_v1_deeptrace_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -840,7 +2223,7 @@ func (x *ErrorDetail) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ErrorDetail.ProtoReflect.Descriptor instead.
 func (*ErrorDetail) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{9}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *ErrorDetail) GetCode() ErrorCode {
@@ -873,7 +2256,7 @@ type RestartRequest struct {
 
 func (x *RestartRequest) Reset() {
 	*x = RestartRequest{}
-	mi := &file_v1_deeptrace_proto_msgTypes[10]
+	mi := &file_v1_deeptrace_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -885,7 +2268,7 @@ func (x *RestartRequest) String() string {
 func (*RestartRequest) ProtoMessage() {}
 
 func (x *RestartRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[10]
+	mi := &file_v1_deeptrace_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -898,7 +2281,7 @@ func (x *RestartRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RestartRequest.ProtoReflect.Descriptor instead.
 func (*RestartRequest) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{10}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *RestartRequest) GetAuthToken() string {
@@ -918,7 +2301,7 @@ type RestartResponse struct {
 
 func (x *RestartResponse) Reset() {
 	*x = RestartResponse{}
-	mi := &file_v1_deeptrace_proto_msgTypes[11]
+	mi := &file_v1_deeptrace_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -930,7 +2313,7 @@ func (x *RestartResponse) String() string {
 func (*RestartResponse) ProtoMessage() {}
 
 func (x *RestartResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[11]
+	mi := &file_v1_deeptrace_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -943,7 +2326,7 @@ func (x *RestartResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RestartResponse.ProtoReflect.Descriptor instead.
 func (*RestartResponse) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{11}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *RestartResponse) GetSuccess() bool {
@@ -973,7 +2356,7 @@ type VersionResponse struct {
 
 func (x *VersionResponse) Reset() {
 	*x = VersionResponse{}
-	mi := &file_v1_deeptrace_proto_msgTypes[12]
+	mi := &file_v1_deeptrace_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -985,7 +2368,7 @@ func (x *VersionResponse) String() string {
 func (*VersionResponse) ProtoMessage() {}
 
 func (x *VersionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[12]
+	mi := &file_v1_deeptrace_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -998,7 +2381,7 @@ func (x *VersionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VersionResponse.ProtoReflect.Descriptor instead.
 func (*VersionResponse) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{12}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *VersionResponse) GetVersion() string {
@@ -1035,13 +2418,15 @@ type GetAlertsRequest struct {
 	EndTime       *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`                               // End timestamp (optional)
 	MinSeverity   Severity               `protobuf:"varint,3,opt,name=min_severity,json=minSeverity,proto3,enum=v1.Severity" json:"min_severity,omitempty"` // Minimum severity level
 	Unprocessed   bool                   `protobuf:"varint,4,opt,name=unprocessed,proto3" json:"unprocessed,omitempty"`
+	Types         []string               `protobuf:"bytes,5,rep,name=types,proto3" json:"types,omitempty"` // Event types, alerts and watchdog events if empty
+	Peek          bool                   `protobuf:"varint,6,opt,name=peek,proto3" json:"peek,omitempty"`  // Do not mark the returned events as processed
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetAlertsRequest) Reset() {
 	*x = GetAlertsRequest{}
-	mi := &file_v1_deeptrace_proto_msgTypes[13]
+	mi := &file_v1_deeptrace_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1053,7 +2438,7 @@ func (x *GetAlertsRequest) String() string {
 func (*GetAlertsRequest) ProtoMessage() {}
 
 func (x *GetAlertsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[13]
+	mi := &file_v1_deeptrace_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1066,7 +2451,7 @@ func (x *GetAlertsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetAlertsRequest.ProtoReflect.Descriptor instead.
 func (*GetAlertsRequest) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{13}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *GetAlertsRequest) GetStartTime() *timestamppb.Timestamp {
@@ -1097,18 +2482,35 @@ func (x *GetAlertsRequest) GetUnprocessed() bool {
 	return false
 }
 
+func (x *GetAlertsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *GetAlertsRequest) GetPeek() bool {
+	if x != nil {
+		return x.Peek
+	}
+	return false
+}
+
 type AlertRecord struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
 	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Severity      Severity               `protobuf:"varint,3,opt,name=severity,proto3,enum=v1.Severity" json:"severity,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`                                                                                   // Event type, e.g. "alert" or "hang"
+	Source        string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`                                                                               // Event source, e.g. "training" or "watchdog"
+	Metadata      map[string]string      `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Extended properties, non-string values are JSON encoded
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AlertRecord) Reset() {
 	*x = AlertRecord{}
-	mi := &file_v1_deeptrace_proto_msgTypes[14]
+	mi := &file_v1_deeptrace_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1120,7 +2522,7 @@ func (x *AlertRecord) String() string {
 func (*AlertRecord) ProtoMessage() {}
 
 func (x *AlertRecord) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[14]
+	mi := &file_v1_deeptrace_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1133,7 +2535,7 @@ func (x *AlertRecord) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AlertRecord.ProtoReflect.Descriptor instead.
 func (*AlertRecord) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{14}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *AlertRecord) GetMessage() string {
@@ -1157,6 +2559,27 @@ func (x *AlertRecord) GetSeverity() Severity {
 	return Severity_INFO
 }
 
+func (x *AlertRecord) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *AlertRecord) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *AlertRecord) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 type GetAlertsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Alerts        []*AlertRecord         `protobuf:"bytes,1,rep,name=alerts,proto3" json:"alerts,omitempty"`
@@ -1166,7 +2589,7 @@ type GetAlertsResponse struct {
 
 func (x *GetAlertsResponse) Reset() {
 	*x = GetAlertsResponse{}
-	mi := &file_v1_deeptrace_proto_msgTypes[15]
+	mi := &file_v1_deeptrace_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1178,7 +2601,7 @@ func (x *GetAlertsResponse) String() string {
 func (*GetAlertsResponse) ProtoMessage() {}
 
 func (x *GetAlertsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_v1_deeptrace_proto_msgTypes[15]
+	mi := &file_v1_deeptrace_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1191,7 +2614,7 @@ func (x *GetAlertsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetAlertsResponse.ProtoReflect.Descriptor instead.
 func (*GetAlertsResponse) Descriptor() ([]byte, []int) {
-	return file_v1_deeptrace_proto_rawDescGZIP(), []int{15}
+	return file_v1_deeptrace_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *GetAlertsResponse) GetAlerts() []*AlertRecord {
@@ -1205,27 +2628,101 @@ var File_v1_deeptrace_proto protoreflect.FileDescriptor
 
 const file_v1_deeptrace_proto_rawDesc = "" +
 	"\n" +
-	"\x12v1/deeptrace.proto\x12\x02v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x98\x01\n" +
+	"\x12v1/deeptrace.proto\x12\x02v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"\xde\x02\n" +
 	"\bLogEntry\x128\n" +
 	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\"\n" +
 	"\x05level\x18\x02 \x01(\x0e2\f.v1.LogLevelR\x05level\x12\x14\n" +
 	"\x05epoch\x18\x03 \x01(\x05R\x05epoch\x12\x18\n" +
-	"\amessage\x18\x04 \x01(\tR\amessage\"\xa7\x01\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x17\n" +
+	"\x04step\x18\x05 \x01(\x03H\x00R\x04step\x88\x01\x01\x12\x17\n" +
+	"\x04loss\x18\x06 \x01(\x01H\x01R\x04loss\x88\x01\x01\x12\x13\n" +
+	"\x02lr\x18\a \x01(\x01H\x02R\x02lr\x88\x01\x01\x12)\n" +
+	"\x0etokens_per_sec\x18\b \x01(\x01H\x03R\ftokensPerSec\x88\x01\x01\x12\x1b\n" +
+	"\x06tflops\x18\t \x01(\x01H\x04R\x06tflops\x88\x01\x01B\a\n" +
+	"\x05_stepB\a\n" +
+	"\x05_lossB\x05\n" +
+	"\x03_lrB\x11\n" +
+	"\x0f_tokens_per_secB\t\n" +
+	"\a_tflops\"\xf9\x01\n" +
 	"\aRankLog\x12\x12\n" +
 	"\x04rank\x18\x01 \x01(\tR\x04rank\x12&\n" +
 	"\aentries\x18\x02 \x03(\v2\f.v1.LogEntryR\aentries\x12'\n" +
 	"\x0fsuspend_seconds\x18\x03 \x01(\x05R\x0esuspendSeconds\x127\n" +
-	"\ttail_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\btailTime\"N\n" +
+	"\ttail_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\btailTime\x12)\n" +
+	"\x06status\x18\x05 \x01(\x0e2\x11.v1.RankLogStatusR\x06status\x12%\n" +
+	"\x0estatus_message\x18\x06 \x01(\tR\rstatusMessage\"\xb4\x02\n" +
 	"\x14GetRecentLogsRequest\x12\x1b\n" +
 	"\tmax_lines\x18\x01 \x01(\x05R\bmaxLines\x12\x19\n" +
-	"\bwork_dir\x18\x02 \x01(\tR\aworkDir\"6\n" +
+	"\bwork_dir\x18\x02 \x01(\tR\aworkDir\x12$\n" +
+	"\x06levels\x18\x03 \x03(\x0e2\f.v1.LogLevelR\x06levels\x12%\n" +
+	"\x0esearch_keyword\x18\x04 \x01(\tR\rsearchKeyword\x12\x14\n" +
+	"\x05regex\x18\x05 \x01(\tR\x05regex\x120\n" +
+	"\x05since\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1d\n" +
+	"\n" +
+	"log_format\x18\b \x01(\tR\tlogFormat\"6\n" +
 	"\vLogResponse\x12'\n" +
-	"\branklogs\x18\x01 \x03(\v2\v.v1.RankLogR\branklogs\"n\n" +
+	"\branklogs\x18\x01 \x03(\v2\v.v1.RankLogR\branklogs\"\xb3\x02\n" +
+	"\x11FollowLogsRequest\x12\x19\n" +
+	"\bwork_dir\x18\x01 \x01(\tR\aworkDir\x12\x1d\n" +
+	"\n" +
+	"tail_lines\x18\x02 \x01(\x05R\ttailLines\x12$\n" +
+	"\x06levels\x18\x03 \x03(\x0e2\f.v1.LogLevelR\x06levels\x12%\n" +
+	"\x0esearch_keyword\x18\x04 \x01(\tR\rsearchKeyword\x12\x14\n" +
+	"\x05regex\x18\x05 \x01(\tR\x05regex\x120\n" +
+	"\x05since\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1d\n" +
+	"\n" +
+	"log_format\x18\b \x01(\tR\tlogFormat\"3\n" +
+	"\x16ResolveLogFilesRequest\x12\x19\n" +
+	"\bwork_dir\x18\x01 \x01(\tR\aworkDir\"\x9c\x01\n" +
+	"\x0fResolvedLogFile\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\tR\x04rank\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x16\n" +
+	"\x06exists\x18\x03 \x01(\bR\x06exists\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x125\n" +
+	"\bmod_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\amodTime\"\xac\x01\n" +
+	"\x17ResolveLogFilesResponse\x12\x19\n" +
+	"\bwork_dir\x18\x01 \x01(\tR\aworkDir\x12\x16\n" +
+	"\x06layout\x18\x02 \x01(\tR\x06layout\x12\x17\n" +
+	"\arun_dir\x18\x03 \x01(\tR\x06runDir\x12)\n" +
+	"\x05files\x18\x04 \x03(\v2\x13.v1.ResolvedLogFileR\x05files\x12\x1a\n" +
+	"\bwarnings\x18\x05 \x03(\tR\bwarnings\"M\n" +
+	"\x13GetLogErrorsRequest\x12\x19\n" +
+	"\bwork_dir\x18\x01 \x01(\tR\aworkDir\x12\x1b\n" +
+	"\tmax_lines\x18\x02 \x01(\x05R\bmaxLines\"\xd5\x01\n" +
+	"\bLogError\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x18\n" +
+	"\asummary\x18\x02 \x01(\tR\asummary\x12\x14\n" +
+	"\x05lines\x18\x03 \x03(\tR\x05lines\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x05R\x05count\x123\n" +
+	"\acontext\x18\x05 \x03(\v2\x19.v1.LogError.ContextEntryR\acontext\x1a:\n" +
+	"\fContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x9b\x01\n" +
+	"\rRankLogErrors\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\tR\x04rank\x12$\n" +
+	"\x06errors\x18\x02 \x03(\v2\f.v1.LogErrorR\x06errors\x12)\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x11.v1.RankLogStatusR\x06status\x12%\n" +
+	"\x0estatus_message\x18\x04 \x01(\tR\rstatusMessage\"B\n" +
+	"\x11LogErrorsResponse\x12-\n" +
+	"\branklogs\x18\x01 \x03(\v2\x11.v1.RankLogErrorsR\branklogs\"\xab\x01\n" +
+	"\n" +
+	"StackFrame\x12-\n" +
+	"\blanguage\x18\x01 \x01(\x0e2\x11.v1.FrameLanguageR\blanguage\x12\x1a\n" +
+	"\bfunction\x18\x02 \x01(\tR\bfunction\x12\x12\n" +
+	"\x04file\x18\x03 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x04 \x01(\x05R\x04line\x12\x12\n" +
+	"\x04code\x18\x05 \x01(\tR\x04code\x12\x16\n" +
+	"\x06module\x18\x06 \x01(\tR\x06module\"\xc7\x01\n" +
 	"\vThreadStack\x12\x1b\n" +
 	"\tthread_id\x18\x01 \x01(\x05R\bthreadId\x12\x1f\n" +
 	"\vthread_name\x18\x02 \x01(\tR\n" +
 	"threadName\x12!\n" +
-	"\fstack_frames\x18\x03 \x03(\tR\vstackFrames\"\xb6\x01\n" +
+	"\fstack_frames\x18\x03 \x03(\tR\vstackFrames\x12&\n" +
+	"\x06frames\x18\x04 \x03(\v2\x0e.v1.StackFrameR\x06frames\x12\x1b\n" +
+	"\tholds_gil\x18\x05 \x01(\bR\bholdsGil\x12\x12\n" +
+	"\x04idle\x18\x06 \x01(\bR\x04idle\"\x8f\x02\n" +
 	"\vProcessInfo\x12\x10\n" +
 	"\x03pid\x18\x01 \x01(\x05R\x03pid\x12\x12\n" +
 	"\x04ppid\x18\x02 \x01(\x05R\x04ppid\x12#\n" +
@@ -1233,19 +2730,55 @@ const file_v1_deeptrace_proto_rawDesc = "" +
 	"\athreads\x18\x04 \x03(\v2\x0f.v1.ThreadStackR\athreads\x12\x12\n" +
 	"\x04rank\x18\x05 \x01(\tR\x04rank\x12\x1d\n" +
 	"\n" +
-	"local_rank\x18\x06 \x01(\tR\tlocalRank\"a\n" +
+	"local_rank\x18\x06 \x01(\tR\tlocalRank\x12\x18\n" +
+	"\abackend\x18\a \x01(\tR\abackend\x12'\n" +
+	"\x06status\x18\b \x01(\x0e2\x0f.v1.StackStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"a\n" +
 	"\x0fProcessInfoList\x12-\n" +
 	"\tprocesses\x18\x01 \x03(\v2\x0f.v1.ProcessInfoR\tprocesses\x12\x1f\n" +
 	"\vtotal_count\x18\x02 \x01(\x05R\n" +
-	"totalCount\"a\n" +
+	"totalCount\"\xd5\x01\n" +
 	"\x17GetProcessStacksRequest\x122\n" +
 	"\fprocess_type\x18\x01 \x01(\x0e2\x0f.v1.ProcessTypeR\vprocessType\x12\x12\n" +
-	"\x04rank\x18\x02 \x01(\tR\x04rank\"\xc1\x01\n" +
+	"\x04rank\x18\x02 \x01(\tR\x04rank\x12/\n" +
+	"\vnative_mode\x18\x03 \x01(\x0e2\x0e.v1.NativeModeR\n" +
+	"nativeMode\x12\x18\n" +
+	"\abackend\x18\x04 \x01(\tR\abackend\x12'\n" +
+	"\x0ftimeout_seconds\x18\x05 \x01(\x05R\x0etimeoutSeconds\"\xc1\x01\n" +
 	"\x15ProcessStacksResponse\x12-\n" +
 	"\tprocesses\x18\x01 \x03(\v2\x0f.v1.ProcessInfoR\tprocesses\x12'\n" +
 	"\x0ftotal_processes\x18\x02 \x01(\x05R\x0etotalProcesses\x12+\n" +
 	"\x11sampled_processes\x18\x03 \x01(\x05R\x10sampledProcesses\x12#\n" +
-	"\rsnapshot_time\x18\x04 \x01(\tR\fsnapshotTime\"\xbe\x01\n" +
+	"\rsnapshot_time\x18\x04 \x01(\tR\fsnapshotTime\"\x99\x02\n" +
+	"\x1aSampleProcessStacksRequest\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x12)\n" +
+	"\x10interval_seconds\x18\x02 \x01(\x05R\x0fintervalSeconds\x122\n" +
+	"\fprocess_type\x18\x03 \x01(\x0e2\x0f.v1.ProcessTypeR\vprocessType\x12\x12\n" +
+	"\x04rank\x18\x04 \x01(\tR\x04rank\x12/\n" +
+	"\vnative_mode\x18\x05 \x01(\x0e2\x0e.v1.NativeModeR\n" +
+	"nativeMode\x12\x18\n" +
+	"\abackend\x18\x06 \x01(\tR\abackend\x12'\n" +
+	"\x0ftimeout_seconds\x18\a \x01(\x05R\x0etimeoutSeconds\"l\n" +
+	"\vStackSample\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12-\n" +
+	"\tprocesses\x18\x02 \x03(\v2\x0f.v1.ProcessInfoR\tprocesses\"L\n" +
+	"\n" +
+	"FrameCount\x12$\n" +
+	"\x05frame\x18\x01 \x01(\v2\x0e.v1.StackFrameR\x05frame\x12\x18\n" +
+	"\asamples\x18\x02 \x01(\x05R\asamples\"\xf8\x01\n" +
+	"\rThreadSummary\x12\x10\n" +
+	"\x03pid\x18\x01 \x01(\x05R\x03pid\x12#\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x0f.v1.ProcessTypeR\x04type\x12\x12\n" +
+	"\x04rank\x18\x03 \x01(\tR\x04rank\x12\x1b\n" +
+	"\tthread_id\x18\x04 \x01(\x05R\bthreadId\x12\x1f\n" +
+	"\vthread_name\x18\x05 \x01(\tR\n" +
+	"threadName\x12\x18\n" +
+	"\asamples\x18\x06 \x01(\x05R\asamples\x12\x1c\n" +
+	"\tunchanged\x18\a \x01(\bR\tunchanged\x12&\n" +
+	"\x06frames\x18\b \x03(\v2\x0e.v1.FrameCountR\x06frames\"u\n" +
+	"\x1bSampleProcessStacksResponse\x12)\n" +
+	"\asamples\x18\x01 \x03(\v2\x0f.v1.StackSampleR\asamples\x12+\n" +
+	"\athreads\x18\x02 \x03(\v2\x11.v1.ThreadSummaryR\athreads\"\xbe\x01\n" +
 	"\vErrorDetail\x12!\n" +
 	"\x04code\x18\x01 \x01(\x0e2\r.v1.ErrorCodeR\x04code\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x126\n" +
@@ -1264,17 +2797,25 @@ const file_v1_deeptrace_proto_rawDesc = "" +
 	"\x06commit\x18\x02 \x01(\tR\x06commit\x12\x1d\n" +
 	"\n" +
 	"build_time\x18\x03 \x01(\tR\tbuildTime\x12\x1b\n" +
-	"\tbuild_tag\x18\x04 \x01(\tR\bbuildTag\"\xd7\x01\n" +
+	"\tbuild_tag\x18\x04 \x01(\tR\bbuildTag\"\x81\x02\n" +
 	"\x10GetAlertsRequest\x129\n" +
 	"\n" +
 	"start_time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
 	"\bend_time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12/\n" +
 	"\fmin_severity\x18\x03 \x01(\x0e2\f.v1.SeverityR\vminSeverity\x12 \n" +
-	"\vunprocessed\x18\x04 \x01(\bR\vunprocessed\"\x8b\x01\n" +
+	"\vunprocessed\x18\x04 \x01(\bR\vunprocessed\x12\x14\n" +
+	"\x05types\x18\x05 \x03(\tR\x05types\x12\x12\n" +
+	"\x04peek\x18\x06 \x01(\bR\x04peek\"\xaf\x02\n" +
 	"\vAlertRecord\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x128\n" +
 	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12(\n" +
-	"\bseverity\x18\x03 \x01(\x0e2\f.v1.SeverityR\bseverity\"<\n" +
+	"\bseverity\x18\x03 \x01(\x0e2\f.v1.SeverityR\bseverity\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x129\n" +
+	"\bmetadata\x18\x06 \x03(\v2\x1d.v1.AlertRecord.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"<\n" +
 	"\x11GetAlertsResponse\x12'\n" +
 	"\x06alerts\x18\x01 \x03(\v2\x0f.v1.AlertRecordR\x06alerts*n\n" +
 	"\bLogLevel\x12\x13\n" +
@@ -1283,12 +2824,37 @@ const file_v1_deeptrace_proto_rawDesc = "" +
 	"\bLOG_INFO\x10\x02\x12\x0f\n" +
 	"\vLOG_WARNING\x10\x03\x12\r\n" +
 	"\tLOG_ERROR\x10\x04\x12\x10\n" +
-	"\fLOG_CRITICAL\x10\x05*j\n" +
+	"\fLOG_CRITICAL\x10\x05*\xa0\x01\n" +
+	"\rRankLogStatus\x12\x18\n" +
+	"\x14RANK_LOG_UNSPECIFIED\x10\x00\x12\x0f\n" +
+	"\vRANK_LOG_OK\x10\x01\x12\x19\n" +
+	"\x15RANK_LOG_FILE_MISSING\x10\x02\x12\x17\n" +
+	"\x13RANK_LOG_READ_ERROR\x10\x03\x12\x18\n" +
+	"\x14RANK_LOG_PARSE_ERROR\x10\x04\x12\x16\n" +
+	"\x12RANK_LOG_TRUNCATED\x10\x05*j\n" +
 	"\vProcessType\x12\x17\n" +
 	"\x13PROCESS_UNSPECIFIED\x10\x00\x12\x13\n" +
 	"\x0fPROCESS_TRAINER\x10\x01\x12\x17\n" +
 	"\x13PROCESS_DATA_LOADER\x10\x02\x12\x14\n" +
-	"\x10PROCESS_LAUNCHER\x10\x03*\xb3\x01\n" +
+	"\x10PROCESS_LAUNCHER\x10\x03*S\n" +
+	"\rFrameLanguage\x12\x1e\n" +
+	"\x1aFRAME_LANGUAGE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fFRAME_PYTHON\x10\x01\x12\x10\n" +
+	"\fFRAME_NATIVE\x10\x02*8\n" +
+	"\n" +
+	"NativeMode\x12\x0e\n" +
+	"\n" +
+	"NATIVE_OFF\x10\x00\x12\n" +
+	"\n" +
+	"\x06NATIVE\x10\x01\x12\x0e\n" +
+	"\n" +
+	"NATIVE_ALL\x10\x02*k\n" +
+	"\vStackStatus\x12\x15\n" +
+	"\x11STACK_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bSTACK_OK\x10\x01\x12\x10\n" +
+	"\fSTACK_FAILED\x10\x02\x12\x11\n" +
+	"\rSTACK_TIMEOUT\x10\x03\x12\x12\n" +
+	"\x0eSTACK_CANCELED\x10\x04*\xb3\x01\n" +
 	"\tErrorCode\x12\x11\n" +
 	"\rERROR_UNKNOWN\x10\x00\x12\x1e\n" +
 	"\x1aERROR_INVALID_PROCESS_TYPE\x10\x01\x12\x1b\n" +
@@ -1300,10 +2866,15 @@ const file_v1_deeptrace_proto_rawDesc = "" +
 	"\x04INFO\x10\x00\x12\v\n" +
 	"\aWARNING\x10\x01\x12\t\n" +
 	"\x05ERROR\x10\x02\x12\f\n" +
-	"\bCRITICAL\x10\x032\x8f\x02\n" +
+	"\bCRITICAL\x10\x032\xa7\x04\n" +
 	"\x10DeepTraceService\x12:\n" +
-	"\rGetRecentLogs\x12\x18.v1.GetRecentLogsRequest\x1a\x0f.v1.LogResponse\x12J\n" +
-	"\x10GetProcessStacks\x12\x1b.v1.GetProcessStacksRequest\x1a\x19.v1.ProcessStacksResponse\x128\n" +
+	"\rGetRecentLogs\x12\x18.v1.GetRecentLogsRequest\x1a\x0f.v1.LogResponse\x122\n" +
+	"\n" +
+	"FollowLogs\x12\x15.v1.FollowLogsRequest\x1a\v.v1.RankLog0\x01\x12J\n" +
+	"\x0fResolveLogFiles\x12\x1a.v1.ResolveLogFilesRequest\x1a\x1b.v1.ResolveLogFilesResponse\x12>\n" +
+	"\fGetLogErrors\x12\x17.v1.GetLogErrorsRequest\x1a\x15.v1.LogErrorsResponse\x12J\n" +
+	"\x10GetProcessStacks\x12\x1b.v1.GetProcessStacksRequest\x1a\x19.v1.ProcessStacksResponse\x12V\n" +
+	"\x13SampleProcessStacks\x12\x1e.v1.SampleProcessStacksRequest\x1a\x1f.v1.SampleProcessStacksResponse\x128\n" +
 	"\rRestartServer\x12\x12.v1.RestartRequest\x1a\x13.v1.RestartResponse\x129\n" +
 	"\n" +
 	"GetVersion\x12\x16.google.protobuf.Empty\x1a\x13.v1.VersionResponse2J\n" +
@@ -1322,67 +2893,122 @@ func file_v1_deeptrace_proto_rawDescGZIP() []byte {
 	return file_v1_deeptrace_proto_rawDescData
 }
 
-var file_v1_deeptrace_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
-var file_v1_deeptrace_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_v1_deeptrace_proto_enumTypes = make([]protoimpl.EnumInfo, 8)
+var file_v1_deeptrace_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
 var file_v1_deeptrace_proto_goTypes = []any{
-	(LogLevel)(0),                   // 0: v1.LogLevel
-	(ProcessType)(0),                // 1: v1.ProcessType
-	(ErrorCode)(0),                  // 2: v1.ErrorCode
-	(Severity)(0),                   // 3: v1.Severity
-	(*LogEntry)(nil),                // 4: v1.LogEntry
-	(*RankLog)(nil),                 // 5: v1.RankLog
-	(*GetRecentLogsRequest)(nil),    // 6: v1.GetRecentLogsRequest
-	(*LogResponse)(nil),             // 7: v1.LogResponse
-	(*ThreadStack)(nil),             // 8: v1.ThreadStack
-	(*ProcessInfo)(nil),             // 9: v1.ProcessInfo
-	(*ProcessInfoList)(nil),         // 10: v1.ProcessInfoList
-	(*GetProcessStacksRequest)(nil), // 11: v1.GetProcessStacksRequest
-	(*ProcessStacksResponse)(nil),   // 12: v1.ProcessStacksResponse
-	(*ErrorDetail)(nil),             // 13: v1.ErrorDetail
-	(*RestartRequest)(nil),          // 14: v1.RestartRequest
-	(*RestartResponse)(nil),         // 15: v1.RestartResponse
-	(*VersionResponse)(nil),         // 16: v1.VersionResponse
-	(*GetAlertsRequest)(nil),        // 17: v1.GetAlertsRequest
-	(*AlertRecord)(nil),             // 18: v1.AlertRecord
-	(*GetAlertsResponse)(nil),       // 19: v1.GetAlertsResponse
-	nil,                             // 20: v1.ErrorDetail.ContextEntry
-	(*timestamppb.Timestamp)(nil),   // 21: google.protobuf.Timestamp
-	(*emptypb.Empty)(nil),           // 22: google.protobuf.Empty
+	(LogLevel)(0),                       // 0: v1.LogLevel
+	(RankLogStatus)(0),                  // 1: v1.RankLogStatus
+	(ProcessType)(0),                    // 2: v1.ProcessType
+	(FrameLanguage)(0),                  // 3: v1.FrameLanguage
+	(NativeMode)(0),                     // 4: v1.NativeMode
+	(StackStatus)(0),                    // 5: v1.StackStatus
+	(ErrorCode)(0),                      // 6: v1.ErrorCode
+	(Severity)(0),                       // 7: v1.Severity
+	(*LogEntry)(nil),                    // 8: v1.LogEntry
+	(*RankLog)(nil),                     // 9: v1.RankLog
+	(*GetRecentLogsRequest)(nil),        // 10: v1.GetRecentLogsRequest
+	(*LogResponse)(nil),                 // 11: v1.LogResponse
+	(*FollowLogsRequest)(nil),           // 12: v1.FollowLogsRequest
+	(*ResolveLogFilesRequest)(nil),      // 13: v1.ResolveLogFilesRequest
+	(*ResolvedLogFile)(nil),             // 14: v1.ResolvedLogFile
+	(*ResolveLogFilesResponse)(nil),     // 15: v1.ResolveLogFilesResponse
+	(*GetLogErrorsRequest)(nil),         // 16: v1.GetLogErrorsRequest
+	(*LogError)(nil),                    // 17: v1.LogError
+	(*RankLogErrors)(nil),               // 18: v1.RankLogErrors
+	(*LogErrorsResponse)(nil),           // 19: v1.LogErrorsResponse
+	(*StackFrame)(nil),                  // 20: v1.StackFrame
+	(*ThreadStack)(nil),                 // 21: v1.ThreadStack
+	(*ProcessInfo)(nil),                 // 22: v1.ProcessInfo
+	(*ProcessInfoList)(nil),             // 23: v1.ProcessInfoList
+	(*GetProcessStacksRequest)(nil),     // 24: v1.GetProcessStacksRequest
+	(*ProcessStacksResponse)(nil),       // 25: v1.ProcessStacksResponse
+	(*SampleProcessStacksRequest)(nil),  // 26: v1.SampleProcessStacksRequest
+	(*StackSample)(nil),                 // 27: v1.StackSample
+	(*FrameCount)(nil),                  // 28: v1.FrameCount
+	(*ThreadSummary)(nil),               // 29: v1.ThreadSummary
+	(*SampleProcessStacksResponse)(nil), // 30: v1.SampleProcessStacksResponse
+	(*ErrorDetail)(nil),                 // 31: v1.ErrorDetail
+	(*RestartRequest)(nil),              // 32: v1.RestartRequest
+	(*RestartResponse)(nil),             // 33: v1.RestartResponse
+	(*VersionResponse)(nil),             // 34: v1.VersionResponse
+	(*GetAlertsRequest)(nil),            // 35: v1.GetAlertsRequest
+	(*AlertRecord)(nil),                 // 36: v1.AlertRecord
+	(*GetAlertsResponse)(nil),           // 37: v1.GetAlertsResponse
+	nil,                                 // 38: v1.LogError.ContextEntry
+	nil,                                 // 39: v1.ErrorDetail.ContextEntry
+	nil,                                 // 40: v1.AlertRecord.MetadataEntry
+	(*timestamppb.Timestamp)(nil),       // 41: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),               // 42: google.protobuf.Empty
 }
 var file_v1_deeptrace_proto_depIdxs = []int32{
-	21, // 0: v1.LogEntry.timestamp:type_name -> google.protobuf.Timestamp
+	41, // 0: v1.LogEntry.timestamp:type_name -> google.protobuf.Timestamp
 	0,  // 1: v1.LogEntry.level:type_name -> v1.LogLevel
-	4,  // 2: v1.RankLog.entries:type_name -> v1.LogEntry
-	21, // 3: v1.RankLog.tail_time:type_name -> google.protobuf.Timestamp
-	5,  // 4: v1.LogResponse.ranklogs:type_name -> v1.RankLog
-	1,  // 5: v1.ProcessInfo.type:type_name -> v1.ProcessType
-	8,  // 6: v1.ProcessInfo.threads:type_name -> v1.ThreadStack
-	9,  // 7: v1.ProcessInfoList.processes:type_name -> v1.ProcessInfo
-	1,  // 8: v1.GetProcessStacksRequest.process_type:type_name -> v1.ProcessType
-	9,  // 9: v1.ProcessStacksResponse.processes:type_name -> v1.ProcessInfo
-	2,  // 10: v1.ErrorDetail.code:type_name -> v1.ErrorCode
-	20, // 11: v1.ErrorDetail.context:type_name -> v1.ErrorDetail.ContextEntry
-	21, // 12: v1.GetAlertsRequest.start_time:type_name -> google.protobuf.Timestamp
-	21, // 13: v1.GetAlertsRequest.end_time:type_name -> google.protobuf.Timestamp
-	3,  // 14: v1.GetAlertsRequest.min_severity:type_name -> v1.Severity
-	21, // 15: v1.AlertRecord.timestamp:type_name -> google.protobuf.Timestamp
-	3,  // 16: v1.AlertRecord.severity:type_name -> v1.Severity
-	18, // 17: v1.GetAlertsResponse.alerts:type_name -> v1.AlertRecord
-	6,  // 18: v1.DeepTraceService.GetRecentLogs:input_type -> v1.GetRecentLogsRequest
-	11, // 19: v1.DeepTraceService.GetProcessStacks:input_type -> v1.GetProcessStacksRequest
-	14, // 20: v1.DeepTraceService.RestartServer:input_type -> v1.RestartRequest
-	22, // 21: v1.DeepTraceService.GetVersion:input_type -> google.protobuf.Empty
-	17, // 22: v1.AlertService.GetAlerts:input_type -> v1.GetAlertsRequest
-	7,  // 23: v1.DeepTraceService.GetRecentLogs:output_type -> v1.LogResponse
-	12, // 24: v1.DeepTraceService.GetProcessStacks:output_type -> v1.ProcessStacksResponse
-	15, // 25: v1.DeepTraceService.RestartServer:output_type -> v1.RestartResponse
-	16, // 26: v1.DeepTraceService.GetVersion:output_type -> v1.VersionResponse
-	19, // 27: v1.AlertService.GetAlerts:output_type -> v1.GetAlertsResponse
-	23, // [23:28] is the sub-list for method output_type
-	18, // [18:23] is the sub-list for method input_type
-	18, // [18:18] is the sub-list for extension type_name
-	18, // [18:18] is the sub-list for extension extendee
-	0,  // [0:18] is the sub-list for field type_name
+	8,  // 2: v1.RankLog.entries:type_name -> v1.LogEntry
+	41, // 3: v1.RankLog.tail_time:type_name -> google.protobuf.Timestamp
+	1,  // 4: v1.RankLog.status:type_name -> v1.RankLogStatus
+	0,  // 5: v1.GetRecentLogsRequest.levels:type_name -> v1.LogLevel
+	41, // 6: v1.GetRecentLogsRequest.since:type_name -> google.protobuf.Timestamp
+	41, // 7: v1.GetRecentLogsRequest.until:type_name -> google.protobuf.Timestamp
+	9,  // 8: v1.LogResponse.ranklogs:type_name -> v1.RankLog
+	0,  // 9: v1.FollowLogsRequest.levels:type_name -> v1.LogLevel
+	41, // 10: v1.FollowLogsRequest.since:type_name -> google.protobuf.Timestamp
+	41, // 11: v1.FollowLogsRequest.until:type_name -> google.protobuf.Timestamp
+	41, // 12: v1.ResolvedLogFile.mod_time:type_name -> google.protobuf.Timestamp
+	14, // 13: v1.ResolveLogFilesResponse.files:type_name -> v1.ResolvedLogFile
+	38, // 14: v1.LogError.context:type_name -> v1.LogError.ContextEntry
+	17, // 15: v1.RankLogErrors.errors:type_name -> v1.LogError
+	1,  // 16: v1.RankLogErrors.status:type_name -> v1.RankLogStatus
+	18, // 17: v1.LogErrorsResponse.ranklogs:type_name -> v1.RankLogErrors
+	3,  // 18: v1.StackFrame.language:type_name -> v1.FrameLanguage
+	20, // 19: v1.ThreadStack.frames:type_name -> v1.StackFrame
+	2,  // 20: v1.ProcessInfo.type:type_name -> v1.ProcessType
+	21, // 21: v1.ProcessInfo.threads:type_name -> v1.ThreadStack
+	5,  // 22: v1.ProcessInfo.status:type_name -> v1.StackStatus
+	22, // 23: v1.ProcessInfoList.processes:type_name -> v1.ProcessInfo
+	2,  // 24: v1.GetProcessStacksRequest.process_type:type_name -> v1.ProcessType
+	4,  // 25: v1.GetProcessStacksRequest.native_mode:type_name -> v1.NativeMode
+	22, // 26: v1.ProcessStacksResponse.processes:type_name -> v1.ProcessInfo
+	2,  // 27: v1.SampleProcessStacksRequest.process_type:type_name -> v1.ProcessType
+	4,  // 28: v1.SampleProcessStacksRequest.native_mode:type_name -> v1.NativeMode
+	41, // 29: v1.StackSample.time:type_name -> google.protobuf.Timestamp
+	22, // 30: v1.StackSample.processes:type_name -> v1.ProcessInfo
+	20, // 31: v1.FrameCount.frame:type_name -> v1.StackFrame
+	2,  // 32: v1.ThreadSummary.type:type_name -> v1.ProcessType
+	28, // 33: v1.ThreadSummary.frames:type_name -> v1.FrameCount
+	27, // 34: v1.SampleProcessStacksResponse.samples:type_name -> v1.StackSample
+	29, // 35: v1.SampleProcessStacksResponse.threads:type_name -> v1.ThreadSummary
+	6,  // 36: v1.ErrorDetail.code:type_name -> v1.ErrorCode
+	39, // 37: v1.ErrorDetail.context:type_name -> v1.ErrorDetail.ContextEntry
+	41, // 38: v1.GetAlertsRequest.start_time:type_name -> google.protobuf.Timestamp
+	41, // 39: v1.GetAlertsRequest.end_time:type_name -> google.protobuf.Timestamp
+	7,  // 40: v1.GetAlertsRequest.min_severity:type_name -> v1.Severity
+	41, // 41: v1.AlertRecord.timestamp:type_name -> google.protobuf.Timestamp
+	7,  // 42: v1.AlertRecord.severity:type_name -> v1.Severity
+	40, // 43: v1.AlertRecord.metadata:type_name -> v1.AlertRecord.MetadataEntry
+	36, // 44: v1.GetAlertsResponse.alerts:type_name -> v1.AlertRecord
+	10, // 45: v1.DeepTraceService.GetRecentLogs:input_type -> v1.GetRecentLogsRequest
+	12, // 46: v1.DeepTraceService.FollowLogs:input_type -> v1.FollowLogsRequest
+	13, // 47: v1.DeepTraceService.ResolveLogFiles:input_type -> v1.ResolveLogFilesRequest
+	16, // 48: v1.DeepTraceService.GetLogErrors:input_type -> v1.GetLogErrorsRequest
+	24, // 49: v1.DeepTraceService.GetProcessStacks:input_type -> v1.GetProcessStacksRequest
+	26, // 50: v1.DeepTraceService.SampleProcessStacks:input_type -> v1.SampleProcessStacksRequest
+	32, // 51: v1.DeepTraceService.RestartServer:input_type -> v1.RestartRequest
+	42, // 52: v1.DeepTraceService.GetVersion:input_type -> google.protobuf.Empty
+	35, // 53: v1.AlertService.GetAlerts:input_type -> v1.GetAlertsRequest
+	11, // 54: v1.DeepTraceService.GetRecentLogs:output_type -> v1.LogResponse
+	9,  // 55: v1.DeepTraceService.FollowLogs:output_type -> v1.RankLog
+	15, // 56: v1.DeepTraceService.ResolveLogFiles:output_type -> v1.ResolveLogFilesResponse
+	19, // 57: v1.DeepTraceService.GetLogErrors:output_type -> v1.LogErrorsResponse
+	25, // 58: v1.DeepTraceService.GetProcessStacks:output_type -> v1.ProcessStacksResponse
+	30, // 59: v1.DeepTraceService.SampleProcessStacks:output_type -> v1.SampleProcessStacksResponse
+	33, // 60: v1.DeepTraceService.RestartServer:output_type -> v1.RestartResponse
+	34, // 61: v1.DeepTraceService.GetVersion:output_type -> v1.VersionResponse
+	37, // 62: v1.AlertService.GetAlerts:output_type -> v1.GetAlertsResponse
+	54, // [54:63] is the sub-list for method output_type
+	45, // [45:54] is the sub-list for method input_type
+	45, // [45:45] is the sub-list for extension type_name
+	45, // [45:45] is the sub-list for extension extendee
+	0,  // [0:45] is the sub-list for field type_name
 }
 
 func init() { file_v1_deeptrace_proto_init() }
@@ -1390,13 +3016,14 @@ func file_v1_deeptrace_proto_init() {
 	if File_v1_deeptrace_proto != nil {
 		return
 	}
+	file_v1_deeptrace_proto_msgTypes[0].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v1_deeptrace_proto_rawDesc), len(file_v1_deeptrace_proto_rawDesc)),
-			NumEnums:      4,
-			NumMessages:   17,
+			NumEnums:      8,
+			NumMessages:   33,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
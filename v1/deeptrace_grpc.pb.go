@@ -20,10 +20,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DeepTraceService_GetRecentLogs_FullMethodName    = "/v1.DeepTraceService/GetRecentLogs"
-	DeepTraceService_GetProcessStacks_FullMethodName = "/v1.DeepTraceService/GetProcessStacks"
-	DeepTraceService_RestartServer_FullMethodName    = "/v1.DeepTraceService/RestartServer"
-	DeepTraceService_GetVersion_FullMethodName       = "/v1.DeepTraceService/GetVersion"
+	DeepTraceService_GetRecentLogs_FullMethodName       = "/v1.DeepTraceService/GetRecentLogs"
+	DeepTraceService_FollowLogs_FullMethodName          = "/v1.DeepTraceService/FollowLogs"
+	DeepTraceService_ResolveLogFiles_FullMethodName     = "/v1.DeepTraceService/ResolveLogFiles"
+	DeepTraceService_GetLogErrors_FullMethodName        = "/v1.DeepTraceService/GetLogErrors"
+	DeepTraceService_GetProcessStacks_FullMethodName    = "/v1.DeepTraceService/GetProcessStacks"
+	DeepTraceService_SampleProcessStacks_FullMethodName = "/v1.DeepTraceService/SampleProcessStacks"
+	DeepTraceService_RestartServer_FullMethodName       = "/v1.DeepTraceService/RestartServer"
+	DeepTraceService_GetVersion_FullMethodName          = "/v1.DeepTraceService/GetVersion"
 )
 
 // DeepTraceServiceClient is the client API for DeepTraceService service.
@@ -34,8 +38,16 @@ const (
 type DeepTraceServiceClient interface {
 	// Get the most recent n lines of logs
 	GetRecentLogs(ctx context.Context, in *GetRecentLogsRequest, opts ...grpc.CallOption) (*LogResponse, error)
+	// Follow the logs of all ranks, streaming new entries as they are written
+	FollowLogs(ctx context.Context, in *FollowLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RankLog], error)
+	// Report which log file is used for each rank without reading them
+	ResolveLogFiles(ctx context.Context, in *ResolveLogFilesRequest, opts ...grpc.CallOption) (*ResolveLogFilesResponse, error)
+	// Extract Python tracebacks and known fatal errors from the tail of the rank logs
+	GetLogErrors(ctx context.Context, in *GetLogErrorsRequest, opts ...grpc.CallOption) (*LogErrorsResponse, error)
 	// Get process stack information by process type
 	GetProcessStacks(ctx context.Context, in *GetProcessStacksRequest, opts ...grpc.CallOption) (*ProcessStacksResponse, error)
+	// Take several stack snapshots on the node and summarize what did not change
+	SampleProcessStacks(ctx context.Context, in *SampleProcessStacksRequest, opts ...grpc.CallOption) (*SampleProcessStacksResponse, error)
 	// Restart server
 	RestartServer(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*RestartResponse, error)
 	// Get version information
@@ -60,6 +72,45 @@ func (c *deepTraceServiceClient) GetRecentLogs(ctx context.Context, in *GetRecen
 	return out, nil
 }
 
+func (c *deepTraceServiceClient) FollowLogs(ctx context.Context, in *FollowLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RankLog], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DeepTraceService_ServiceDesc.Streams[0], DeepTraceService_FollowLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FollowLogsRequest, RankLog]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DeepTraceService_FollowLogsClient = grpc.ServerStreamingClient[RankLog]
+
+func (c *deepTraceServiceClient) ResolveLogFiles(ctx context.Context, in *ResolveLogFilesRequest, opts ...grpc.CallOption) (*ResolveLogFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResolveLogFilesResponse)
+	err := c.cc.Invoke(ctx, DeepTraceService_ResolveLogFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deepTraceServiceClient) GetLogErrors(ctx context.Context, in *GetLogErrorsRequest, opts ...grpc.CallOption) (*LogErrorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogErrorsResponse)
+	err := c.cc.Invoke(ctx, DeepTraceService_GetLogErrors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *deepTraceServiceClient) GetProcessStacks(ctx context.Context, in *GetProcessStacksRequest, opts ...grpc.CallOption) (*ProcessStacksResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ProcessStacksResponse)
@@ -70,6 +121,16 @@ func (c *deepTraceServiceClient) GetProcessStacks(ctx context.Context, in *GetPr
 	return out, nil
 }
 
+func (c *deepTraceServiceClient) SampleProcessStacks(ctx context.Context, in *SampleProcessStacksRequest, opts ...grpc.CallOption) (*SampleProcessStacksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SampleProcessStacksResponse)
+	err := c.cc.Invoke(ctx, DeepTraceService_SampleProcessStacks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *deepTraceServiceClient) RestartServer(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*RestartResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(RestartResponse)
@@ -98,8 +159,16 @@ func (c *deepTraceServiceClient) GetVersion(ctx context.Context, in *emptypb.Emp
 type DeepTraceServiceServer interface {
 	// Get the most recent n lines of logs
 	GetRecentLogs(context.Context, *GetRecentLogsRequest) (*LogResponse, error)
+	// Follow the logs of all ranks, streaming new entries as they are written
+	FollowLogs(*FollowLogsRequest, grpc.ServerStreamingServer[RankLog]) error
+	// Report which log file is used for each rank without reading them
+	ResolveLogFiles(context.Context, *ResolveLogFilesRequest) (*ResolveLogFilesResponse, error)
+	// Extract Python tracebacks and known fatal errors from the tail of the rank logs
+	GetLogErrors(context.Context, *GetLogErrorsRequest) (*LogErrorsResponse, error)
 	// Get process stack information by process type
 	GetProcessStacks(context.Context, *GetProcessStacksRequest) (*ProcessStacksResponse, error)
+	// Take several stack snapshots on the node and summarize what did not change
+	SampleProcessStacks(context.Context, *SampleProcessStacksRequest) (*SampleProcessStacksResponse, error)
 	// Restart server
 	RestartServer(context.Context, *RestartRequest) (*RestartResponse, error)
 	// Get version information
@@ -117,9 +186,21 @@ type UnimplementedDeepTraceServiceServer struct{}
 func (UnimplementedDeepTraceServiceServer) GetRecentLogs(context.Context, *GetRecentLogsRequest) (*LogResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRecentLogs not implemented")
 }
+func (UnimplementedDeepTraceServiceServer) FollowLogs(*FollowLogsRequest, grpc.ServerStreamingServer[RankLog]) error {
+	return status.Errorf(codes.Unimplemented, "method FollowLogs not implemented")
+}
+func (UnimplementedDeepTraceServiceServer) ResolveLogFiles(context.Context, *ResolveLogFilesRequest) (*ResolveLogFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveLogFiles not implemented")
+}
+func (UnimplementedDeepTraceServiceServer) GetLogErrors(context.Context, *GetLogErrorsRequest) (*LogErrorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogErrors not implemented")
+}
 func (UnimplementedDeepTraceServiceServer) GetProcessStacks(context.Context, *GetProcessStacksRequest) (*ProcessStacksResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProcessStacks not implemented")
 }
+func (UnimplementedDeepTraceServiceServer) SampleProcessStacks(context.Context, *SampleProcessStacksRequest) (*SampleProcessStacksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SampleProcessStacks not implemented")
+}
 func (UnimplementedDeepTraceServiceServer) RestartServer(context.Context, *RestartRequest) (*RestartResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RestartServer not implemented")
 }
@@ -165,6 +246,53 @@ func _DeepTraceService_GetRecentLogs_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DeepTraceService_FollowLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FollowLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DeepTraceServiceServer).FollowLogs(m, &grpc.GenericServerStream[FollowLogsRequest, RankLog]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DeepTraceService_FollowLogsServer = grpc.ServerStreamingServer[RankLog]
+
+func _DeepTraceService_ResolveLogFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveLogFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeepTraceServiceServer).ResolveLogFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeepTraceService_ResolveLogFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeepTraceServiceServer).ResolveLogFiles(ctx, req.(*ResolveLogFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeepTraceService_GetLogErrors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLogErrorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeepTraceServiceServer).GetLogErrors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeepTraceService_GetLogErrors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeepTraceServiceServer).GetLogErrors(ctx, req.(*GetLogErrorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DeepTraceService_GetProcessStacks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetProcessStacksRequest)
 	if err := dec(in); err != nil {
@@ -183,6 +311,24 @@ func _DeepTraceService_GetProcessStacks_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DeepTraceService_SampleProcessStacks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SampleProcessStacksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeepTraceServiceServer).SampleProcessStacks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeepTraceService_SampleProcessStacks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeepTraceServiceServer).SampleProcessStacks(ctx, req.(*SampleProcessStacksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DeepTraceService_RestartServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RestartRequest)
 	if err := dec(in); err != nil {
@@ -230,10 +376,22 @@ var DeepTraceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRecentLogs",
 			Handler:    _DeepTraceService_GetRecentLogs_Handler,
 		},
+		{
+			MethodName: "ResolveLogFiles",
+			Handler:    _DeepTraceService_ResolveLogFiles_Handler,
+		},
+		{
+			MethodName: "GetLogErrors",
+			Handler:    _DeepTraceService_GetLogErrors_Handler,
+		},
 		{
 			MethodName: "GetProcessStacks",
 			Handler:    _DeepTraceService_GetProcessStacks_Handler,
 		},
+		{
+			MethodName: "SampleProcessStacks",
+			Handler:    _DeepTraceService_SampleProcessStacks_Handler,
+		},
 		{
 			MethodName: "RestartServer",
 			Handler:    _DeepTraceService_RestartServer_Handler,
@@ -243,7 +401,13 @@ var DeepTraceService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _DeepTraceService_GetVersion_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "FollowLogs",
+			Handler:       _DeepTraceService_FollowLogs_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "v1/deeptrace.proto",
 }
 